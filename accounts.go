@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the on-chain account check
+const (
+	AccountCheckOff  string = "off"
+	AccountCheckWarn string = "warn"
+	AccountCheckFail string = "fail"
+)
+
+// checkAccounts queries the chain node for the account of every imported key and returns the addresses without one.
+// Accounts only exist on chain once funded, so a missing account is either a key never funded or a key derived from
+// the wrong mnemonic, index or algorithm: reported with a warning, or failing the pass in AccountCheckFail.
+func checkAccounts(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey) ([]string, error) {
+	if appConfig.AccountCheck == AccountCheckOff || len(importedKeys) == 0 {
+		return nil, nil
+	}
+
+	missing := make([]string, 0)
+	for _, importedKey := range importedKeys {
+		if slices.Contains(missing, importedKey.Address) {
+			continue
+		}
+		found, err := chain.account(importedKey.Address)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			keyLog.Warn().
+				Str("name", importedKey.Name).
+				Str("address", importedKey.Address).
+				Str("role", importedKey.Role).
+				Int("index", importedKey.EntryIndex).
+				Msg("Account not found on chain, the key was never funded or was derived wrong")
+			missing = append(missing, importedKey.Address)
+		}
+	}
+
+	if len(missing) == 0 {
+		log.Info().Int("keys", len(importedKeys)).Msg("All accounts found on chain")
+		return missing, nil
+	}
+	if appConfig.AccountCheck == AccountCheckFail {
+		return missing, withExitCode(ExitValidationError, fmt.Errorf("%w: %v", ErrAccountNotFound, missing))
+	}
+	log.Warn().Strs("addresses", missing).Msg("Accounts not found on chain")
+	return missing, nil
+}
+
+// AccountRecord is the account of an imported key on chain, with the account number and next sequence offline
+// signers and downstream automation sign its transactions with.
+type AccountRecord struct {
+	Address       string `json:"address"`
+	AccountNumber uint64 `json:"account_number"`
+	Sequence      uint64 `json:"sequence"`
+}
+
+// recordAccounts queries the chain node for the account of every imported key, once per address, when chain queries
+// are enabled. Keys without an account are left out, see checkAccounts.
+func recordAccounts(chain *ChainClient, importedKeys []ImportedKey) ([]AccountRecord, error) {
+	if chain == nil || len(importedKeys) == 0 {
+		return nil, nil
+	}
+
+	accounts := make([]AccountRecord, 0, len(importedKeys))
+	for _, importedKey := range importedKeys {
+		if slices.ContainsFunc(accounts, func(account AccountRecord) bool { return account.Address == importedKey.Address }) {
+			continue
+		}
+		account, err := chain.accountInfo(importedKey.Address)
+		if err != nil {
+			return accounts, err
+		}
+		if account == nil {
+			continue
+		}
+		accounts = append(accounts, AccountRecord{Address: importedKey.Address, AccountNumber: account.AccountNumber, Sequence: account.Sequence})
+	}
+	log.Debug().Int("accounts", len(accounts)).Msg("Accounts recorded")
+	return accounts, nil
+}
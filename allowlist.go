@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+// validateAddressLists checks that the addresses of ADDRESS_ALLOWLIST, ADDRESS_DENYLIST and
+// APPLICATION_GATEWAY_ADDRESSES are valid addresses of ADDRESS_PREFIX, so a typo doesn't silently reject (or let
+// through) a key.
+func validateAddressLists(appConfig *AppConfig) error {
+	lists := map[string][]string{
+		"ADDRESS_ALLOWLIST":             appConfig.AddressAllowlist,
+		"ADDRESS_DENYLIST":              appConfig.AddressDenylist,
+		"APPLICATION_GATEWAY_ADDRESSES": appConfig.ApplicationGatewayAddresses,
+	}
+	for setting, addresses := range lists {
+		for _, address := range addresses {
+			prefix, _, err := bech32.DecodeAndConvert(address)
+			if err != nil {
+				return fmt.Errorf("invalid address %s in %s: %w", address, setting, err)
+			}
+			if prefix != appConfig.AddressPrefix {
+				return fmt.Errorf("address %s in %s doesn't have the %s prefix", address, setting, appConfig.AddressPrefix)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAddressAllowed rejects the keys whose address is on ADDRESS_DENYLIST, or missing from ADDRESS_ALLOWLIST when
+// it is set.
+func checkAddressAllowed(appConfig *AppConfig, address string) error {
+	if slices.Contains(appConfig.AddressDenylist, address) {
+		return fmt.Errorf("%w: %s is on ADDRESS_DENYLIST", ErrAddressNotAllowed, address)
+	}
+	if len(appConfig.AddressAllowlist) > 0 && !slices.Contains(appConfig.AddressAllowlist, address) {
+		return fmt.Errorf("%w: %s is not on ADDRESS_ALLOWLIST", ErrAddressNotAllowed, address)
+	}
+	return nil
+}
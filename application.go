@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// ApplicationConfig is the AppGate-style config generated for an application key.
+type ApplicationConfig struct {
+	SelfSigning       bool     `yaml:"self_signing"`
+	SigningKey        string   `yaml:"signing_key"`
+	ServiceIds        []string `yaml:"service_ids"`
+	ListeningEndpoint string   `yaml:"listening_endpoint"`
+	QueryNodeRPCUrl   string   `yaml:"query_node_rpc_url"`
+	QueryNodeGRPCUrl  string   `yaml:"query_node_grpc_url"`
+}
+
+// applicationQueryNodeUrls returns the query node urls of the application configs.
+// Unset urls fall back to the pocket_node section of the relay miner config when there is one, then to the chain
+// node of the loader.
+func applicationQueryNodeUrls(appConfig *AppConfig, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) (string, string) {
+	rpcUrl := appConfig.ApplicationQueryNodeRPCUrl
+	grpcUrl := appConfig.ApplicationQueryNodeGRPCUrl
+
+	if relayMinerConfig != nil {
+		if rpcUrl == "" {
+			rpcUrl = relayMinerConfig.PocketNode.QueryNodeRPCUrl
+		}
+		if grpcUrl == "" {
+			grpcUrl = relayMinerConfig.PocketNode.QueryNodeGRPCUrl
+		}
+	}
+
+	if grpcUrl == "" && appConfig.ChainGRPCUrl != "" {
+		grpcUrl = appConfig.ChainGRPCUrl
+		if !strings.Contains(grpcUrl, "://") {
+			grpcUrl = "tcp://" + grpcUrl
+		}
+	}
+	return orDefault(rpcUrl, appConfig.ChainRPCUrl), grpcUrl
+}
+
+// writeApplicationConfigs writes a `<application_address>.yaml` AppGate-style config for every application key.
+func writeApplicationConfigs(appConfig *AppConfig, importedKeys []ImportedKey, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	if appConfig.ApplicationConfigOutputDir == "" {
+		return nil
+	}
+	if err := checkRunContext(appConfig); err != nil {
+		return err
+	}
+
+	rpcUrl, grpcUrl := applicationQueryNodeUrls(appConfig, relayMinerConfig)
+	if rpcUrl == "" || grpcUrl == "" {
+		return fmt.Errorf("application configs require APPLICATION_QUERY_NODE_RPC_URL and APPLICATION_QUERY_NODE_GRPC_URL")
+	}
+
+	if err := mkdirAllMode(appConfig.ApplicationConfigOutputDir, 0755); err != nil {
+		return fmt.Errorf("unable to create application config output dir: %w", err)
+	}
+
+	written := 0
+	for _, key := range importedKeys {
+		if key.Role != ApplicationRole {
+			continue
+		}
+
+		content, err := yaml.Marshal(&ApplicationConfig{
+			SelfSigning:       true,
+			SigningKey:        key.Name,
+			ServiceIds:        key.ServiceIds,
+			ListeningEndpoint: appConfig.ApplicationListeningEndpoint,
+			QueryNodeRPCUrl:   rpcUrl,
+			QueryNodeGRPCUrl:  grpcUrl,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to marshal application config: %w", err)
+		}
+
+		path := filepath.Join(appConfig.ApplicationConfigOutputDir, key.Address+".yaml")
+		if err := writeFileAtomic(path, content, 0644); err != nil {
+			return fmt.Errorf("unable to write application config: %w", err)
+		}
+		if err := auditConfigWritten(appConfig, path, content); err != nil {
+			return err
+		}
+
+		log.Debug().
+			Str("path", path).
+			Str("name", key.Name).
+			Strs("service_ids", key.ServiceIds).
+			Msg("Application config written")
+		written++
+	}
+
+	log.Info().
+		Int("application_configs", written).
+		Str("dir", appConfig.ApplicationConfigOutputDir).
+		Msg("Application configs written successfully")
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditKeyImported   string = "key_imported"
+	AuditKeyDeleted    string = "key_deleted"
+	AuditConfigWritten string = "config_written"
+)
+
+// AuditRecord is a line of the audit log. It never carries private key material: keys are identified by their name
+// and address, configs by their target and content hash.
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Pod    string    `json:"pod"`
+	// Name and Address identify the key of key actions.
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+	// Source is where an imported key came from, or what deleted it.
+	Source string `json:"source,omitempty"`
+	// Target and Hash (SHA-256 of the content) identify the written config of config actions.
+	Target string `json:"target,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+}
+
+// auditMu serializes the appends of concurrent passes (daemon mode, tenants).
+var auditMu sync.Mutex
+
+// audit appends record to AuditLogPath as a JSON line, unless the audit log is disabled. The file is only ever
+// appended to and fsynced, so records survive a crash of the loader.
+func audit(appConfig *AppConfig, record AuditRecord) error {
+	if appConfig.AuditLogPath == "" {
+		return nil
+	}
+	record.Time = time.Now().UTC()
+	record.Pod = podName()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to marshal audit record: %w", err)
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	file, err := os.OpenFile(appConfig.AuditLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log '%s': %w", appConfig.AuditLogPath, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to append to audit log '%s': %w", appConfig.AuditLogPath, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync audit log '%s': %w", appConfig.AuditLogPath, err)
+	}
+	keyLog.Debug().Str("action", record.Action).Str("path", appConfig.AuditLogPath).Msg("Audit record written")
+	return nil
+}
+
+// auditConfigWritten records a config written to target.
+func auditConfigWritten(appConfig *AppConfig, target string, content []byte) error {
+	return audit(appConfig, AuditRecord{Action: AuditConfigWritten, Target: target, Hash: contentHash(content)})
+}
+
+// keysSource describes where the keys of a pass are read from, for the audit log.
+func keysSource(appConfig *AppConfig) string {
+	switch {
+	case len(appConfig.KeysData) > 0:
+		return "interactive"
+	case appConfig.ConfigSource == KubernetesSource:
+		return fmt.Sprintf("secret %s/%s", appConfig.KeysNamespace, appConfig.KeysSecretName)
+	default:
+		return "file " + appConfig.KeysFilePath
+	}
+}
+
+// auditedKeyring records the keys imported into and deleted from the wrapped keyring in the audit log. Only the
+// keyring the relayminer signs with is wrapped, the in-memory keyrings of plan and verify aren't audited.
+type auditedKeyring struct {
+	keyring.Keyring
+	appConfig *AppConfig
+	source    string
+}
+
+// newAuditedKeyring wraps kr, attributing its changes to source.
+func newAuditedKeyring(appConfig *AppConfig, kr keyring.Keyring, source string) keyring.Keyring {
+	if appConfig.AuditLogPath == "" {
+		return kr
+	}
+	return &auditedKeyring{Keyring: kr, appConfig: appConfig, source: source}
+}
+
+// ImportPrivKeyHex imports the key, then records it.
+func (k *auditedKeyring) ImportPrivKeyHex(uid, privKey, algoStr string) error {
+	if err := k.Keyring.ImportPrivKeyHex(uid, privKey, algoStr); err != nil {
+		return err
+	}
+	record, err := k.Keyring.Key(uid)
+	if err != nil {
+		return fmt.Errorf("unable to read imported key %s: %w", uid, err)
+	}
+	address, err := record.GetAddress()
+	if err != nil {
+		return fmt.Errorf("unable to read address of imported key %s: %w", uid, err)
+	}
+	return audit(k.appConfig, AuditRecord{Action: AuditKeyImported, Name: uid, Address: address.String(), Source: k.source})
+}
+
+// DeleteByAddress deletes the key, then records it.
+func (k *auditedKeyring) DeleteByAddress(address sdk.Address) error {
+	name := ""
+	if record, err := k.Keyring.KeyByAddress(address); err == nil {
+		name = record.Name
+	}
+	if err := k.Keyring.DeleteByAddress(address); err != nil {
+		return err
+	}
+	return audit(k.appConfig, AuditRecord{Action: AuditKeyDeleted, Name: name, Address: address.String(), Source: k.source})
+}
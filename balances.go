@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the balance check
+const (
+	BalanceCheckOff  string = "off"
+	BalanceCheckWarn string = "warn"
+	BalanceCheckFail string = "fail"
+)
+
+// LowBalance is an imported key holding less than MinBalance.
+type LowBalance struct {
+	Address string `json:"address"`
+	Role    string `json:"role"`
+	Balance string `json:"balance"`
+}
+
+// checkBalances queries the chain node for the balance, in the denom of MinBalance, of every imported supplier
+// (operator and owner) key and returns the ones holding less. An operator without funds can't pay the fees of its
+// claims and proofs, which the relayminer only logs: reported with a warning, or failing the pass in
+// BalanceCheckFail.
+func checkBalances(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey) ([]LowBalance, error) {
+	if appConfig.BalanceCheck == BalanceCheckOff {
+		return nil, nil
+	}
+	minBalance, err := sdk.ParseCoinNormalized(appConfig.MinBalance)
+	if err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("invalid minimum balance: %w", err))
+	}
+
+	supplierKeys := make([]ImportedKey, 0, len(importedKeys))
+	for _, importedKey := range importedKeys {
+		if importedKey.Role == OperatorRole || importedKey.Role == OwnerRole {
+			supplierKeys = append(supplierKeys, importedKey)
+		}
+	}
+	if len(supplierKeys) == 0 {
+		return nil, nil
+	}
+
+	lowBalances := make([]LowBalance, 0)
+	checked := make([]string, 0, len(supplierKeys))
+	for _, importedKey := range supplierKeys {
+		if slices.Contains(checked, importedKey.Address) {
+			continue
+		}
+		checked = append(checked, importedKey.Address)
+
+		balance, err := chain.balance(importedKey.Address, minBalance.Denom)
+		if err != nil {
+			return nil, err
+		}
+		if !balance.IsLT(minBalance) {
+			continue
+		}
+		keyLog.Warn().
+			Str("name", importedKey.Name).
+			Str("address", importedKey.Address).
+			Str("role", importedKey.Role).
+			Str("balance", balance.String()).
+			Str("min_balance", minBalance.String()).
+			Msg("Key balance below the minimum, it may not afford its fees")
+		lowBalances = append(lowBalances, LowBalance{Address: importedKey.Address, Role: importedKey.Role, Balance: balance.String()})
+	}
+
+	if len(lowBalances) == 0 {
+		log.Info().Int("keys", len(checked)).Str("min_balance", minBalance.String()).Msg("All supplier keys funded")
+		return lowBalances, nil
+	}
+	if appConfig.BalanceCheck == BalanceCheckFail {
+		return lowBalances, withExitCode(ExitValidationError, fmt.Errorf("%w: %d keys below %s", ErrInsufficientBalance, len(lowBalances), minBalance))
+	}
+	log.Warn().Int("keys", len(lowBalances)).Str("min_balance", minBalance.String()).Msg("Supplier keys below the minimum balance")
+	return lowBalances, nil
+}
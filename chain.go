@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cosmossdk.io/x/tx/signing"
+	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/codec/address"
+	"github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/gogoproto/proto"
+	"github.com/pokt-network/poktroll/app/pocket"
+	apptypes "github.com/pokt-network/poktroll/x/application/types"
+	servicetypes "github.com/pokt-network/poktroll/x/service/types"
+	sharedtypes "github.com/pokt-network/poktroll/x/shared/types"
+	suppliertypes "github.com/pokt-network/poktroll/x/supplier/types"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// chainAddressPrefixes maps the chain ids of the known networks to the Bech32 prefix of their account addresses.
+var chainAddressPrefixes = map[string]string{
+	pocket.MainNetChainId:      "pokt",
+	pocket.AlphaTestNetChainId: "pokt",
+	pocket.BetaTestNetChainId:  "pokt",
+}
+
+// checkAddressPrefix makes sure AddressPrefix is the one of the network of ChainID, when it is a known one: keys
+// derived with another prefix get addresses the chain rejects, which only shows once they are funded or staked.
+func checkAddressPrefix(appConfig *AppConfig) error {
+	prefix, ok := chainAddressPrefixes[appConfig.ChainID]
+	if !ok || appConfig.AddressPrefix == prefix {
+		return nil
+	}
+	return fmt.Errorf("ADDRESS_PREFIX %s doesn't match CHAIN_ID %s, whose addresses start with %s", appConfig.AddressPrefix, appConfig.ChainID, prefix)
+}
+
+// chainGRPCTarget returns the gRPC target of a node URL and whether it is dialed over TLS, following the
+// query_node_grpc_url of the relayminer: https URLs are dialed over TLS, tcp and http ones (or a bare host:port) in
+// plaintext.
+func chainGRPCTarget(nodeUrl string) (string, bool, error) {
+	if !strings.Contains(nodeUrl, "://") {
+		nodeUrl = "tcp://" + nodeUrl
+	}
+	parsed, err := url.Parse(nodeUrl)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid chain gRPC url: %w", err)
+	}
+	if parsed.Host == "" {
+		return "", false, fmt.Errorf("chain gRPC url has no host: %s", nodeUrl)
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		host := parsed.Host
+		if parsed.Port() == "" {
+			host += ":443"
+		}
+		return host, true, nil
+	case "tcp", "http":
+		return parsed.Host, false, nil
+	default:
+		return "", false, fmt.Errorf("unsupported chain gRPC url scheme: %s", parsed.Scheme)
+	}
+}
+
+// chainTransportCredentials returns the credentials of the connection to the chain node: TLS trusting the system
+// roots, or ChainGRPCCAFile when set, for https urls and none for plaintext ones.
+func chainTransportCredentials(appConfig *AppConfig, secure bool) (credentials.TransportCredentials, error) {
+	if !secure {
+		return insecure.NewCredentials(), nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if appConfig.ChainGRPCCAFile != "" {
+		content, err := os.ReadFile(appConfig.ChainGRPCCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read chain gRPC CA file '%s': %w", appConfig.ChainGRPCCAFile, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no PEM certificate in chain gRPC CA file '%s'", appConfig.ChainGRPCCAFile)
+		}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// chainCodec returns the codec of the queries and transactions sent to the chain node, which knows the account
+// types of the auth queries and the messages the loader signs. Signers are resolved with the bech32 prefixes
+// configured by configureSdk.
+func chainCodec() *codec.ProtoCodec {
+	sdkConfig := sdk.GetConfig()
+	interfaceRegistry, err := types.NewInterfaceRegistryWithOptions(types.InterfaceRegistryOptions{
+		ProtoFiles: proto.HybridResolver,
+		SigningOptions: signing.Options{
+			AddressCodec:          address.NewBech32Codec(sdkConfig.GetBech32AccountAddrPrefix()),
+			ValidatorAddressCodec: address.NewBech32Codec(sdkConfig.GetBech32ValidatorAddrPrefix()),
+		},
+	})
+	if err != nil {
+		// only fails on missing proto files, which are compiled in
+		panic(fmt.Sprintf("unable to create chain interface registry: %s", err))
+	}
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+	authtypes.RegisterInterfaces(interfaceRegistry)
+	suppliertypes.RegisterInterfaces(interfaceRegistry)
+	apptypes.RegisterInterfaces(interfaceRegistry)
+	return codec.NewProtoCodec(interfaceRegistry)
+}
+
+// ChainClient is the connection to the chain node at ChainGRPCUrl shared by the on-chain checks, transactions and
+// faucet of a pass. Every query is bounded by ChainQueryTimeout and retried on transient failures up to
+// ChainRetryAttempts, the read-only ones falling back to ChainRESTUrl; the first one makes sure the node runs
+// ChainID.
+type ChainClient struct {
+	appConfig *AppConfig
+	conn      *grpc.ClientConn
+	codec     *codec.ProtoCodec
+
+	verifyOnce sync.Once
+	verifyErr  error
+	// grpcUnavailable switches the queries to ChainRESTUrl, see fetch.
+	grpcUnavailable atomic.Bool
+}
+
+// newChainClient returns the client of the chain node at ChainGRPCUrl, nil when it is unset since every on-chain
+// feature requires it. The connection is only established by the first query.
+func newChainClient(appConfig *AppConfig) (*ChainClient, error) {
+	if appConfig.ChainGRPCUrl == "" {
+		return nil, nil
+	}
+	target, secure, err := chainGRPCTarget(appConfig.ChainGRPCUrl)
+	if err != nil {
+		return nil, withExitCode(ExitConfigError, err)
+	}
+	transportCredentials, err := chainTransportCredentials(appConfig, secure)
+	if err != nil {
+		return nil, withExitCode(ExitConfigError, err)
+	}
+
+	chainCodec := chainCodec()
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(chainCodec.GRPCCodec())),
+	)
+	if err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("unable to connect to chain node %s: %w", redactSetting("CHAIN_GRPC_URL", appConfig.ChainGRPCUrl), err))
+	}
+	return &ChainClient{appConfig: appConfig, conn: conn, codec: chainCodec}, nil
+}
+
+// Close closes the connection to the chain node, if any.
+func (c *ChainClient) Close() {
+	if c != nil {
+		_ = c.conn.Close()
+	}
+}
+
+// retriableChainError reports whether a failed query may succeed when sent again: unreachable or overloaded nodes
+// and timeouts. Errors the node answered with, like NotFound, are final.
+func retriableChainError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// query calls fn with a context bounded by ChainQueryTimeout until it succeeds, fails with a non-retriable error or
+// ChainRetryAttempts are used up, sleeping with exponential backoff and jitter in between.
+func (c *ChainClient) query(operation string, fn func(ctx context.Context) error) error {
+	if err := c.verifyChainId(); err != nil {
+		return err
+	}
+	return c.retry(operation, fn)
+}
+
+// retry is query without the chain id check.
+func (c *ChainClient) retry(operation string, fn func(ctx context.Context) error) error {
+	backoff := wait.Backoff{
+		Duration: c.appConfig.ChainRetryInitialDelay,
+		Factor:   2,
+		Jitter:   0.2,
+		Steps:    c.appConfig.ChainRetryAttempts,
+		Cap:      c.appConfig.ChainRetryMaxDelay,
+	}
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(c.appConfig.runContext(), c.appConfig.ChainQueryTimeout)
+		err := fn(ctx)
+		cancel()
+		if err == nil || !retriableChainError(err) || attempt >= c.appConfig.ChainRetryAttempts {
+			return err
+		}
+
+		delay := backoff.Step()
+		log.Warn().
+			Err(err).
+			Str("operation", operation).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Chain query failed, retrying")
+		select {
+		case <-c.appConfig.runContext().Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// verifyChainId makes sure, once, that the chain node runs ChainID when set: a node of another network would have
+// the checks pass against the wrong chain and the transactions fail their signature.
+func (c *ChainClient) verifyChainId() error {
+	c.verifyOnce.Do(func() {
+		if c.appConfig.ChainID == "" {
+			return
+		}
+		response := &cmtservice.GetNodeInfoResponse{}
+		err := c.fetch("node info", "/cosmos.base.tendermint.v1beta1.Service/GetNodeInfo", &cmtservice.GetNodeInfoRequest{}, response,
+			"/cosmos/base/tendermint/v1beta1/node_info")
+		if err != nil {
+			c.verifyErr = withExitCode(ExitSourceError, fmt.Errorf("unable to query chain node info: %w", err))
+			return
+		}
+		if network := response.GetDefaultNodeInfo().GetNetwork(); network != c.appConfig.ChainID {
+			log.Error().Str("chain_id", c.appConfig.ChainID).Str("node_chain_id", network).Msg("Chain node runs another chain")
+			c.verifyErr = withExitCode(ExitConfigError, fmt.Errorf("%w: CHAIN_ID is %s, the chain node runs %s", ErrChainMismatch, c.appConfig.ChainID, network))
+			return
+		}
+		log.Debug().Str("chain_id", c.appConfig.ChainID).Msg("Chain node verified")
+	})
+	return c.verifyErr
+}
+
+// get is fetch after the chain id check, for the read-only queries of the on-chain features.
+func (c *ChainClient) get(operation, method string, request, response proto.Message, restPath string) error {
+	if err := c.verifyChainId(); err != nil {
+		return err
+	}
+	return c.fetch(operation, method, request, response, restPath)
+}
+
+// account reports whether address has an account on chain.
+func (c *ChainClient) account(address string) (bool, error) {
+	err := c.get("account", "/cosmos.auth.v1beta1.Query/Account",
+		&authtypes.QueryAccountRequest{Address: address}, &authtypes.QueryAccountResponse{},
+		"/cosmos/auth/v1beta1/accounts/"+url.PathEscape(address))
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return false, nil
+	case err != nil:
+		return false, chainQueryError(err, "unable to query account %s", address)
+	}
+	return true, nil
+}
+
+// accountInfo returns the account of address, nil when it has none on chain.
+func (c *ChainClient) accountInfo(address string) (*authtypes.BaseAccount, error) {
+	response := &authtypes.QueryAccountInfoResponse{}
+	err := c.get("account info", "/cosmos.auth.v1beta1.Query/AccountInfo",
+		&authtypes.QueryAccountInfoRequest{Address: address}, response,
+		"/cosmos/auth/v1beta1/account_info/"+url.PathEscape(address))
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return nil, nil
+	case err != nil:
+		return nil, chainQueryError(err, "unable to query account %s", address)
+	}
+	return response.Info, nil
+}
+
+// balance returns the balance of address in denom.
+func (c *ChainClient) balance(address, denom string) (sdk.Coin, error) {
+	response := &banktypes.QueryBalanceResponse{}
+	err := c.get("balance", "/cosmos.bank.v1beta1.Query/Balance",
+		&banktypes.QueryBalanceRequest{Address: address, Denom: denom}, response,
+		"/cosmos/bank/v1beta1/balances/"+url.PathEscape(address)+"/by_denom?denom="+url.QueryEscape(denom))
+	if err != nil {
+		return sdk.Coin{}, chainQueryError(err, "unable to query balance of %s", address)
+	}
+	if response.Balance == nil {
+		return sdk.NewInt64Coin(denom, 0), nil
+	}
+	return *response.Balance, nil
+}
+
+// service reports whether the service serviceId exists on chain.
+func (c *ChainClient) service(serviceId string) (bool, error) {
+	err := c.get("service", "/pocket.service.Query/Service",
+		&servicetypes.QueryGetServiceRequest{Id: serviceId}, &servicetypes.QueryGetServiceResponse{},
+		"/pokt-network/poktroll/service/service/"+url.PathEscape(serviceId))
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return false, nil
+	case err != nil:
+		return false, chainQueryError(err, "unable to query service %s", serviceId)
+	}
+	return true, nil
+}
+
+// supplier returns the dehydrated supplier of operatorAddress, nil when it isn't staked.
+func (c *ChainClient) supplier(operatorAddress string) (*sharedtypes.Supplier, error) {
+	response := &suppliertypes.QueryGetSupplierResponse{}
+	err := c.get("supplier", "/pocket.supplier.Query/Supplier",
+		&suppliertypes.QueryGetSupplierRequest{OperatorAddress: operatorAddress, Dehydrated: true}, response,
+		"/pokt-network/poktroll/supplier/supplier/"+url.PathEscape(operatorAddress)+"?dehydrated=true")
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return nil, nil
+	case err != nil:
+		return nil, chainQueryError(err, "unable to query supplier %s", operatorAddress)
+	}
+	return &response.Supplier, nil
+}
+
+// application returns the application staked by address, nil when it isn't staked.
+func (c *ChainClient) application(address string) (*apptypes.Application, error) {
+	response := &apptypes.QueryGetApplicationResponse{}
+	err := c.get("application", "/pocket.application.Query/Application",
+		&apptypes.QueryGetApplicationRequest{Address: address}, response,
+		"/pokt-network/poktroll/application/application/"+url.PathEscape(address))
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return nil, nil
+	case err != nil:
+		return nil, chainQueryError(err, "unable to query application %s", address)
+	}
+	return &response.Application, nil
+}
+
+// supplierParams returns the params of the supplier module.
+func (c *ChainClient) supplierParams() (*suppliertypes.Params, error) {
+	response := &suppliertypes.QueryParamsResponse{}
+	err := c.get("supplier params", "/pocket.supplier.Query/Params",
+		&suppliertypes.QueryParamsRequest{}, response,
+		"/pokt-network/poktroll/supplier/params")
+	if err != nil {
+		return nil, chainQueryError(err, "unable to query supplier params")
+	}
+	return &response.Params, nil
+}
+
+// applicationParams returns the params of the application module.
+func (c *ChainClient) applicationParams() (*apptypes.Params, error) {
+	response := &apptypes.QueryParamsResponse{}
+	err := c.get("application params", "/pocket.application.Query/Params",
+		&apptypes.QueryParamsRequest{}, response,
+		"/pokt-network/poktroll/application/params")
+	if err != nil {
+		return nil, chainQueryError(err, "unable to query application params")
+	}
+	return &response.Params, nil
+}
+
+// chainQueryError wraps the error of a failed query as an ExitSourceError, keeping the classification of the chain id
+// check.
+func chainQueryError(err error, format string, args ...any) error {
+	var exitError *ExitError
+	if errors.As(err, &exitError) {
+		return err
+	}
+	return withExitCode(ExitSourceError, fmt.Errorf(format+": %w", append(args, err)...))
+}
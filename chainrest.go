@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/gogoproto/jsonpb"
+	"github.com/cosmos/gogoproto/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chainRESTError is the body of a failed REST (LCD) query, the gRPC status the gateway translated.
+type chainRESTError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// fetch sends the read-only query request to the gRPC method of the chain node, filling response. When the gRPC
+// endpoint is unavailable (once its retries are used up) and ChainRESTUrl is set, the query and every later one of the
+// pass go to restPath of the REST (LCD) API instead, since many nodes only expose port 1317 publicly.
+func (c *ChainClient) fetch(operation, method string, request, response proto.Message, restPath string) error {
+	if !c.grpcUnavailable.Load() {
+		err := c.retry(operation, func(ctx context.Context) error {
+			return c.conn.Invoke(ctx, method, request, response)
+		})
+		if c.appConfig.ChainRESTUrl == "" || status.Code(err) != codes.Unavailable {
+			return err
+		}
+		if c.grpcUnavailable.CompareAndSwap(false, true) {
+			log.Warn().Err(err).Str("rest_url", redactSetting("CHAIN_REST_URL", c.appConfig.ChainRESTUrl)).Msg("Chain gRPC endpoint unavailable, falling back to REST")
+		}
+	}
+	return c.retry(operation, func(ctx context.Context) error {
+		return c.restGet(ctx, restPath, response)
+	})
+}
+
+// restGet queries path of the REST API at ChainRESTUrl, decoding its JSON answer into response. Failures are
+// returned as gRPC statuses so they are retried and classified like the gRPC ones: the gateway answers with the
+// status code of the query, unreachable nodes and 5xx without one are Unavailable.
+func (c *ChainClient) restGet(ctx context.Context, path string, response proto.Message) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.appConfig.ChainRESTUrl, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("unable to build REST query: %w", err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	httpResponse, err := http.DefaultClient.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer httpResponse.Body.Close()
+	body, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return status.Error(codes.Unavailable, fmt.Sprintf("unable to read REST answer: %s", err))
+	}
+
+	if httpResponse.StatusCode != http.StatusOK {
+		restError := chainRESTError{}
+		if json.Unmarshal(body, &restError) == nil && restError.Code != 0 {
+			return status.Error(codes.Code(restError.Code), restError.Message)
+		}
+		switch {
+		case httpResponse.StatusCode == http.StatusNotFound:
+			return status.Error(codes.NotFound, httpResponse.Status)
+		case httpResponse.StatusCode == http.StatusTooManyRequests:
+			return status.Error(codes.ResourceExhausted, httpResponse.Status)
+		case httpResponse.StatusCode >= http.StatusInternalServerError:
+			return status.Error(codes.Unavailable, httpResponse.Status)
+		default:
+			return status.Error(codes.Unknown, httpResponse.Status)
+		}
+	}
+	// nodes newer than the loader may answer with fields it doesn't know yet
+	unmarshaler := jsonpb.Unmarshaler{AnyResolver: c.codec.InterfaceRegistry(), AllowUnknownFields: true}
+	if err := unmarshaler.Unmarshal(bytes.NewReader(body), response); err != nil {
+		return fmt.Errorf("unable to decode REST answer of %s: %w", path, err)
+	}
+	return types.UnpackInterfaces(response, c.codec.InterfaceRegistry())
+}
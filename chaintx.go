@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Broadcast modes of the transactions: sync waits for the node to check them, async doesn't, and block also waits
+// for their inclusion in a block, like the removed BROADCAST_MODE_BLOCK of the Cosmos SDK.
+const (
+	TxBroadcastSync  string = "sync"
+	TxBroadcastAsync string = "async"
+	TxBroadcastBlock string = "block"
+)
+
+// Transactions the loader broadcasts
+const (
+	TxStakeSupplier     string = "stake_supplier"
+	TxDelegateToGateway string = "delegate_to_gateway"
+)
+
+// ChainTx is a transaction the loader broadcast during a pass, or wrote unsigned to File for offline signing (without
+// Hash nor Height then). AccountNumber and Sequence are the ones of the signer it is signed with.
+type ChainTx struct {
+	Type          string `json:"type"`
+	Signer        string `json:"signer"`
+	AccountNumber uint64 `json:"account_number"`
+	Sequence      uint64 `json:"sequence"`
+	Hash          string `json:"hash,omitempty"`
+	Height        int64  `json:"height,omitempty"`
+	File          string `json:"file,omitempty"`
+}
+
+// chainTxConfig returns the config encoding and signing the transactions of the loader.
+func chainTxConfig() client.TxConfig {
+	return authtx.NewTxConfig(chainCodec(), authtx.DefaultSignModes)
+}
+
+// broadcastTx signs msgs with the key name of kr and broadcasts them as a single transaction to the chain node, then
+// waits for it to be included in a block in TxBroadcastBlock. The gas limit is TxGasLimit, or simulated when 0 and
+// scaled by TxGasAdjustment like `--gas-adjustment`; the fees are TxFees, or follow TxGasPrices. A transaction rejected by the node or failing on chain is an ExitValidationError, an unreachable node
+// an ExitSourceError. The broadcast itself is never retried, the node may have accepted a transaction whose answer
+// was lost. With UnsignedTxOutputDir, the transaction is written there unsigned instead, see writeUnsignedTx.
+func (c *ChainClient) broadcastTx(kr keyring.Keyring, name, txType string, msgs ...sdk.Msg) (ChainTx, error) {
+	appConfig := c.appConfig
+	factory, txBuilder, chainTx, err := c.buildTx(kr, name, txType, msgs...)
+	if err != nil {
+		return chainTx, err
+	}
+	if err := checkRunContext(appConfig); err != nil {
+		return chainTx, err
+	}
+	if appConfig.UnsignedTxOutputDir != "" {
+		return c.writeUnsignedTx(chainTx, txBuilder)
+	}
+
+	if err := tx.Sign(appConfig.runContext(), factory, name, txBuilder, true); err != nil {
+		return chainTx, fmt.Errorf("unable to sign %s: %w", txType, err)
+	}
+	txBytes, err := chainTxConfig().TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return chainTx, fmt.Errorf("unable to encode %s: %w", txType, err)
+	}
+
+	mode := txtypes.BroadcastMode_BROADCAST_MODE_SYNC
+	if appConfig.TxBroadcastMode == TxBroadcastAsync {
+		mode = txtypes.BroadcastMode_BROADCAST_MODE_ASYNC
+	}
+	ctx, cancel := context.WithTimeout(appConfig.runContext(), appConfig.ChainQueryTimeout)
+	broadcast, err := txtypes.NewServiceClient(c.conn).BroadcastTx(ctx, &txtypes.BroadcastTxRequest{TxBytes: txBytes, Mode: mode})
+	cancel()
+	if err != nil {
+		return chainTx, withExitCode(ExitSourceError, fmt.Errorf("unable to broadcast %s: %w", txType, err))
+	}
+	chainTx.Hash = broadcast.TxResponse.TxHash
+	if broadcast.TxResponse.Code != 0 {
+		return chainTx, withExitCode(ExitValidationError, fmt.Errorf("%w: %s %s rejected with code %d: %s", ErrTxFailed, txType, chainTx.Hash, broadcast.TxResponse.Code, broadcast.TxResponse.RawLog))
+	}
+	log.Info().
+		Str("type", txType).
+		Str("signer", chainTx.Signer).
+		Str("hash", chainTx.Hash).
+		Uint64("gas", factory.Gas()).
+		Str("mode", appConfig.TxBroadcastMode).
+		Msg("Transaction broadcast")
+	if appConfig.TxBroadcastMode != TxBroadcastBlock {
+		return chainTx, nil
+	}
+
+	chainTx.Height, err = c.waitForTx(txType, chainTx.Hash)
+	return chainTx, err
+}
+
+// buildTx builds the unsigned transaction of msgs for the key name of kr, returning the factory signing it for the
+// account number and sequence of its signer on chain.
+func (c *ChainClient) buildTx(kr keyring.Keyring, name, txType string, msgs ...sdk.Msg) (tx.Factory, client.TxBuilder, ChainTx, error) {
+	appConfig := c.appConfig
+	factory := tx.Factory{}
+	record, err := kr.Key(name)
+	if err != nil {
+		return factory, nil, ChainTx{}, fmt.Errorf("unable to read signing key %s: %w", name, err)
+	}
+	signer, err := record.GetAddress()
+	if err != nil {
+		return factory, nil, ChainTx{}, fmt.Errorf("unable to read address of signing key %s: %w", name, err)
+	}
+	chainTx := ChainTx{Type: txType, Signer: signer.String()}
+
+	account, err := c.accountInfo(chainTx.Signer)
+	if err != nil {
+		return factory, nil, chainTx, err
+	}
+	if account == nil {
+		return factory, nil, chainTx, withExitCode(ExitValidationError, fmt.Errorf("%w: %s has no account on chain to pay for %s", ErrTxFailed, chainTx.Signer, txType))
+	}
+	chainTx.AccountNumber = account.AccountNumber
+	chainTx.Sequence = account.Sequence
+
+	factory = factory.
+		WithTxConfig(chainTxConfig()).
+		WithKeybase(kr).
+		WithFromName(name).
+		WithChainID(appConfig.ChainID).
+		WithAccountNumber(account.AccountNumber).
+		WithSequence(account.Sequence).
+		WithMemo(appConfig.TxMemo).
+		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT)
+	// the factory refuses both fees and gas prices
+	if appConfig.TxFees != "" {
+		factory = factory.WithFees(appConfig.TxFees)
+	} else {
+		factory = factory.WithGasPrices(appConfig.TxGasPrices)
+	}
+
+	gasLimit := uint64(appConfig.TxGasLimit)
+	if gasLimit == 0 {
+		simulation, err := factory.BuildSimTx(msgs...)
+		if err != nil {
+			return factory, nil, chainTx, fmt.Errorf("unable to build %s simulation: %w", txType, err)
+		}
+		var response *txtypes.SimulateResponse
+		err = c.query("simulate "+txType, func(ctx context.Context) error {
+			var err error
+			response, err = txtypes.NewServiceClient(c.conn).Simulate(ctx, &txtypes.SimulateRequest{TxBytes: simulation})
+			return err
+		})
+		if err != nil {
+			return factory, nil, chainTx, withExitCode(txErrorExitCode(err), fmt.Errorf("unable to simulate %s: %w", txType, err))
+		}
+		gasLimit = uint64(math.Ceil(float64(response.GasInfo.GasUsed) * appConfig.TxGasAdjustment))
+	}
+
+	factory = factory.WithGas(gasLimit)
+	txBuilder, err := factory.BuildUnsignedTx(msgs...)
+	if err != nil {
+		return factory, nil, chainTx, fmt.Errorf("unable to build %s: %w", txType, err)
+	}
+	return factory, txBuilder, chainTx, nil
+}
+
+// writeUnsignedTx writes the unsigned transaction of txBuilder to `<signer>-<type>.json` in UnsignedTxOutputDir, in
+// the JSON of `pocketd tx ... --generate-only`, for air-gapped custody: it is signed elsewhere with `pocketd tx sign
+// --offline` and the account number and sequence of chainTx, then broadcast with `pocketd tx broadcast`.
+func (c *ChainClient) writeUnsignedTx(chainTx ChainTx, txBuilder client.TxBuilder) (ChainTx, error) {
+	appConfig := c.appConfig
+	content, err := chainTxConfig().TxJSONEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return chainTx, fmt.Errorf("unable to encode unsigned %s: %w", chainTx.Type, err)
+	}
+	content = append(content, '\n')
+
+	if err := mkdirAllMode(appConfig.UnsignedTxOutputDir, 0755); err != nil {
+		return chainTx, fmt.Errorf("unable to create unsigned tx output dir: %w", err)
+	}
+	path := filepath.Join(appConfig.UnsignedTxOutputDir, chainTx.Signer+"-"+chainTx.Type+".json")
+	if err := writeFileAtomic(path, content, 0644); err != nil {
+		return chainTx, fmt.Errorf("unable to write unsigned %s: %w", chainTx.Type, err)
+	}
+	if err := auditConfigWritten(appConfig, path, content); err != nil {
+		return chainTx, err
+	}
+	chainTx.File = path
+
+	log.Info().
+		Str("type", chainTx.Type).
+		Str("signer", chainTx.Signer).
+		Uint64("account_number", chainTx.AccountNumber).
+		Uint64("sequence", chainTx.Sequence).
+		Str("path", path).
+		Msg("Unsigned transaction written")
+	return chainTx, nil
+}
+
+// waitForTx looks the transaction hash up until it is included in a block or TxTimeout elapses, returning its height.
+func (c *ChainClient) waitForTx(txType, hash string) (int64, error) {
+	appConfig := c.appConfig
+	client := txtypes.NewServiceClient(c.conn)
+	deadline := time.Now().Add(appConfig.TxTimeout)
+	for {
+		var response *txtypes.GetTxResponse
+		err := c.query("get "+txType, func(ctx context.Context) error {
+			var err error
+			response, err = client.GetTx(ctx, &txtypes.GetTxRequest{Hash: hash})
+			return err
+		})
+		switch {
+		case err == nil && response.TxResponse.Code != 0:
+			return response.TxResponse.Height, withExitCode(ExitValidationError, fmt.Errorf("%w: %s %s failed with code %d: %s", ErrTxFailed, txType, hash, response.TxResponse.Code, response.TxResponse.RawLog))
+		case err == nil:
+			log.Info().Str("type", txType).Str("hash", hash).Int64("height", response.TxResponse.Height).Msg("Transaction included")
+			return response.TxResponse.Height, nil
+		case status.Code(err) != codes.NotFound:
+			return 0, withExitCode(ExitSourceError, fmt.Errorf("unable to look %s %s up: %w", txType, hash, err))
+		}
+
+		if time.Now().After(deadline) {
+			return 0, withExitCode(ExitSourceError, fmt.Errorf("%s %s not included within %s", txType, hash, appConfig.TxTimeout))
+		}
+		select {
+		case <-appConfig.runContext().Done():
+			return 0, appConfig.runContext().Err()
+		case <-time.After(appConfig.TxPollInterval):
+		}
+	}
+}
+
+// txErrorExitCode classifies a failed simulation: a transaction the node refused to execute won't succeed on retry,
+// an unreachable node may.
+func txErrorExitCode(err error) int {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Canceled:
+		return ExitSourceError
+	default:
+		return ExitValidationError
+	}
+}
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Subcommands of the loader running a single pass
+const (
+	// ImportCommand imports the keys into the keyring without generating the relay miner config.
+	ImportCommand string = "import"
+	// GenerateConfigCommand imports the keys and generates the relay miner config.
+	GenerateConfigCommand string = "generate-config"
+	// ListCommand lists the keys of the keyring.
+	ListCommand string = "list"
+	// ConfigCommand groups the commands inspecting the settings, ConfigShowCommand prints them.
+	ConfigCommand     string = "config"
+	ConfigShowCommand string = "show"
+)
+
+// envAnnotation is the flag annotation holding the environment variable a flag mirrors.
+const envAnnotation = "env"
+
+// settingFlag is a command line flag mirroring an environment variable of AppConfig.
+type settingFlag struct {
+	Env   string
+	Usage string
+	// Bool flags may be given without a value (--leader-election means LEADER_ELECTION=true).
+	Bool bool
+}
+
+// settingFlags lists every environment variable of the loader, see the Environment Variables section of the README.
+var settingFlags = []settingFlag{
+	{Env: "LOADER_CONFIG_FILE", Usage: "YAML document holding the settings, overridden by the environment"},
+	{Env: "RUN_MODE", Usage: "once, hold, watch, daemon, operator or webhook"},
+	{Env: "WATCH_DEBOUNCE", Usage: "delay coalescing source changes in watch mode"},
+	{Env: "RESYNC_INTERVAL", Usage: "periodic re-run in watch and daemon modes (0 disables it)"},
+	{Env: "SHUTDOWN_TIMEOUT", Usage: "wait for the in-flight pass on SIGTERM/SIGINT"},
+	{Env: "OPERATOR_NAMESPACE", Usage: "namespace of the WalletKeyImports reconciled in operator mode (empty watches all)"},
+	{Env: "RBAC_PREFLIGHT", Usage: "review the Kubernetes permissions a pass needs before it starts", Bool: true},
+	{Env: "HEALTH_LISTEN_ADDRESS", Usage: "address of the probe server in hold, watch and daemon modes"},
+	{Env: "PPROF_LISTEN_ADDRESS", Usage: "loopback address serving pprof profiles in hold, watch, daemon and operator modes"},
+	{Env: "HEALTH_STUCK_AFTER", Usage: "fail the liveness probe of watch and daemon modes when a pass runs longer"},
+	{Env: "STATUS_SOCKET_PATH", Usage: "unix socket serving the status in watch and daemon modes"},
+	{Env: "LEADER_ELECTION", Usage: "elect a single writer through a Lease in the long-lived modes", Bool: true},
+	{Env: "LEADER_ELECTION_NAMESPACE", Usage: "namespace of the leader election Lease"},
+	{Env: "LEADER_ELECTION_LEASE_NAME", Usage: "name of the leader election Lease"},
+	{Env: "LEADER_ELECTION_LEASE_DURATION", Usage: "wait before taking over an unrenewed Lease"},
+	{Env: "LEADER_ELECTION_RENEW_DEADLINE", Usage: "how long the leader retries renewing the Lease"},
+	{Env: "LEADER_ELECTION_RETRY_PERIOD", Usage: "interval of the Lease acquire and renew attempts"},
+	{Env: "WEBHOOK_LISTEN_ADDRESS", Usage: "address of the admission webhook in webhook mode"},
+	{Env: "WEBHOOK_TLS_CERT_FILE", Usage: "serving certificate of the webhook"},
+	{Env: "WEBHOOK_TLS_KEY_FILE", Usage: "serving certificate key of the webhook"},
+	{Env: "WEBHOOK_LOADER_IMAGE", Usage: "image of the injected init container"},
+	{Env: "WEBHOOK_LOADER_ENV_CONFIGMAP", Usage: "ConfigMap with extra environment variables of the injected init container"},
+	{Env: "WEBHOOK_KEYRING_MOUNT_PATH", Usage: "mount path of the injected keyring volume"},
+	{Env: "WEBHOOK_CONFIG_MOUNT_PATH", Usage: "mount path of the injected config volume"},
+	{Env: "LOG_LEVEL", Usage: "log level (trace, debug, info, warn, error)"},
+	{Env: "LOG_COLOR", Usage: "colorize the logs", Bool: true},
+	{Env: "LOG_FORMAT", Usage: "log format: console or json"},
+	{Env: "LOG_SAMPLE_BURST", Usage: "per-key debug and info lines logged per sampling period (0 disables sampling)"},
+	{Env: "LOG_SAMPLE_PERIOD", Usage: "sampling period of the per-key log lines"},
+	{Env: "LOG_SAMPLE_EVERY", Usage: "past the burst, log one per-key line out of this many (0 drops them)"},
+	{Env: "GENERATE_RELAYMINER_CONFIG", Usage: "update the relay miner config with the imported keys", Bool: true},
+	{Env: "ADDRESS_PREFIX", Usage: "Bech32 address prefix"},
+	{Env: "ADDRESS_ALLOWLIST", Usage: "comma-separated addresses keys may be imported as, any other is rejected"},
+	{Env: "ADDRESS_DENYLIST", Usage: "comma-separated addresses keys are never imported as"},
+	{Env: "KEYRING_APP_NAME", Usage: "Cosmos SDK keyring application name"},
+	{Env: "KEYRING_DIR_MODE", Usage: "mode the keyring dir is created with when missing (octal)"},
+	{Env: "UMASK", Usage: "umask of the process (octal)"},
+	{Env: "PERMISSIONS_CHECK", Usage: "fail, fix or off: what to do with keyring and state files other users can read"},
+	{Env: "KEYRING_MIN_FREE_MB", Usage: "free space the keyring dir must have left before an import (0 disables the check)"},
+	{Env: "LOCK_MEMORY", Usage: "lock the process memory so key material is never swapped out", Bool: true},
+	{Env: "CORE_DUMPS", Usage: "allow core dumps, which may contain key material", Bool: true},
+	{Env: "KEY_SELF_TEST", Usage: "sign and verify a random payload with every imported key", Bool: true},
+	{Env: "FIPS_MODE", Usage: "require the Go FIPS 140-3 module and an approved keyring backend", Bool: true},
+	{Env: "KEYRING_BACKEND", Usage: "Cosmos SDK keyring backend (test, pass, os)"},
+	{Env: "KEYRING_DIR", Usage: "directory of the keyring"},
+	{Env: "CONFIG_SOURCE", Usage: "where the inputs are loaded from: file or kubernetes"},
+	{Env: "KEYS_NAMESPACE", Usage: "namespace(s) of the keys Secret"},
+	{Env: "KEYS_SECRET_NAME", Usage: "name of the keys Secret"},
+	{Env: "KEYS_SECRET_KEY", Usage: "key of keys.json in the keys Secret"},
+	{Env: "KEYS_SECRET_ANNOTATION", Usage: "annotation set on the keys Secret with the addresses and public key fingerprints of the imported keys"},
+	{Env: "KEYS_FILE_PATH", Usage: "path of keys.json"},
+	{Env: "RELAYMINER_CONFIG_NAMESPACE", Usage: "namespace(s) of the base relay miner config"},
+	{Env: "RELAYMINER_CONFIG_NAME", Usage: "name of the base relay miner config ConfigMap"},
+	{Env: "RELAYMINER_CONFIG_KEY", Usage: "key of the base relay miner config in its ConfigMap"},
+	{Env: "RELAYMINER_CONFIG_FILE_PATH", Usage: "path of the base relay miner config"},
+	{Env: "RELAYMINER_CONFIG_FILE_OUTPUT_PATH", Usage: "path of the generated relay miner config (may be a template)"},
+	{Env: "RELAYMINER_CONFIG_OUTPUT_TARGET", Usage: "where the generated config is written: file, configmap or secret"},
+	{Env: "RELAYMINER_CONFIG_OUTPUT_NAMESPACE", Usage: "namespace of the output ConfigMap/Secret"},
+	{Env: "RELAYMINER_CONFIG_OUTPUT_NAME", Usage: "name of the output ConfigMap/Secret"},
+	{Env: "RELAYMINER_CONFIG_OUTPUT_KEY", Usage: "data key of the output ConfigMap/Secret"},
+	{Env: "RELAYMINER_CONFIG_HASH_ANNOTATION", Usage: "annotation holding the SHA-256 of the generated config"},
+	{Env: "RELAYMINER_CONFIG_RELOADER_MATCH", Usage: "annotate the output for Reloader search workloads", Bool: true},
+	{Env: "ROLLOUT_TARGETS", Usage: "comma-separated deployment/<name> or statefulset/<name> workloads to roll"},
+	{Env: "ROLLOUT_NAMESPACE", Usage: "namespace of the rollout targets"},
+	{Env: "BACKEND_DISCOVERY", Usage: "resolve supplier backend urls from labeled Services", Bool: true},
+	{Env: "BACKEND_DISCOVERY_NAMESPACE", Usage: "namespace searched for backend Services"},
+	{Env: "BACKEND_DISCOVERY_LABEL", Usage: "label key holding the service ID on backend Services"},
+	{Env: "BACKEND_DISCOVERY_SCHEME", Usage: "scheme of discovered backend urls"},
+	{Env: "SUPPLIER_STAKE_CONFIG_OUTPUT_DIR", Usage: "directory receiving the supplier stake configs"},
+	{Env: "SUPPLIER_STAKE_AMOUNT", Usage: "stake amount of the supplier stake configs"},
+	{Env: "SUPPLIER_AUTO_STAKE", Usage: "stake the operator keys not staked yet, signing with the imported key", Bool: true},
+	{Env: "APPLICATION_CONFIG_OUTPUT_DIR", Usage: "directory receiving the application configs"},
+	{Env: "APPLICATION_LISTENING_ENDPOINT", Usage: "listening_endpoint of the application configs"},
+	{Env: "APPLICATION_QUERY_NODE_RPC_URL", Usage: "query_node_rpc_url of the application configs"},
+	{Env: "APPLICATION_QUERY_NODE_GRPC_URL", Usage: "query_node_grpc_url of the application configs"},
+	{Env: "APPLICATION_GATEWAY_ADDRESSES", Usage: "comma-separated gateways the application keys are delegated to"},
+	{Env: "APPLICATION_AUTO_DELEGATE", Usage: "delegate the application keys to their gateways, signing with the imported key", Bool: true},
+	{Env: "UNSIGNED_TX_OUTPUT_DIR", Usage: "write the stake and delegation transactions unsigned to this directory instead of broadcasting them"},
+	{Env: "RELAYMINER_OUTPUT_FORMAT", Usage: "format of the generated config: yaml or json"},
+	{Env: "RELAYMINER_CONFIG_DIFF", Usage: "log the paths changed against the previously generated config", Bool: true},
+	{Env: "RELAYMINER_CONFIG_DIFF_VALUES", Usage: "also log the old and new values of the config changes", Bool: true},
+	{Env: "RELAYMINER_CONFIG_DIFF_OUTPUT_PATH", Usage: "path receiving the config changes as JSON"},
+	{Env: "RELAYMINER_CONFIG_BACKUPS", Usage: "timestamped backups of the previous config to keep"},
+	{Env: "RELAYMINER_CONFIG_FILE_MODE", Usage: "octal file mode of the generated config"},
+	{Env: "OUTPUT_UID", Usage: "owner uid of the outputs (-1 leaves it unchanged)"},
+	{Env: "OUTPUT_GID", Usage: "owner gid of the outputs (-1 leaves it unchanged)"},
+	{Env: "SERVICE_GROUPS_NAMESPACE", Usage: "namespace of the service groups ConfigMap"},
+	{Env: "SERVICE_GROUPS_NAME", Usage: "name of the service groups ConfigMap"},
+	{Env: "SERVICE_GROUPS_KEY", Usage: "key of the service groups document in its ConfigMap"},
+	{Env: "SERVICE_GROUPS_FILE_PATH", Usage: "path of the service groups document"},
+	{Env: "TENANTS_NAMESPACE", Usage: "namespace of the tenants manifest ConfigMap"},
+	{Env: "TENANTS_NAME", Usage: "name of the tenants manifest ConfigMap"},
+	{Env: "TENANTS_KEY", Usage: "key of the tenants manifest in its ConfigMap"},
+	{Env: "TENANTS_FILE_PATH", Usage: "path of the tenants manifest"},
+	{Env: "KUBERNETES_RETRY_ATTEMPTS", Usage: "attempts of every Kubernetes read and write"},
+	{Env: "KUBERNETES_RETRY_INITIAL_DELAY", Usage: "delay before the first Kubernetes retry"},
+	{Env: "KUBERNETES_RETRY_MAX_DELAY", Usage: "upper bound of the delay between Kubernetes retries"},
+	{Env: "RUN_TIMEOUT", Usage: "upper bound of a whole pass (0 disables it)"},
+	{Env: "API_WAIT_TIMEOUT", Usage: "wait for the Kubernetes API server at startup (0 disables it)"},
+	{Env: "RUN_LOCK", Usage: "lock taken around every pass: file, lease or none"},
+	{Env: "RUN_LOCK_PATH", Usage: "lock file of the file run lock"},
+	{Env: "RUN_LOCK_LEASE_NAME", Usage: "Lease of the lease run lock"},
+	{Env: "RUN_LOCK_TIMEOUT", Usage: "wait for another instance to release the run lock"},
+	{Env: "STATE_FILE_PATH", Usage: "state file skipping passes with unchanged inputs"},
+	{Env: "COMPLETION_FILE_PATH", Usage: "sentinel file written after every successful pass"},
+	{Env: "COMPLETION_EVENT", Usage: "emit a Kubernetes Event after every pass, on success and on failure", Bool: true},
+	{Env: "EVENT_TARGET", Usage: "object the Events are recorded on: pod or output"},
+	{Env: "REPORT_FILE_PATH", Usage: "JSON summary of every pass: keys, services, skipped entries and errors"},
+	{Env: "REPORT_CONFIGMAP_NAMESPACE", Usage: "namespace of the ConfigMap receiving the run reports"},
+	{Env: "REPORT_CONFIGMAP_NAME", Usage: "ConfigMap receiving the run report of every pass, shared by a fleet"},
+	{Env: "REPORT_CONFIGMAP_KEY", Usage: "key of the run report in the ConfigMap (defaults to <pod name>.json)"},
+	{Env: "REPORT_SIGNING_KEY_FILE", Usage: "file holding the hex secp256k1 private key signing the run reports"},
+	{Env: "REPORT_SIGNING_KEY_NAME", Usage: "name of the keyring key signing the run reports"},
+	{Env: "EXPORT_AGE_RECIPIENTS", Usage: "comma-separated age or SSH recipients encrypting the run reports and config backups"},
+	{Env: "AUDIT_LOG_PATH", Usage: "append-only JSONL audit log of key imports, deletions and config writes"},
+	{Env: "NOTIFY_WEBHOOK_URL", Usage: "webhook notified after every pass"},
+	{Env: "NOTIFY_FORMAT", Usage: "payload of the notification webhook: json or slack"},
+	{Env: "NOTIFY_ON", Usage: "passes notified: always or failure"},
+	{Env: "PUSHGATEWAY_URL", Usage: "Prometheus Pushgateway receiving the metrics of one-shot runs"},
+	{Env: "PUSHGATEWAY_JOB", Usage: "job label of the pushed metrics"},
+	{Env: "PUSHGATEWAY_INSTANCE", Usage: "instance label of the pushed metrics (defaults to the pod name)"},
+	{Env: "PRE_IMPORT_HOOK", Usage: "shell command run before the import, with a JSON summary on stdin"},
+	{Env: "POST_IMPORT_HOOK", Usage: "shell command run after a successful pass, with a JSON summary on stdin"},
+	{Env: "HOOK_TIMEOUT", Usage: "upper bound of every import hook"},
+	{Env: "MAX_KEYS", Usage: "upper bound of the keys the entries may derive (0 disables it)"},
+	{Env: "FAIL_MODE", Usage: "abort or continue past failing keys.json entries"},
+	{Env: "EMPTY_SUPPLIER_MODE", Usage: "warn or fail on suppliers left without signing keys"},
+	{Env: "KEY_EXPIRY_MODE", Usage: "warn or fail on keys entries past their expires_at"},
+	{Env: "KEY_EXPIRY_WARNING", Usage: "how long before their expires_at to start warning about keys entries"},
+	{Env: "CHAIN_GRPC_URL", Usage: "gRPC endpoint of the full node the on-chain features query"},
+	{Env: "CHAIN_GRPC_CA_FILE", Usage: "PEM CA bundle trusted by the TLS connection to the chain node"},
+	{Env: "CHAIN_REST_URL", Usage: "REST (LCD) endpoint of the full node the chain queries fall back to"},
+	{Env: "CHAIN_RPC_URL", Usage: "CometBFT RPC endpoint of the full node, for the generated configs"},
+	{Env: "CHAIN_QUERY_TIMEOUT", Usage: "timeout of every query to the chain node"},
+	{Env: "CHAIN_RETRY_ATTEMPTS", Usage: "attempts of every query to the chain node"},
+	{Env: "CHAIN_RETRY_INITIAL_DELAY", Usage: "delay before the first chain query retry"},
+	{Env: "CHAIN_RETRY_MAX_DELAY", Usage: "upper bound of the delay between chain query retries"},
+	{Env: "CHAIN_ID", Usage: "chain the node must run and the transactions are signed for"},
+	{Env: "TX_GAS_LIMIT", Usage: "gas limit of the transactions, 0 simulates it"},
+	{Env: "TX_GAS_ADJUSTMENT", Usage: "factor scaling the simulated gas of the transactions into their gas limit"},
+	{Env: "TX_GAS_PRICES", Usage: "fees per unit of gas of the transactions"},
+	{Env: "TX_FEES", Usage: "fees of the transactions, overriding TX_GAS_PRICES"},
+	{Env: "TX_MEMO", Usage: "memo of the transactions"},
+	{Env: "TX_BROADCAST_MODE", Usage: "how long a broadcast waits: for the node to check the transaction (sync), not at all (async) or for its inclusion in a block (block)"},
+	{Env: "TX_POLL_INTERVAL", Usage: "how often a broadcast transaction is looked up until included in a block"},
+	{Env: "TX_TIMEOUT", Usage: "how long to wait for a broadcast transaction to be included in a block"},
+	{Env: "SERVICE_CHECK", Usage: "what to do with service ids that don't exist on chain (off, warn or fail)"},
+	{Env: "ACCOUNT_CHECK", Usage: "what to do with imported keys without an account on chain (off, warn or fail)"},
+	{Env: "BALANCE_CHECK", Usage: "what to do with supplier keys holding less than MIN_BALANCE (off, warn or fail)"},
+	{Env: "MIN_BALANCE", Usage: "minimum balance of the operator and owner keys, as a coin (1000000upokt)"},
+	{Env: "DELEGATION_CHECK", Usage: "what to do with application keys not delegated to their gateways (off, warn or fail)"},
+	{Env: "PARAMS_CHECK", Usage: "what to do with stake amounts and gateway delegations the chain params would reject (off, warn or fail)"},
+	{Env: "FAUCET_URL", Usage: "faucet funding the imported keys never funded, on LocalNet and the testnets only"},
+	{Env: "FAUCET_DENOM", Usage: "denom requested from the faucet"},
+	{Env: "FAUCET_INTERVAL", Usage: "minimum delay between two faucet requests"},
+	{Env: "FAUCET_MAX_REQUESTS", Usage: "upper bound of the faucet requests of a pass"},
+	{Env: "STAKE_CHECK", Usage: "what to do with operator keys not staked for the services they are registered to (off, warn or fail)"},
+	{Env: "ONCHAIN_SUPPLIERS", Usage: "add (add) or also remove (sync) suppliers to match the on-chain stakes of the operator keys, or off"},
+	{Env: "ONCHAIN_SUPPLIERS_LISTEN_URL", Usage: "listen_url of the suppliers added from the chain"},
+	{Env: "ONCHAIN_SUPPLIERS_BACKEND_URL", Usage: "backend_url of the suppliers added from the chain, {service_id} is replaced"},
+	{Env: "BACKEND_PREFLIGHT", Usage: "probe every supplier backend_url after generating the config", Bool: true},
+	{Env: "BACKEND_PREFLIGHT_TIMEOUT", Usage: "timeout of each backend probe"},
+	{Env: "BACKEND_PREFLIGHT_FAIL", Usage: "fail the pass on unreachable backends", Bool: true},
+}
+
+// flagName returns the flag mirroring an environment variable, e.g. --keys-file-path for KEYS_FILE_PATH.
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// addSettingFlags registers a flag for every environment variable of the loader. Flags carry no defaults, those
+// stay in loadAppConfig: an unset flag leaves the environment (and .env file) in charge.
+func addSettingFlags(flags *pflag.FlagSet) {
+	for _, setting := range settingFlags {
+		name := flagName(setting.Env)
+		usage := fmt.Sprintf("%s (env %s)", setting.Usage, setting.Env)
+		if setting.Bool {
+			flags.Bool(name, false, usage)
+		} else {
+			flags.String(name, "", usage)
+		}
+		_ = flags.SetAnnotation(name, envAnnotation, []string{setting.Env})
+	}
+}
+
+// applySettingFlags exports the flags given on the command line to their environment variables, so they take
+// precedence over the environment and the .env file.
+func applySettingFlags(flags *pflag.FlagSet) error {
+	var err error
+	flags.Visit(func(flag *pflag.Flag) {
+		if env, ok := flag.Annotations[envAnnotation]; ok && err == nil {
+			err = os.Setenv(env[0], flag.Value.String())
+			settingOrigins[env[0]] = FlagOrigin
+		}
+	})
+	return err
+}
+
+// normalizeArgs rewrites single-dash long flags (-out) to double-dash ones (--out): the subcommands used to parse
+// their flags with the standard library, which accepts both. No flag has a shorthand, so nothing is ambiguous.
+func normalizeArgs(args []string) []string {
+	normalized := make([]string, len(args))
+	for i, arg := range args {
+		if len(arg) > 2 && arg[0] == '-' && arg[1] >= 'a' && arg[1] <= 'z' {
+			arg = "-" + arg
+		}
+		normalized[i] = arg
+	}
+	return normalized
+}
+
+// configArgs tags the errors of a positional arguments validator as invalid settings.
+func configArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return withExitCode(ExitConfigError, err)
+		}
+		return nil
+	}
+}
+
+// loadSettings resolves the settings (flags, then environment, .env file, loader config file and defaults) into an
+// AppConfig, recording where each came from.
+func loadSettings(flags *pflag.FlagSet) (*AppConfig, error) {
+	markSettingOrigins(EnvOrigin)
+	if err := applySettingFlags(flags); err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("error applying flags: %w", err))
+	}
+
+	if err := loadEnv(); err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("error loading env file: %w", err))
+	}
+	markSettingOrigins(DotEnvOrigin)
+
+	// The loader config file fills the settings left unset by the flags, the environment and the .env file
+	if err := applyLoaderConfigFile(); err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("error loading loader config file: %w", err))
+	}
+	markSettingOrigins(LoaderConfigFileOrigin)
+
+	if err := configureLogger(); err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("error configuring logger: %w", err))
+	}
+
+	appConfig, err := loadAppConfig()
+	if err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("error loading config: %w", err))
+	}
+	return appConfig, nil
+}
+
+// setup loads, validates and applies the settings, then waits for the Kubernetes API server.
+func setup(flags *pflag.FlagSet) (*AppConfig, error) {
+	appConfig, err := loadSettings(flags)
+	if err != nil {
+		return nil, withComponent(ComponentSettings, err)
+	}
+	logBuildInfo()
+
+	if err := validateConfig(appConfig); err != nil {
+		return nil, withComponent(ComponentSettings, withExitCode(ExitConfigError, fmt.Errorf("error validating config: %w", err)))
+	}
+
+	// Keep the key material out of swap and core dumps, and the files created without an explicit mode private
+	if err := protectMemory(appConfig); err != nil {
+		return nil, withComponent(ComponentSettings, withExitCode(ExitConfigError, err))
+	}
+	setUmask(appConfig.Umask)
+
+	// Configure the sdk to use the right account prefix
+	configureSdk(appConfig)
+
+	// Don't fail on a control plane or CNI that isn't ready yet
+	if err := waitForAPIServer(appConfig); err != nil {
+		return nil, withComponent(ComponentKubernetes, fmt.Errorf("error waiting for kubernetes API server: %w", err))
+	}
+	return appConfig, nil
+}
+
+// runMode runs the loader in its RUN_MODE.
+func runMode(appConfig *AppConfig) error {
+	switch {
+	case appConfig.RunMode == WatchRunMode, appConfig.RunMode == DaemonRunMode:
+		// Keep running and reconcile every time the sources change (or periodically)
+		return serveWatch(appConfig)
+	case appConfig.RunMode == OperatorRunMode:
+		// Reconcile WalletKeyImport resources
+		return leading(appConfig, func() error { return operate(appConfig) })
+	case appConfig.RunMode == WebhookRunMode:
+		// Inject the loader into labeled pods
+		return serveWebhook(appConfig)
+	case appConfig.RunMode == HoldRunMode:
+		// Run once and stay alive as a sidecar
+		return hold(appConfig)
+	default:
+		return runOnce(appConfig)
+	}
+}
+
+// runOnce runs a single pass, or one per tenant when a tenants manifest is configured.
+func runOnce(appConfig *AppConfig) error {
+	started := time.Now()
+	var err error
+	if tenantsEnabled(appConfig) {
+		err = runTenants(appConfig)
+	} else {
+		err = run(appConfig)
+	}
+
+	recordRun(started, err)
+	pushRunMetrics(appConfig)
+	return err
+}
+
+// listKeys prints the name and address of every key of the keyring, as a table or as JSON.
+func listKeys(appConfig *AppConfig, asJSON bool) error {
+	kr, err := newKeyring(appConfig)
+	if err != nil {
+		return fmt.Errorf("error initializing keyring: %w", err)
+	}
+
+	records, err := kr.List()
+	if err != nil {
+		return withExitCode(ExitKeyringError, fmt.Errorf("error listing keyring: %w", err))
+	}
+
+	keys := make([]ImportedKey, 0, len(records))
+	for _, record := range records {
+		address, err := record.GetAddress()
+		if err != nil {
+			return withExitCode(ExitKeyringError, fmt.Errorf("error reading address of key '%s': %w", record.Name, err))
+		}
+		keys = append(keys, ImportedKey{Name: record.Name, Address: address.String()})
+	}
+
+	if asJSON {
+		content, err := json.MarshalIndent(keys, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal keys: %w", err)
+		}
+		_, err = fmt.Println(string(content))
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "NAME\tADDRESS")
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\n", key.Name, key.Address)
+	}
+	return writer.Flush()
+}
+
+// newRootCommand builds the command line of the loader. Without a subcommand it runs in its RUN_MODE, as it always
+// did; every environment variable can also be given as a flag.
+func newRootCommand() *cobra.Command {
+	var appConfig *AppConfig
+
+	root := &cobra.Command{
+		Use:   "shannon-keyring-loader",
+		Short: "Import wallet keys into a Cosmos SDK keyring and generate the relay miner config",
+		Long: "Imports wallet keys into a Cosmos SDK keyring and registers them in the relay miner config.\n\n" +
+			"Every setting is read from its environment variable (or a .env file, or the LOADER_CONFIG_FILE document) and can\n" +
+			"be overridden by the flag of the same name, e.g. --keys-file-path for KEYS_FILE_PATH. Without a subcommand the\n" +
+			"loader runs in its RUN_MODE.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		CompletionOptions: cobra.CompletionOptions{
+			DisableDefaultCmd: true,
+		},
+		Args: configArgs(cobra.NoArgs),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			appConfig, err = setup(cmd.Flags())
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMode(appConfig)
+		},
+	}
+	addSettingFlags(root.PersistentFlags())
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitConfigError, err)
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   ImportCommand,
+		Short: "Import the keys into the keyring without generating the relay miner config",
+		Args:  configArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appConfig.GenerateRelayMinerConfig = false
+			return runOnce(appConfig)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   GenerateConfigCommand,
+		Short: "Import the keys and generate the relay miner config in a single pass",
+		Args:  configArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appConfig.GenerateRelayMinerConfig = true
+			return runOnce(appConfig)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   InteractiveCommand,
+		Short: "Prompt for a mnemonic or hex key, its derivation range and services, then import it in a single pass",
+		Args:  configArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return interactive(appConfig)
+		},
+	})
+
+	var listJSON bool
+	list := &cobra.Command{
+		Use:   ListCommand,
+		Short: "List the keys of the keyring",
+		Args:  configArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listKeys(appConfig, listJSON)
+		},
+	}
+	list.Flags().BoolVar(&listJSON, "json", false, "print the keys as JSON")
+	root.AddCommand(list)
+
+	var verifyOut string
+	var verifyStrict bool
+	verify := &cobra.Command{
+		Use:   VerifyCommand,
+		Short: "Validate keys.json and the base relay miner config without writing anything",
+		Args:  configArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyCommand(appConfig, verifyOut, verifyStrict)
+		},
+	}
+	verify.Flags().StringVar(&verifyOut, "out", "", "write the findings report to this file instead of stdout")
+	verify.Flags().BoolVar(&verifyStrict, "strict", false, "fail on warnings too")
+	root.AddCommand(verify)
+
+	var configJSON bool
+	config := &cobra.Command{
+		Use:   ConfigCommand,
+		Short: "Inspect the settings of the loader",
+		Args:  configArgs(cobra.NoArgs),
+	}
+	show := &cobra.Command{
+		Use:   ConfigShowCommand,
+		Short: "Print the effective settings and where each came from, without validating them or running anything",
+		Args:  configArgs(cobra.NoArgs),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			appConfig, err = loadSettings(cmd.Flags())
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := showConfig(configJSON); err != nil {
+				return err
+			}
+			if err := validateConfig(appConfig); err != nil {
+				return withExitCode(ExitConfigError, fmt.Errorf("error validating config: %w", err))
+			}
+			return nil
+		},
+	}
+	show.Flags().BoolVar(&configJSON, "json", false, "print the settings as JSON")
+	config.AddCommand(show)
+	root.AddCommand(config)
+
+	var doctorJSON bool
+	doctorCmd := &cobra.Command{
+		Use:   DoctorCommand,
+		Short: "Check credentials, RBAC, keyring backend and inputs before anything is mutated",
+		Args:  configArgs(cobra.NoArgs),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			appConfig, err = loadSettings(cmd.Flags())
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doctorCommand(appConfig, doctorJSON)
+		},
+	}
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "print the report as JSON")
+	root.AddCommand(doctorCmd)
+
+	var initFormat string
+	var initForce bool
+	initCmd := &cobra.Command{
+		Use:   InitCommand + " [dir]",
+		Short: "Write a sample keys document, base relay miner config and loader config file wired together",
+		Args:  configArgs(cobra.MaximumNArgs(1)),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applySettingFlags(cmd.Flags()); err != nil {
+				return withExitCode(ExitConfigError, fmt.Errorf("error applying flags: %w", err))
+			}
+			if err := configureLogger(); err != nil {
+				return withExitCode(ExitConfigError, fmt.Errorf("error configuring logger: %w", err))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			return scaffold(dir, initFormat, initForce)
+		},
+	}
+	initCmd.Flags().StringVar(&initFormat, "format", ScaffoldYAML, "format of the keys document: yaml (commented) or json")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite existing files")
+	root.AddCommand(initCmd)
+
+	var probeMaxAge time.Duration
+	probeCmd := &cobra.Command{
+		Use:   ProbeCommand,
+		Short: "Exit 0 when a pass completed (recently enough with --max-age) and 1 otherwise, for exec probes",
+		Args:  configArgs(cobra.NoArgs),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			appConfig, err = loadSettings(cmd.Flags())
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return probe(appConfig, probeMaxAge)
+		},
+	}
+	probeCmd.Flags().DurationVar(&probeMaxAge, "max-age", 0, "fail when the last pass completed longer ago (needs COMPLETION_FILE_PATH)")
+	root.AddCommand(probeCmd)
+
+	root.AddCommand(&cobra.Command{
+		Use:   StatusCommand,
+		Short: "Print the status of the loader running in watch or daemon mode, read from STATUS_SOCKET_PATH",
+		Args:  configArgs(cobra.NoArgs),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			appConfig, err = loadSettings(cmd.Flags())
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return statusCommand(appConfig)
+		},
+	})
+
+	var versionJSON bool
+	versionCmd := &cobra.Command{
+		Use:   VersionCommand,
+		Short: "Print the version, commit, build date and relay miner config schema of the loader",
+		Args:  configArgs(cobra.NoArgs),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return versionCommand(versionJSON)
+		},
+	}
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "print the build metadata as JSON")
+	root.AddCommand(versionCmd)
+
+	var planOut string
+	plan := &cobra.Command{
+		Use:   PlanCommand,
+		Short: "Compute the changes a pass would make as a machine-readable plan",
+		Args:  configArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return planCommand(appConfig, planOut)
+		},
+	}
+	plan.Flags().StringVar(&planOut, "out", "", "write the plan to this file instead of stdout")
+	root.AddCommand(plan)
+
+	root.AddCommand(&cobra.Command{
+		Use:   ApplyCommand + " <plan file>",
+		Short: "Execute a plan previously written by plan",
+		Args:  configArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return applyCommand(appConfig, args[0])
+		},
+	})
+
+	return root
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons of the Events emitted after a pass
+const (
+	completionEventReason = "KeyringProvisioned"
+	failureEventReason    = "KeyringProvisioningFailed"
+)
+
+// Objects the Events of a pass are recorded on
+const (
+	// EventTargetPod records them on the loader's pod (default).
+	EventTargetPod string = "pod"
+	// EventTargetOutput records them on the ConfigMap or Secret receiving the generated config, falling back to the
+	// pod when the config is written to a file.
+	EventTargetOutput string = "output"
+)
+
+// eventObject returns the object the Events of a pass are recorded on, see EventTarget.
+func eventObject(appConfig *AppConfig) corev1.ObjectReference {
+	if appConfig.EventTarget == EventTargetOutput && appConfig.GenerateRelayMinerConfig {
+		switch appConfig.RelayMinerConfigOutputTarget {
+		case ConfigMapSource:
+			return corev1.ObjectReference{Kind: "ConfigMap", APIVersion: "v1", Namespace: appConfig.RelayMinerConfigOutputNamespace, Name: appConfig.RelayMinerConfigOutputName}
+		case SecretSource:
+			return corev1.ObjectReference{Kind: "Secret", APIVersion: "v1", Namespace: appConfig.RelayMinerConfigOutputNamespace, Name: appConfig.RelayMinerConfigOutputName}
+		}
+	}
+	return corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: podNamespace(), Name: podName()}
+}
+
+// completionMessage summarizes a successful pass for its Event.
+func completionMessage(appConfig *AppConfig, importedKeys []ImportedKey) string {
+	message := fmt.Sprintf("Imported %d keys into the keyring", len(importedKeys))
+	if !appConfig.GenerateRelayMinerConfig {
+		return message
+	}
+	switch appConfig.RelayMinerConfigOutputTarget {
+	case FileSource:
+		return message + " and generated the relay miner config"
+	default:
+		return message + fmt.Sprintf(" and updated the relay miner config in %s %s/%s", appConfig.RelayMinerConfigOutputTarget,
+			appConfig.RelayMinerConfigOutputNamespace, appConfig.RelayMinerConfigOutputName)
+	}
+}
+
+// CompletionMarker is the content of the completion sentinel file.
+type CompletionMarker struct {
+	CompletedAt time.Time `json:"completed_at"`
+	Keys        int       `json:"keys"`
+}
+
+// signalCompletion tells sidecars and startup probes that the keyring and configs are provisioned: it writes the
+// completion sentinel file and/or emits a Kubernetes Event on the pod, as configured.
+func signalCompletion(appConfig *AppConfig, importedKeys []ImportedKey) error {
+	if err := checkRunContext(appConfig); err != nil {
+		return err
+	}
+	appConfig.status.completed(len(importedKeys))
+	keysProvisioned.Add(float64(len(importedKeys)))
+
+	if appConfig.CompletionFilePath != "" {
+		marker, err := json.Marshal(CompletionMarker{CompletedAt: time.Now().UTC(), Keys: len(importedKeys)})
+		if err != nil {
+			return fmt.Errorf("unable to marshal completion marker: %w", err)
+		}
+		if err := writeFileAtomic(appConfig.CompletionFilePath, marker, 0644); err != nil {
+			return fmt.Errorf("unable to write completion file: %w", err)
+		}
+		if err := chownPath(appConfig.CompletionFilePath, appConfig.OutputUid, appConfig.OutputGid, false); err != nil {
+			return err
+		}
+		log.Info().Str("path", appConfig.CompletionFilePath).Msg("Completion file written")
+	}
+
+	if appConfig.CompletionEvent {
+		message := completionMessage(appConfig, importedKeys)
+		if err := emitEvent(eventObject(appConfig), corev1.EventTypeNormal, completionEventReason, message); err != nil {
+			return err
+		}
+		log.Info().Str("reason", completionEventReason).Msg("Completion event emitted")
+	}
+
+	notify(appConfig, newRunNotification(appConfig, importedKeys, nil))
+	return nil
+}
+
+// signalFailure reports a failed pass: it emits a Warning Event carrying the error, when CompletionEvent is set,
+// and fires the notification webhook. The pass already failed, so a failure to report it is only logged.
+func signalFailure(appConfig *AppConfig, err error) {
+	notify(appConfig, newRunNotification(appConfig, nil, err))
+	if !appConfig.CompletionEvent {
+		return
+	}
+
+	message := fmt.Sprintf("Pass failed with exit code %d: %s", exitCode(err), err)
+	// Event messages are capped at 1kB by the API server
+	if len(message) > 1024 {
+		message = message[:1021] + "..."
+	}
+	if eventErr := emitEvent(eventObject(appConfig), corev1.EventTypeWarning, failureEventReason, message); eventErr != nil {
+		log.Warn().Err(eventErr).Msg("Unable to emit failure event")
+		return
+	}
+	log.Info().Str("reason", failureEventReason).Msg("Failure event emitted")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	apptypes "github.com/pokt-network/poktroll/x/application/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the gateway delegation check
+const (
+	DelegationCheckOff  string = "off"
+	DelegationCheckWarn string = "warn"
+	DelegationCheckFail string = "fail"
+)
+
+// MissingDelegation is an application key not delegated to some of its gateway_addresses on chain.
+type MissingDelegation struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// Staked is unset when the application isn't staked on chain, so it can't be delegated at all.
+	Staked           bool     `json:"staked"`
+	GatewayAddresses []string `json:"gateway_addresses"`
+}
+
+// checkDelegations queries the application module of the chain node for every imported application key with
+// gateway addresses and returns the ones not delegated to all of them: reported with a warning, or failing the pass
+// in DelegationCheckFail. A gateway only relays for the applications delegated to it.
+func checkDelegations(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey) ([]MissingDelegation, error) {
+	if appConfig.DelegationCheck == DelegationCheckOff {
+		return nil, nil
+	}
+
+	applicationKeys := make([]ImportedKey, 0, len(importedKeys))
+	for _, importedKey := range importedKeys {
+		if importedKey.Role == ApplicationRole && len(importedKey.GatewayAddresses) > 0 {
+			applicationKeys = append(applicationKeys, importedKey)
+		}
+	}
+	if len(applicationKeys) == 0 {
+		return nil, nil
+	}
+
+	missing := make([]MissingDelegation, 0)
+	for _, importedKey := range applicationKeys {
+		application, err := chain.application(importedKey.Address)
+		if err != nil {
+			return nil, err
+		}
+		if application == nil {
+			keyLog.Warn().
+				Str("name", importedKey.Name).
+				Str("address", importedKey.Address).
+				Msg("Application key not staked, it can't be delegated to its gateways")
+			missing = append(missing, MissingDelegation{Name: importedKey.Name, Address: importedKey.Address, GatewayAddresses: importedKey.GatewayAddresses})
+			continue
+		}
+
+		delegation := MissingDelegation{Name: importedKey.Name, Address: importedKey.Address, Staked: true, GatewayAddresses: []string{}}
+		for _, gatewayAddress := range importedKey.GatewayAddresses {
+			if !slices.Contains(application.DelegateeGatewayAddresses, gatewayAddress) {
+				delegation.GatewayAddresses = append(delegation.GatewayAddresses, gatewayAddress)
+			}
+		}
+		if len(delegation.GatewayAddresses) == 0 {
+			keyLog.Debug().Str("name", importedKey.Name).Strs("gateway_addresses", importedKey.GatewayAddresses).Msg("Application delegated to its gateways")
+			continue
+		}
+		keyLog.Warn().
+			Str("name", importedKey.Name).
+			Str("address", importedKey.Address).
+			Strs("gateway_addresses", delegation.GatewayAddresses).
+			Msg("Application key not delegated to its gateways")
+		missing = append(missing, delegation)
+	}
+
+	if len(missing) == 0 {
+		log.Info().Int("keys", len(applicationKeys)).Msg("All application keys delegated to their gateways")
+		return missing, nil
+	}
+	if appConfig.DelegationCheck == DelegationCheckFail {
+		return missing, withExitCode(ExitValidationError, fmt.Errorf("%w: %d application keys", ErrMissingDelegation, len(missing)))
+	}
+	log.Warn().Int("keys", len(missing)).Msg("Application keys missing gateway delegations")
+	return missing, nil
+}
+
+// delegateApplications delegates every imported application key with gateway addresses to the gateways it isn't
+// delegated to yet, completing the onboarding of applications in one pass: the delegate-to-gateway messages of a key
+// are signed by the key itself and broadcast as a single transaction. Applications that aren't staked can't be
+// delegated, they are left for DelegationCheck to report.
+func delegateApplications(appConfig *AppConfig, chain *ChainClient, walletKeyring keyring.Keyring, importedKeys []ImportedKey) ([]ChainTx, error) {
+	if !appConfig.ApplicationAutoDelegate {
+		return nil, nil
+	}
+
+	transactions := make([]ChainTx, 0)
+	for _, importedKey := range importedKeys {
+		if importedKey.Role != ApplicationRole || len(importedKey.GatewayAddresses) == 0 {
+			continue
+		}
+
+		application, err := chain.application(importedKey.Address)
+		if err != nil {
+			return transactions, err
+		}
+		if application == nil {
+			keyLog.Warn().Str("address", importedKey.Address).Msg("Skipping gateway delegation of application key not staked")
+			continue
+		}
+
+		msgs := make([]sdk.Msg, 0, len(importedKey.GatewayAddresses))
+		gatewayAddresses := make([]string, 0, len(importedKey.GatewayAddresses))
+		for _, gatewayAddress := range importedKey.GatewayAddresses {
+			if !slices.Contains(application.DelegateeGatewayAddresses, gatewayAddress) {
+				msgs = append(msgs, apptypes.NewMsgDelegateToGateway(importedKey.Address, gatewayAddress))
+				gatewayAddresses = append(gatewayAddresses, gatewayAddress)
+			}
+		}
+		if len(msgs) == 0 {
+			keyLog.Debug().Str("address", importedKey.Address).Msg("Application already delegated to its gateways")
+			continue
+		}
+
+		chainTx, err := chain.broadcastTx(walletKeyring, importedKey.Name, TxDelegateToGateway, msgs...)
+		if chainTx.Hash != "" || chainTx.File != "" {
+			transactions = append(transactions, chainTx)
+		}
+		if err != nil {
+			return transactions, fmt.Errorf("error delegating application %s: %w", importedKey.Address, err)
+		}
+		if chainTx.File != "" {
+			continue
+		}
+		log.Info().
+			Str("address", importedKey.Address).
+			Strs("gateway_addresses", gatewayAddresses).
+			Str("hash", chainTx.Hash).
+			Msg("Application delegated to gateways")
+	}
+	return transactions, nil
+}
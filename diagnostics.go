@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// dumpState logs the internal state of the process in one structured entry: the effective settings (redacted), the
+// status of the loop with its counts and last error, and the runtime figures that tell a stuck loop from a busy one.
+func dumpState(appConfig *AppConfig) {
+	settings := zerolog.Dict()
+	origins := zerolog.Dict()
+	for _, setting := range effectiveSettings() {
+		settings.Str(setting.Name, setting.Value)
+		if setting.Origin != DefaultOrigin {
+			origins.Str(setting.Name, setting.Origin)
+		}
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	event := log.Info().
+		Dict("settings", settings).
+		Dict("origins", origins).
+		Int("goroutines", runtime.NumGoroutine()).
+		Uint64("heap_alloc_bytes", memory.HeapAlloc)
+	if appConfig.status != nil {
+		event = event.Interface("status", appConfig.status.snapshot())
+	}
+	event.Msg("State dump")
+}
+
+// dumpStateOnSignal logs the internal state on every SIGUSR1 until ctx is done, for debugging long-lived loops in
+// production without restarting them: `kill -USR1 <pid>`.
+func dumpStateOnSignal(ctx context.Context, appConfig *AppConfig) {
+	if len(dumpStateSignals) == 0 {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, dumpStateSignals...)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				dumpState(appConfig)
+			}
+		}
+	}()
+}
+
+// checkLoopbackAddress fails unless address listens on the loopback interface only.
+func checkLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%s is not a loopback address", address)
+}
+
+// servePprof serves the net/http/pprof handlers on PprofListenAddress, unless disabled, so CPU and heap profiles of
+// large derivations can be captured in place: `kubectl port-forward` then `go tool pprof`. The returned function
+// stops the server.
+func servePprof(appConfig *AppConfig) func() {
+	if appConfig.PprofListenAddress == "" {
+		return func() {}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Addr: appConfig.PprofListenAddress, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		log.Info().Str("address", server.Addr).Msg("Serving pprof")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("pprof server failed")
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
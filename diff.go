@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Kinds of config changes reported by the diff
+const (
+	ChangeAdded   string = "added"
+	ChangeRemoved string = "removed"
+	ChangeUpdated string = "updated"
+)
+
+// ConfigChange is a single difference between the previous and the newly generated config.
+type ConfigChange struct {
+	Path     string      `json:"path"`
+	Kind     string      `json:"kind"`
+	OldValue interface{} `json:"old_value,omitempty"`
+	NewValue interface{} `json:"new_value,omitempty"`
+}
+
+// credentialConfigFields are the relay miner config fields whose values are credentials (backend basic auth and
+// headers like Authorization), redacted from the reported changes.
+var credentialConfigFields = map[string]bool{
+	"authentication": true,
+	"headers":        true,
+}
+
+// pathIndexPattern matches the sequence indexes of a change path.
+var pathIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// diffDocuments walks two documents (as produced by jsonCompatible) and returns every difference between them, keyed by field path
+// (e.g. `suppliers[1].signing_key_names[0]`). Sequences are compared index by index.
+func diffDocuments(path string, previous, current interface{}) []ConfigChange {
+	changes := make([]ConfigChange, 0)
+
+	previousFields, previousIsMap := previous.(map[string]interface{})
+	currentFields, currentIsMap := current.(map[string]interface{})
+	if previousIsMap && currentIsMap {
+
+		names := make([]string, 0, len(previousFields)+len(currentFields))
+		for name := range previousFields {
+			names = append(names, name)
+		}
+		for name := range currentFields {
+			if _, ok := previousFields[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			previousValue, inPrevious := previousFields[name]
+			currentValue, inCurrent := currentFields[name]
+			switch {
+			case !inPrevious:
+				changes = append(changes, ConfigChange{Path: childPath, Kind: ChangeAdded, NewValue: currentValue})
+			case !inCurrent:
+				changes = append(changes, ConfigChange{Path: childPath, Kind: ChangeRemoved, OldValue: previousValue})
+			default:
+				changes = append(changes, diffDocuments(childPath, previousValue, currentValue)...)
+			}
+		}
+		return changes
+	}
+
+	previousSlice, previousIsSlice := previous.([]interface{})
+	currentSlice, currentIsSlice := current.([]interface{})
+	if previousIsSlice && currentIsSlice {
+		for i := 0; i < len(previousSlice) || i < len(currentSlice); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(previousSlice):
+				changes = append(changes, ConfigChange{Path: childPath, Kind: ChangeAdded, NewValue: currentSlice[i]})
+			case i >= len(currentSlice):
+				changes = append(changes, ConfigChange{Path: childPath, Kind: ChangeRemoved, OldValue: previousSlice[i]})
+			default:
+				changes = append(changes, diffDocuments(childPath, previousSlice[i], currentSlice[i])...)
+			}
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(previous, current) {
+		changes = append(changes, ConfigChange{
+			Path:     path,
+			Kind:     ChangeUpdated,
+			OldValue: previous,
+			NewValue: current,
+		})
+	}
+	return changes
+}
+
+// configChanges returns the changes between the previously generated config and the newly generated content.
+// A missing (nil) or unparsable previous config is treated as empty.
+func configChanges(previousContent, updatedContent []byte) ([]ConfigChange, error) {
+	var previous, current interface{}
+
+	if err := yaml.Unmarshal(previousContent, &previous); err != nil {
+		log.Warn().Err(err).Msg("Unable to parse previous config for diff")
+		previous = nil
+	}
+
+	if err := yaml.Unmarshal(updatedContent, &current); err != nil {
+		return nil, fmt.Errorf("unable to parse generated config for diff: %w", err)
+	}
+	if previous == nil {
+		previous = map[interface{}]interface{}{}
+	}
+
+	return diffDocuments("", jsonCompatible(previous), jsonCompatible(current)), nil
+}
+
+// redactConfigChanges returns changes with the values of credential fields (see credentialConfigFields) redacted,
+// whether the change is the field itself, below it or a document holding it.
+func redactConfigChanges(changes []ConfigChange) []ConfigChange {
+	redacted := make([]ConfigChange, 0, len(changes))
+	for _, change := range changes {
+		change.OldValue = redactConfigValue(change.Path, change.OldValue)
+		change.NewValue = redactConfigValue(change.Path, change.NewValue)
+		redacted = append(redacted, change)
+	}
+	return redacted
+}
+
+// redactConfigValue returns a copy of the value at path with the values of credential fields redacted.
+func redactConfigValue(path string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	for _, field := range strings.Split(pathIndexPattern.ReplaceAllString(path, ""), ".") {
+		if credentialConfigFields[field] {
+			return redactedSecret
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(typed))
+		for name, fieldValue := range typed {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			fields[name] = redactConfigValue(childPath, fieldValue)
+		}
+		return fields
+	case []interface{}:
+		items := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			items = append(items, redactConfigValue(path, item))
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+// reportConfigDiff compares the previously generated config with the newly generated content, logs the path of
+// every change (and their values if RelayMinerConfigDiffValues is set) and, if RelayMinerConfigDiffOutputPath is
+// set, writes the changes there as JSON. Credential values are redacted from both.
+func reportConfigDiff(appConfig *AppConfig, previousContent, updatedContent []byte) error {
+	if !appConfig.RelayMinerConfigDiff {
+		return nil
+	}
+
+	changes, err := configChanges(previousContent, updatedContent)
+	if err != nil {
+		return err
+	}
+	changes = redactConfigChanges(changes)
+	for _, change := range changes {
+		event := log.Info().
+			Str("path", change.Path).
+			Str("kind", change.Kind)
+		if appConfig.RelayMinerConfigDiffValues {
+			event = event.
+				Interface("old_value", change.OldValue).
+				Interface("new_value", change.NewValue)
+		}
+		event.Msg("Relay miner config change")
+	}
+	log.Info().Int("changes", len(changes)).Msg("Relay miner config diff computed")
+
+	if appConfig.RelayMinerConfigDiffOutputPath == "" {
+		return nil
+	}
+
+	report, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal config diff: %w", err)
+	}
+	if err := writeFileAtomic(appConfig.RelayMinerConfigDiffOutputPath, report, 0600); err != nil {
+		return fmt.Errorf("unable to write config diff: %w", err)
+	}
+
+	log.Debug().Str("path", appConfig.RelayMinerConfigDiffOutputPath).Msg("Relay miner config diff written")
+	return nil
+}
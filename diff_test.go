@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous interface{}
+		current  interface{}
+		want     []ConfigChange
+	}{
+		{
+			name:     "identical",
+			previous: map[string]interface{}{"default_signing_key_names": []interface{}{"a"}},
+			current:  map[string]interface{}{"default_signing_key_names": []interface{}{"a"}},
+			want:     []ConfigChange{},
+		},
+		{
+			name:     "added and removed fields",
+			previous: map[string]interface{}{"metrics": map[string]interface{}{"enabled": true}},
+			current:  map[string]interface{}{"pprof": map[string]interface{}{"enabled": false}},
+			want: []ConfigChange{
+				{Path: "metrics", Kind: ChangeRemoved, OldValue: map[string]interface{}{"enabled": true}},
+				{Path: "pprof", Kind: ChangeAdded, NewValue: map[string]interface{}{"enabled": false}},
+			},
+		},
+		{
+			name: "nested sequences",
+			previous: map[string]interface{}{"suppliers": []interface{}{
+				map[string]interface{}{"signing_key_names": []interface{}{"a", "b"}},
+			}},
+			current: map[string]interface{}{"suppliers": []interface{}{
+				map[string]interface{}{"signing_key_names": []interface{}{"a", "c", "d"}},
+			}},
+			want: []ConfigChange{
+				{Path: "suppliers[0].signing_key_names[1]", Kind: ChangeUpdated, OldValue: "b", NewValue: "c"},
+				{Path: "suppliers[0].signing_key_names[2]", Kind: ChangeAdded, NewValue: "d"},
+			},
+		},
+		{
+			name:     "shorter sequence",
+			previous: []interface{}{"a", "b"},
+			current:  []interface{}{"a"},
+			want:     []ConfigChange{{Path: "[1]", Kind: ChangeRemoved, OldValue: "b"}},
+		},
+		{
+			name:     "type change",
+			previous: map[string]interface{}{"pocket_node": "tcp://node:26657"},
+			current:  map[string]interface{}{"pocket_node": map[string]interface{}{"query_node_rpc_url": "tcp://node:26657"}},
+			want: []ConfigChange{{
+				Path:     "pocket_node",
+				Kind:     ChangeUpdated,
+				OldValue: "tcp://node:26657",
+				NewValue: map[string]interface{}{"query_node_rpc_url": "tcp://node:26657"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffDocuments("", tt.previous, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffDocuments = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactConfigChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change ConfigChange
+		want   ConfigChange
+	}{
+		{
+			name:   "plain field",
+			change: ConfigChange{Path: "suppliers[0].service_config.backend_url", Kind: ChangeUpdated, OldValue: "http://a:8545", NewValue: "http://b:8545"},
+			want:   ConfigChange{Path: "suppliers[0].service_config.backend_url", Kind: ChangeUpdated, OldValue: "http://a:8545", NewValue: "http://b:8545"},
+		},
+		{
+			name:   "authentication password",
+			change: ConfigChange{Path: "suppliers[0].service_config.authentication.password", Kind: ChangeUpdated, OldValue: "old", NewValue: "new"},
+			want:   ConfigChange{Path: "suppliers[0].service_config.authentication.password", Kind: ChangeUpdated, OldValue: redactedSecret, NewValue: redactedSecret},
+		},
+		{
+			name:   "header",
+			change: ConfigChange{Path: "suppliers[1].service_config.headers.Authorization", Kind: ChangeAdded, NewValue: "Bearer token"},
+			want:   ConfigChange{Path: "suppliers[1].service_config.headers.Authorization", Kind: ChangeAdded, NewValue: redactedSecret},
+		},
+		{
+			name: "added supplier holding credentials",
+			change: ConfigChange{Path: "suppliers[2]", Kind: ChangeAdded, NewValue: map[string]interface{}{
+				"service_id": "anvil",
+				"service_config": map[string]interface{}{
+					"backend_url":    "http://anvil:8547",
+					"authentication": map[string]interface{}{"username": "user", "password": "secret"},
+					"headers":        map[string]interface{}{"X-Api-Key": "key"},
+				},
+			}},
+			want: ConfigChange{Path: "suppliers[2]", Kind: ChangeAdded, NewValue: map[string]interface{}{
+				"service_id": "anvil",
+				"service_config": map[string]interface{}{
+					"backend_url":    "http://anvil:8547",
+					"authentication": redactedSecret,
+					"headers":        redactedSecret,
+				},
+			}},
+		},
+		{
+			name: "whole document",
+			change: ConfigChange{Path: "", Kind: ChangeUpdated, OldValue: nil, NewValue: []interface{}{
+				map[string]interface{}{"headers": map[string]interface{}{"Authorization": "Bearer token"}},
+			}},
+			want: ConfigChange{Path: "", Kind: ChangeUpdated, NewValue: []interface{}{
+				map[string]interface{}{"headers": redactedSecret},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactConfigChanges([]ConfigChange{tt.change})
+			if !reflect.DeepEqual(got, []ConfigChange{tt.want}) {
+				t.Errorf("redactConfigChanges = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations refining the backend url built from a discovered Service
+const (
+	backendSchemeAnnotation = "pokt.network/backend-scheme"
+	backendPortAnnotation   = "pokt.network/backend-port"
+	backendPathAnnotation   = "pokt.network/backend-path"
+)
+
+// serviceBackendUrl builds the in-cluster backend url of a Kubernetes Service.
+// The scheme, port (name or number) and path can be set through annotations on the Service,
+// otherwise the default scheme and the first Service port are used.
+func serviceBackendUrl(service corev1.Service, defaultScheme string) (string, error) {
+	scheme := defaultScheme
+	if v := service.Annotations[backendSchemeAnnotation]; v != "" {
+		scheme = v
+	}
+
+	if len(service.Spec.Ports) == 0 {
+		return "", fmt.Errorf("service '%s' has no ports", service.Name)
+	}
+	port := service.Spec.Ports[0].Port
+	if v := service.Annotations[backendPortAnnotation]; v != "" {
+		found := false
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.Name == v || strconv.Itoa(int(servicePort.Port)) == v {
+				port = servicePort.Port
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("service '%s' has no port '%s'", service.Name, v)
+		}
+	}
+
+	return fmt.Sprintf("%s://%s.%s.svc:%d%s", scheme, service.Name, service.Namespace, port, service.Annotations[backendPathAnnotation]), nil
+}
+
+// discoverSupplierBackends resolves suppliers[].service_config.backend_url from the Kubernetes Services labeled
+// with the supplier service ID (e.g. pokt.network/service-id=eth). Suppliers without a matching Service keep
+// the backend url of the base config, while more than one matching Service is an error.
+func discoverSupplierBackends(appConfig *AppConfig, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	if !appConfig.GenerateRelayMinerConfig || !appConfig.BackendDiscovery {
+		return nil
+	}
+
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	namespace := appConfig.BackendDiscoveryNamespace
+	log.Info().
+		Str("namespace", namespace).
+		Str("label", appConfig.BackendDiscoveryLabel).
+		Msg("Discovering supplier backends from Kubernetes Services")
+
+	for j := range relayMinerConfig.Suppliers {
+		supplierConfig := &relayMinerConfig.Suppliers[j]
+		selector := fmt.Sprintf("%s=%s", appConfig.BackendDiscoveryLabel, supplierConfig.ServiceId)
+
+		var services *corev1.ServiceList
+		err := retryKubernetes(appConfig, "list services", func() (err error) {
+			services, err = clientset.CoreV1().Services(namespace).List(appConfig.runContext(), v1.ListOptions{LabelSelector: selector})
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Str("namespace", namespace).Str("selector", selector).Msg("Failed to list Services")
+			return fmt.Errorf("error listing services '%s' in namespace '%s': %w", selector, namespace, err)
+		}
+
+		switch len(services.Items) {
+		case 0:
+			log.Debug().
+				Str("service_id", supplierConfig.ServiceId).
+				Msg("No Service found for supplier, keeping configured backend url")
+			continue
+		case 1:
+		default:
+			return fmt.Errorf("found %d services labeled %s, expected at most one", len(services.Items), selector)
+		}
+
+		backendUrl, err := serviceBackendUrl(services.Items[0], appConfig.BackendDiscoveryScheme)
+		if err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("service_id", supplierConfig.ServiceId).
+			Str("previous", supplierConfig.ServiceConfig.BackendUrl).
+			Str("backend_url", backendUrl).
+			Msg("Discovered supplier backend")
+		supplierConfig.ServiceConfig.BackendUrl = backendUrl
+	}
+
+	return nil
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DoctorCommand checks the environment of the loader without mutating anything.
+const DoctorCommand string = "doctor"
+
+// doctorTimeout bounds every Kubernetes request of the doctor checks.
+const doctorTimeout = 10 * time.Second
+
+// Statuses of a doctor check
+const (
+	CheckPass string = "pass"
+	CheckFail string = "fail"
+	CheckSkip string = "skip"
+)
+
+// DoctorCheck is the outcome of one doctor check.
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DoctorReport lists the outcome of every doctor check.
+type DoctorReport struct {
+	Failures int           `json:"failures"`
+	Checks   []DoctorCheck `json:"checks"`
+}
+
+// add records the outcome of a check: pass when err is nil, fail otherwise.
+func (r *DoctorReport) add(name string, err error, detail string) {
+	check := DoctorCheck{Name: name, Status: CheckPass, Detail: detail}
+	if err != nil {
+		check.Status = CheckFail
+		check.Detail = err.Error()
+		r.Failures++
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// skip records a check that doesn't apply to the configuration.
+func (r *DoctorReport) skip(name, reason string) {
+	r.Checks = append(r.Checks, DoctorCheck{Name: name, Status: CheckSkip, Detail: reason})
+}
+
+// resourceAccess is a Kubernetes permission the configuration needs.
+type resourceAccess struct {
+	Verb        string
+	Group       string
+	Resource    string
+	Subresource string
+	Namespace   string
+	Name        string
+}
+
+// String implements fmt.Stringer, e.g. `get secrets/pocket-keys in default`.
+func (a resourceAccess) String() string {
+	resource := a.Resource
+	if a.Group != "" {
+		resource += "." + a.Group
+	}
+	if a.Name != "" {
+		resource += "/" + a.Name
+	}
+	if a.Subresource != "" {
+		resource += "/" + a.Subresource
+	}
+	if a.Namespace == "" {
+		return fmt.Sprintf("%s %s cluster-wide", a.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in %s", a.Verb, resource, a.Namespace)
+}
+
+// sourceAccess returns the permissions reading a ConfigMap or Secret from a namespace setting needs: get in every
+// listed namespace, or a cluster-wide list for wildcards.
+func sourceAccess(resource, namespaces, name string) []resourceAccess {
+	if hasNamespaceWildcard(namespaces) {
+		return []resourceAccess{{Verb: "list", Resource: resource}}
+	}
+	accesses := make([]resourceAccess, 0)
+	for _, namespace := range namespacePatterns(namespaces) {
+		accesses = append(accesses, resourceAccess{Verb: "get", Resource: resource, Namespace: namespace, Name: name})
+	}
+	return accesses
+}
+
+// watchAccess returns the permissions the informer watching a ConfigMap or Secret needs: list and watch in the
+// namespace, or cluster-wide when the namespace setting selects several.
+func watchAccess(resource, namespaces, name string) []resourceAccess {
+	namespace := namespaces
+	if isMultiNamespace(namespaces) {
+		namespace = ""
+	}
+	return []resourceAccess{
+		{Verb: "list", Resource: resource, Namespace: namespace, Name: name},
+		{Verb: "watch", Resource: resource, Namespace: namespace, Name: name},
+	}
+}
+
+// leaseAccess returns the permissions holding a Lease needs: get and update it, and create it when missing.
+func leaseAccess(namespace, name string) []resourceAccess {
+	return []resourceAccess{
+		{Verb: "get", Group: "coordination.k8s.io", Resource: "leases", Namespace: namespace, Name: name},
+		{Verb: "update", Group: "coordination.k8s.io", Resource: "leases", Namespace: namespace, Name: name},
+		{Verb: "create", Group: "coordination.k8s.io", Resource: "leases", Namespace: namespace},
+	}
+}
+
+// requiredAccess lists the Kubernetes permissions a pass with this configuration needs, along with those of the
+// enabled long-lived features: the informers of watch and operator modes, and leader election.
+func requiredAccess(appConfig *AppConfig) []resourceAccess {
+	accesses := make([]resourceAccess, 0)
+
+	if appConfig.ConfigSource == KubernetesSource {
+		accesses = append(accesses, sourceAccess("secrets", appConfig.KeysNamespace, appConfig.KeysSecretName)...)
+		if appConfig.KeysSecretAnnotation != "" {
+			accesses = append(accesses, resourceAccess{Verb: "patch", Resource: "secrets", Namespace: appConfig.KeysNamespace, Name: appConfig.KeysSecretName})
+		}
+		if appConfig.GenerateRelayMinerConfig {
+			accesses = append(accesses, sourceAccess("configmaps", appConfig.RelayMinerConfigNamespace, appConfig.RelayMinerConfigName)...)
+		}
+		if appConfig.ServiceGroupsName != "" {
+			accesses = append(accesses, sourceAccess("configmaps", appConfig.ServiceGroupsNamespace, appConfig.ServiceGroupsName)...)
+		}
+		if appConfig.TenantsName != "" {
+			accesses = append(accesses, sourceAccess("configmaps", appConfig.TenantsNamespace, appConfig.TenantsName)...)
+		}
+	}
+
+	if appConfig.GenerateRelayMinerConfig && appConfig.RelayMinerConfigOutputTarget != FileSource {
+		resource := appConfig.RelayMinerConfigOutputTarget + "s"
+		for _, verb := range []string{"get", "update"} {
+			accesses = append(accesses, resourceAccess{Verb: verb, Resource: resource, Namespace: appConfig.RelayMinerConfigOutputNamespace, Name: appConfig.RelayMinerConfigOutputName})
+		}
+		accesses = append(accesses, resourceAccess{Verb: "create", Resource: resource, Namespace: appConfig.RelayMinerConfigOutputNamespace})
+	}
+
+	for _, target := range appConfig.RolloutTargets {
+		if kind, name, err := parseRolloutTarget(target); err == nil {
+			accesses = append(accesses, resourceAccess{Verb: "patch", Group: "apps", Resource: kind + "s", Namespace: appConfig.RolloutNamespace, Name: name})
+		}
+	}
+
+	if appConfig.GenerateRelayMinerConfig && appConfig.BackendDiscovery {
+		accesses = append(accesses, resourceAccess{Verb: "list", Resource: "services", Namespace: appConfig.BackendDiscoveryNamespace})
+	}
+
+	if appConfig.ReportConfigMapName != "" {
+		accesses = append(accesses,
+			resourceAccess{Verb: "patch", Resource: "configmaps", Namespace: appConfig.ReportConfigMapNamespace, Name: appConfig.ReportConfigMapName},
+			resourceAccess{Verb: "create", Resource: "configmaps", Namespace: appConfig.ReportConfigMapNamespace},
+		)
+	}
+
+	if appConfig.CompletionEvent {
+		accesses = append(accesses, resourceAccess{Verb: "create", Resource: "events", Namespace: eventObject(appConfig).Namespace})
+	}
+
+	leaseNamespace := orDefault(appConfig.LeaderElectionNamespace, podNamespace())
+	if appConfig.RunLock == RunLockLease {
+		accesses = append(accesses, leaseAccess(leaseNamespace, appConfig.RunLockLeaseName)...)
+	}
+	if appConfig.LeaderElection {
+		accesses = append(accesses, leaseAccess(leaseNamespace, appConfig.LeaderElectionLeaseName)...)
+	}
+
+	if appConfig.RunMode == WatchRunMode && appConfig.ConfigSource == KubernetesSource {
+		accesses = append(accesses, watchAccess("secrets", appConfig.KeysNamespace, appConfig.KeysSecretName)...)
+		if appConfig.GenerateRelayMinerConfig {
+			accesses = append(accesses, watchAccess("configmaps", appConfig.RelayMinerConfigNamespace, appConfig.RelayMinerConfigName)...)
+		}
+		if serviceGroupsEnabled(appConfig) {
+			accesses = append(accesses, watchAccess("configmaps", appConfig.ServiceGroupsNamespace, appConfig.ServiceGroupsName)...)
+		}
+	}
+
+	if appConfig.RunMode == OperatorRunMode {
+		for _, resource := range operatorResources() {
+			gvr := resource.resource
+			accesses = append(accesses,
+				resourceAccess{Verb: "list", Group: gvr.Group, Resource: gvr.Resource, Namespace: appConfig.OperatorNamespace},
+				resourceAccess{Verb: "watch", Group: gvr.Group, Resource: gvr.Resource, Namespace: appConfig.OperatorNamespace},
+				resourceAccess{Verb: "update", Group: gvr.Group, Resource: gvr.Resource, Subresource: "status", Namespace: appConfig.OperatorNamespace},
+			)
+		}
+		// the references of the custom resources are watched to reconcile them on change
+		for _, resource := range []string{"secrets", "configmaps"} {
+			accesses = append(accesses, watchAccess(resource, appConfig.OperatorNamespace, "")...)
+		}
+	}
+
+	return accesses
+}
+
+// checkAccess asks the API server whether the loader's identity has a permission.
+func checkAccess(clientset kubernetes.Interface, access resourceAccess) error {
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   access.Namespace,
+				Verb:        access.Verb,
+				Group:       access.Group,
+				Resource:    access.Resource,
+				Subresource: access.Subresource,
+				Name:        access.Name,
+			},
+		},
+	}
+	result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, v1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error reviewing access: %w", err)
+	}
+	if !result.Status.Allowed {
+		return fmt.Errorf("%w: %s", ErrAccessDenied, orDefault(result.Status.Reason, "no RBAC rule allows it"))
+	}
+	return nil
+}
+
+// preflightAccess reviews every permission the pass needs before it starts, so missing RBAC rules fail it up front
+// with the full list instead of a Forbidden halfway through.
+func preflightAccess(appConfig *AppConfig) error {
+	if !appConfig.RBACPreflight || !usesKubernetes(appConfig) {
+		return nil
+	}
+	accesses := requiredAccess(appConfig)
+	if len(accesses) == 0 {
+		return nil
+	}
+
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return withExitCode(ExitSourceError, err)
+	}
+	missing := make([]string, 0)
+	for _, access := range accesses {
+		err := checkAccess(clientset, access)
+		if errors.Is(err, ErrAccessDenied) {
+			missing = append(missing, access.String())
+			continue
+		}
+		if err != nil {
+			return withExitCode(ExitSourceError, err)
+		}
+	}
+	if len(missing) > 0 {
+		log.Error().Strs("missing", missing).Msg("Missing RBAC permissions")
+		return withExitCode(ExitConfigError, fmt.Errorf("%w: the loader's service account lacks %d permissions: %s",
+			ErrAccessDenied, len(missing), strings.Join(missing, "; ")))
+	}
+	log.Debug().Int("permissions", len(accesses)).Msg("RBAC permissions reviewed")
+	return nil
+}
+
+// checkWritableDir checks that files can be created in dir, or in its nearest existing parent when it doesn't exist
+// yet (the pass creates it). The probe file is removed right away.
+func checkWritableDir(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) || filepath.Dir(dir) == dir {
+			return err
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	probe, err := os.CreateTemp(dir, ".keyring-loader-doctor-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	_ = probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// osKeyringStore names the credential store the os keyring backend uses on this platform.
+func osKeyringStore() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS Keychain"
+	case "windows":
+		return "Windows Credential Manager"
+	default:
+		return "Secret Service (or KWallet, pass or an encrypted file under KEYRING_DIR)"
+	}
+}
+
+// doctor runs every check that applies to the configuration and returns the report. It only reads: sources are
+// loaded, permissions are reviewed and directories probed, but nothing is imported or written.
+func doctor(appConfig *AppConfig) *DoctorReport {
+	report := &DoctorReport{Checks: make([]DoctorCheck, 0)}
+
+	if err := validateConfig(appConfig); err != nil {
+		report.add("settings", err, "")
+		return report
+	}
+	report.add("settings", nil, "")
+	configureSdk(appConfig)
+
+	if _, known := chainAddressPrefixes[appConfig.ChainID]; known {
+		report.add("address prefix", checkAddressPrefix(appConfig), appConfig.AddressPrefix+" addresses on "+appConfig.ChainID)
+	} else {
+		report.skip("address prefix", "CHAIN_ID is unset or not a known network")
+	}
+
+	if usesKubernetes(appConfig) {
+		clientset, err := newKubernetesClient()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+			err = clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+			cancel()
+		}
+		report.add("kubernetes credentials", err, "in-cluster config, API server reachable")
+
+		if err == nil {
+			for _, access := range requiredAccess(appConfig) {
+				report.add("rbac: "+access.String(), checkAccess(clientset, access), "")
+			}
+		}
+	} else {
+		report.skip("kubernetes credentials", "the configuration doesn't use the Kubernetes API")
+	}
+
+	switch appConfig.KeyringBackend {
+	case "test":
+		report.add("keyring dir", checkWritableDir(appConfig.KeyringDir), appConfig.KeyringDir+" is writable")
+		report.add("keyring dir free space", checkFreeSpace(appConfig.KeyringDir, appConfig.KeyringMinFreeMB), fmt.Sprintf("at least %d MB left", appConfig.KeyringMinFreeMB))
+	case "pass":
+		report.add("pass backend", checkKeyringDependencies("pass"), "pass, gpg and gpg-agent found, password store initialized")
+	case "os":
+		report.add("os backend", checkKeyringDependencies("os"), "the keys are stored in the "+osKeyringStore())
+	default:
+		report.skip("keyring dir", "not used by the "+appConfig.KeyringBackend+" backend")
+	}
+
+	if tenantsEnabled(appConfig) {
+		tenants, err := loadTenants(appConfig)
+		report.add("tenants manifest", err, fmt.Sprintf("%d tenants", len(tenants)))
+	}
+
+	keys, err := loadWalletKeys(appConfig)
+	report.add("keys.json", err, fmt.Sprintf("%d entries", len(keys)))
+
+	if serviceGroupsEnabled(appConfig) {
+		groups, err := loadServiceGroups(appConfig)
+		report.add("service groups", err, fmt.Sprintf("%d groups", len(groups)))
+	}
+
+	if appConfig.GenerateRelayMinerConfig {
+		relayMinerConfig, err := loadRelayMinerConfig(appConfig)
+		detail := ""
+		if err == nil {
+			detail = fmt.Sprintf("%d suppliers", len(relayMinerConfig.Suppliers))
+		}
+		report.add("base relay miner config", err, detail)
+
+		if appConfig.RelayMinerConfigOutputTarget == FileSource {
+			output := appConfig.RelayMinerConfigFileOutputPath
+			if strings.Contains(output, "{{") {
+				report.skip("output dir", "templated output path")
+			} else {
+				report.add("output dir", checkWritableDir(filepath.Dir(output)), filepath.Dir(output)+" is writable")
+			}
+		}
+	} else {
+		report.skip("base relay miner config", "GENERATE_RELAYMINER_CONFIG is disabled")
+	}
+
+	return report
+}
+
+// doctorCommand runs the doctor subcommand: it prints the report, as a table or as JSON, and fails when a check
+// failed.
+func doctorCommand(appConfig *AppConfig, asJSON bool) error {
+	report := doctor(appConfig)
+
+	if asJSON {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal doctor report: %w", err)
+		}
+		if _, err := fmt.Println(string(content)); err != nil {
+			return err
+		}
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "CHECK\tSTATUS\tDETAIL")
+		for _, check := range report.Checks {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", check.Name, check.Status, check.Detail)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if report.Failures > 0 {
+		return fmt.Errorf("%d doctor checks failed", report.Failures)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRequiredAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "one-shot pass",
+			env:    map[string]string{"RUN_MODE": OnceRunMode},
+			want:   []string{"get secrets/pocket-keys in default"},
+			absent: []string{"watch secrets/pocket-keys in default", "update leases.coordination.k8s.io/shannon-keyring-loader in default"},
+		},
+		{
+			name: "watch mode",
+			env:  map[string]string{"RUN_MODE": WatchRunMode, "KEYS_NAMESPACE": "team-a,team-b"},
+			want: []string{
+				"list secrets/pocket-keys cluster-wide",
+				"watch secrets/pocket-keys cluster-wide",
+			},
+		},
+		{
+			name: "leader election",
+			env:  map[string]string{"RUN_MODE": DaemonRunMode, "LEADER_ELECTION": "true", "LEADER_ELECTION_NAMESPACE": "loaders"},
+			want: []string{
+				"get leases.coordination.k8s.io/shannon-keyring-loader in loaders",
+				"update leases.coordination.k8s.io/shannon-keyring-loader in loaders",
+				"create leases.coordination.k8s.io in loaders",
+			},
+		},
+		{
+			name: "operator mode",
+			env:  map[string]string{"RUN_MODE": OperatorRunMode, "OPERATOR_NAMESPACE": "relayminers"},
+			want: []string{
+				"list walletkeyimports.keyring.pokt.network in relayminers",
+				"watch walletkeyimports.keyring.pokt.network in relayminers",
+				"update walletkeyimports.keyring.pokt.network/status in relayminers",
+				"update relayminerconfigtemplates.keyring.pokt.network/status in relayminers",
+				"watch secrets in relayminers",
+				"watch configmaps in relayminers",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_SOURCE", KubernetesSource)
+			t.Setenv("KEYS_SECRET_NAME", "pocket-keys")
+			t.Setenv("KEYS_NAMESPACE", "default")
+			t.Setenv("LEADER_ELECTION_NAMESPACE", "default")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			appConfig, err := loadAppConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			accesses := make([]string, 0)
+			for _, access := range requiredAccess(appConfig) {
+				accesses = append(accesses, access.String())
+			}
+			for _, want := range tt.want {
+				if !slices.Contains(accesses, want) {
+					t.Errorf("requiredAccess() = %v, missing %q", accesses, want)
+				}
+			}
+			for _, absent := range tt.absent {
+				if slices.Contains(accesses, absent) {
+					t.Errorf("requiredAccess() = %v, unexpected %q", accesses, absent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+)
+
+// parseAgeRecipients parses age X25519 recipients (`age1...`) and SSH public keys (`ssh-ed25519 ...`, `ssh-rsa ...`).
+func parseAgeRecipients(values []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(values))
+	for _, value := range values {
+		var recipient age.Recipient
+		var err error
+		if strings.HasPrefix(value, "ssh-") {
+			recipient, err = agessh.ParseRecipient(value)
+		} else {
+			recipient, err = age.ParseX25519Recipient(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", value, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// encryptExport encrypts an exported artifact to the EXPORT_AGE_RECIPIENTS, ASCII-armored so it fits ConfigMap values
+// as well as files, and returns it unchanged when there are none. `age --decrypt` reads it back.
+func encryptExport(appConfig *AppConfig, content []byte) ([]byte, error) {
+	if len(appConfig.ExportAgeRecipients) == 0 {
+		return content, nil
+	}
+	recipients, err := parseAgeRecipients(appConfig.ExportAgeRecipients)
+	if err != nil {
+		return nil, err
+	}
+
+	var encrypted bytes.Buffer
+	armored := armor.NewWriter(&encrypted)
+	writer, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt export: %w", err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return nil, fmt.Errorf("unable to encrypt export: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("unable to encrypt export: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return nil, fmt.Errorf("unable to armor export: %w", err)
+	}
+	return encrypted.Bytes(), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import "errors"
+
+// Sentinel errors of the failures a caller may want to branch on, wrapped (so matched with errors.Is) by the errors
+// of the pipeline. The messages stay the ones logged and reported.
+var (
+	// ErrInvalidEntry is a keys entry that can't be processed: neither mnemonic nor hex, or an invalid role,
+	// distribution or range.
+	ErrInvalidEntry = errors.New("invalid entry")
+	// ErrInvalidMnemonic is a mnemonic failing the BIP-39 checks (word list, length, checksum).
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	// ErrInvalidHexKey is a private key that isn't valid hex.
+	ErrInvalidHexKey = errors.New("invalid hex key")
+	// ErrAddressMismatch is a key whose derived address isn't the one recorded in the expected_addresses of its entry.
+	ErrAddressMismatch = errors.New("address mismatch")
+	// ErrAddressNotAllowed is a key whose address is on ADDRESS_DENYLIST, or missing from ADDRESS_ALLOWLIST.
+	ErrAddressNotAllowed = errors.New("address not allowed")
+	// ErrKeyExpired is an enabled keys entry past its expires_at, in KEY_EXPIRY_MODE=fail.
+	ErrKeyExpired = errors.New("keys expired")
+	// ErrKeySelfTest is an imported key failing to sign, or to verify its signature, through the keyring.
+	ErrKeySelfTest = errors.New("key self-test failed")
+	// ErrAccountNotFound is an imported key without an account on chain, in ACCOUNT_CHECK=fail.
+	ErrAccountNotFound = errors.New("accounts not found on chain")
+	// ErrInsufficientBalance is an imported supplier key holding less than MIN_BALANCE, in BALANCE_CHECK=fail.
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	// ErrStakeMismatch is an imported operator key whose supplier stake on chain doesn't match its registration, in
+	// STAKE_CHECK=fail.
+	ErrStakeMismatch = errors.New("supplier stake mismatch")
+	// ErrParamViolation is a value of the generated configs the chain would reject under its module params, in
+	// PARAMS_CHECK=fail.
+	ErrParamViolation = errors.New("values outside the chain params")
+	// ErrChainMismatch is a chain node running another chain than CHAIN_ID.
+	ErrChainMismatch = errors.New("chain id mismatch")
+	// ErrTxFailed is a transaction the chain node rejected, or that failed once included in a block.
+	ErrTxFailed = errors.New("transaction failed")
+	// ErrUnknownService is a service ID keys are registered to that doesn't exist on chain, in SERVICE_CHECK=fail.
+	ErrUnknownService = errors.New("services not found on chain")
+	// ErrMissingDelegation is an imported application key not delegated to its gateway_addresses on chain, in
+	// DELEGATION_CHECK=fail.
+	ErrMissingDelegation = errors.New("missing gateway delegations")
+	// ErrServiceNotFound is a service ID (or pattern) of a keys entry matching no supplier of the relay miner config.
+	ErrServiceNotFound = errors.New("service id not found under suppliers[].service_id")
+	// ErrServicePattern is a service ID pattern of a keys entry while GENERATE_RELAYMINER_CONFIG is false, leaving no
+	// relay miner config to resolve it against.
+	ErrServicePattern = errors.New("service id patterns require GENERATE_RELAYMINER_CONFIG")
+	// ErrTooManyKeys is a keys spec deriving more keys than MAX_KEYS.
+	ErrTooManyKeys = errors.New("too many keys")
+	// ErrSuppliersWithoutKeys is a relay miner config left with suppliers no key signs for, in EMPTY_SUPPLIER_MODE=fail.
+	ErrSuppliersWithoutKeys = errors.New("suppliers left without signing keys")
+	// ErrAccessDenied is a Kubernetes permission the configuration needs but the loader's identity lacks.
+	ErrAccessDenied = errors.New("forbidden")
+	// ErrRunLockHeld is a run lock still held by another instance at the end of RUN_LOCK_TIMEOUT.
+	ErrRunLockHeld = errors.New("run lock held by another instance")
+	// ErrRunTimeout is a pass canceled at its RUN_TIMEOUT deadline.
+	ErrRunTimeout = errors.New("pass did not complete within RUN_TIMEOUT")
+	// ErrPassAbandoned is a timed-out pass still stuck past the grace period, e.g. in a keyring operation on a hung
+	// gpg-agent. It may still hold the run lock, so only a process exit releases it.
+	ErrPassAbandoned = errors.New("pass abandoned after its RUN_TIMEOUT grace period")
+)
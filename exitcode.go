@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+)
+
+// Exit codes of the loader, so Kubernetes Jobs (podFailurePolicy) and Argo workflows (retryStrategy) can retry only
+// the retryable classes of failures
+const (
+	// ExitFailure is any failure not classified below.
+	ExitFailure int = 1
+	// ExitConfigError is an invalid setting; retrying won't help until the environment is fixed.
+	ExitConfigError int = 2
+	// ExitSourceError is a source or target that couldn't be reached (Kubernetes API, files, backends, run lock);
+	// usually transient, retry.
+	ExitSourceError int = 3
+	// ExitKeyringError is a keyring that couldn't be opened or written.
+	ExitKeyringError int = 4
+	// ExitValidationError is invalid content in keys.json or the relay miner config; retrying won't help until the
+	// content is fixed.
+	ExitValidationError int = 5
+)
+
+// ExitError tags an error with the exit code the process should end with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error implements error.
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// withExitCode tags err with an exit code, nil stays nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// exitCode returns the exit code of err: the code of the innermost tagged error, ExitFailure when there is none.
+func exitCode(err error) int {
+	code := ExitFailure
+	for err != nil {
+		var exitError *ExitError
+		if !errors.As(err, &exitError) {
+			break
+		}
+		code = exitError.Code
+		err = exitError.Err
+	}
+	return code
+}
+
+// Components of the pipeline a PipelineError can come from
+const (
+	ComponentSettings         string = "settings"
+	ComponentKubernetes       string = "kubernetes"
+	ComponentRunLock          string = "run_lock"
+	ComponentKeys             string = "keys"
+	ComponentKeyring          string = "keyring"
+	ComponentRelayMinerConfig string = "relayminer_config"
+	ComponentState            string = "state"
+	ComponentHooks            string = "hooks"
+	ComponentOutputs          string = "outputs"
+	ComponentBackends         string = "backends"
+	ComponentChain            string = "chain"
+	ComponentSignals          string = "signals"
+	ComponentReport           string = "report"
+)
+
+// Error codes of a PipelineError, one per class of exit code so automation can classify failures without parsing
+// the messages
+const (
+	CodeFailure         string = "failure"
+	CodeInvalidConfig   string = "invalid_config"
+	CodeSourceError     string = "source_unavailable"
+	CodeKeyringError    string = "keyring_unavailable"
+	CodeValidationError string = "invalid_content"
+)
+
+// errorCodes maps the exit codes to their error code.
+var errorCodes = map[int]string{
+	ExitFailure:         CodeFailure,
+	ExitConfigError:     CodeInvalidConfig,
+	ExitSourceError:     CodeSourceError,
+	ExitKeyringError:    CodeKeyringError,
+	ExitValidationError: CodeValidationError,
+}
+
+// PipelineError classifies a failure of the loader: its error code, the component of the pipeline that failed and
+// whether retrying may help. It is surfaced in the logs and the run report.
+type PipelineError struct {
+	Code      string
+	Component string
+	Retryable bool
+	Err       error
+}
+
+// Error implements error.
+func (e *PipelineError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// withComponent classifies err as a failure of component, its code and retryability following its exit code: only
+// unreachable sources and targets are worth retrying. An already classified err is left alone, nil stays nil.
+func withComponent(component string, err error) error {
+	var pipelineError *PipelineError
+	if err == nil || errors.As(err, &pipelineError) {
+		return err
+	}
+	code := exitCode(err)
+	return &PipelineError{
+		Code:      errorCodes[code],
+		Component: component,
+		Retryable: code == ExitSourceError,
+		Err:       err,
+	}
+}
+
+// classifyError returns the PipelineError of err, classifying it as an unknown failure when it has none.
+func classifyError(err error) PipelineError {
+	var pipelineError *PipelineError
+	if errors.As(err, &pipelineError) {
+		return *pipelineError
+	}
+	code := exitCode(err)
+	return PipelineError{Code: errorCodes[code], Retryable: code == ExitSourceError, Err: err}
+}
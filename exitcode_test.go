@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "untagged", err: errors.New("boom"), want: ExitFailure},
+		{name: "tagged", err: withExitCode(ExitConfigError, errors.New("bad setting")), want: ExitConfigError},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("pass failed: %w", withExitCode(ExitSourceError, errors.New("unreachable"))),
+			want: ExitSourceError,
+		},
+		{
+			name: "innermost code wins",
+			err:  withExitCode(ExitValidationError, fmt.Errorf("keys: %w", withExitCode(ExitKeyringError, errors.New("locked")))),
+			want: ExitKeyringError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithExitCodeNil(t *testing.T) {
+	if err := withExitCode(ExitConfigError, nil); err != nil {
+		t.Errorf("withExitCode(nil) = %v, want nil", err)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want PipelineError
+	}{
+		{
+			name: "unclassified",
+			err:  errors.New("boom"),
+			want: PipelineError{Code: CodeFailure},
+		},
+		{
+			name: "tagged only",
+			err:  withExitCode(ExitSourceError, errors.New("unreachable")),
+			want: PipelineError{Code: CodeSourceError, Retryable: true},
+		},
+		{
+			name: "classified",
+			err:  withComponent(ComponentKeyring, withExitCode(ExitKeyringError, errors.New("locked"))),
+			want: PipelineError{Code: CodeKeyringError, Component: ComponentKeyring},
+		},
+		{
+			name: "wrapped classification",
+			err:  fmt.Errorf("pass failed: %w", withComponent(ComponentKubernetes, withExitCode(ExitSourceError, errors.New("timeout")))),
+			want: PipelineError{Code: CodeSourceError, Component: ComponentKubernetes, Retryable: true},
+		},
+		{
+			name: "first classification wins",
+			err:  withComponent(ComponentOutputs, withComponent(ComponentSettings, withExitCode(ExitConfigError, errors.New("bad")))),
+			want: PipelineError{Code: CodeInvalidConfig, Component: ComponentSettings},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if got.Code != tt.want.Code || got.Component != tt.want.Component || got.Retryable != tt.want.Retryable {
+				t.Errorf("classifyError = %+v, want %+v", got, tt.want)
+			}
+			if got.Err == nil {
+				t.Error("classifyError lost the error")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Modes for keys past their expires_at
+const (
+	KeyExpiryWarn string = "warn"
+	KeyExpiryFail string = "fail"
+)
+
+// deadlineDateLayout is the date-only layout accepted for deadlines, meaning midnight UTC.
+const deadlineDateLayout = "2006-01-02"
+
+// Deadline is a rotate_after or expires_at date of a keys entry, either RFC 3339 or a date.
+type Deadline struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting RFC 3339 timestamps and dates.
+func (d *Deadline) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("deadline must be a string: %w", err)
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.Parse(deadlineDateLayout, value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid deadline %q: expected an RFC 3339 timestamp or a %s date", value, deadlineDateLayout)
+	}
+	d.Time = parsed.UTC()
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, always as RFC 3339.
+func (d Deadline) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Time.Format(time.RFC3339))
+}
+
+// passed tells whether the deadline is set and before now.
+func (d *Deadline) passed(now time.Time) bool {
+	return d != nil && d.Before(now)
+}
+
+// within tells whether the deadline is set and before now plus window.
+func (d *Deadline) within(now time.Time, window time.Duration) bool {
+	return d != nil && d.Before(now.Add(window))
+}
+
+// checkKeyDeadlines warns about the enabled entries past their rotate_after, or past (or within KEY_EXPIRY_WARNING of)
+// their expires_at. Expired entries fail the pass in KEY_EXPIRY_MODE=fail, before any key is imported.
+func checkKeyDeadlines(appConfig *AppConfig, keys []WalletKeySpec) error {
+	now := time.Now()
+	expired := make([]int, 0)
+	for i, entry := range keys {
+		if entry.Disabled {
+			continue
+		}
+
+		switch {
+		case entry.ExpiresAt.passed(now):
+			expired = append(expired, i)
+			log.Warn().Int("entry", i).Time("expires_at", entry.ExpiresAt.Time).Msg("Entry keys expired, rotate them")
+		case entry.ExpiresAt.within(now, appConfig.KeyExpiryWarning):
+			log.Warn().Int("entry", i).Time("expires_at", entry.ExpiresAt.Time).Msg("Entry keys expire soon, rotate them")
+		case entry.RotateAfter.passed(now):
+			log.Warn().Int("entry", i).Time("rotate_after", entry.RotateAfter.Time).Msg("Entry keys are due for rotation")
+		}
+	}
+
+	if len(expired) > 0 && appConfig.KeyExpiryMode == KeyExpiryFail {
+		log.Error().Ints("entries", expired).Msg("Entries with expired keys")
+		return fmt.Errorf("%w: entries %v", ErrKeyExpired, expired)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Fail modes deciding what happens when a keys.json entry fails
+const (
+	// FailModeAbort stops the pass at the first failing entry (default).
+	FailModeAbort string = "abort"
+	// FailModeContinue skips failing entries, finishes the pass with the others and fails at the end.
+	FailModeContinue string = "continue"
+)
+
+// EntryError is the failure of a single keys.json entry.
+type EntryError struct {
+	Index int
+	Err   error
+}
+
+// Error implements error.
+func (e EntryError) Error() string {
+	return fmt.Sprintf("entry %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e EntryError) Unwrap() error {
+	return e.Err
+}
+
+// EntryErrors aggregates the entries that failed in FailModeContinue.
+type EntryErrors []EntryError
+
+// Error implements error.
+func (e EntryErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, entryError := range e {
+		messages = append(messages, entryError.Error())
+	}
+	return fmt.Sprintf("%d entries failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the failures of every entry.
+func (e EntryErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, entryError := range e {
+		errs = append(errs, entryError)
+	}
+	return errs
+}
+
+// report logs every failed entry.
+func (e EntryErrors) report() {
+	for _, entryError := range e {
+		log.Error().Int("entry", entryError.Index).Err(entryError.Err).Msg("Entry failed")
+	}
+	log.Error().Int("failed_entries", len(e)).Msg("Pass completed with failed entries")
+}
+
+// cloneRelayMinerConfig returns a deep copy of a relay miner config, so the registrations of a failing entry
+// can be rolled back. Returns nil for a nil config.
+func cloneRelayMinerConfig(relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) (*poktrollconfig.YAMLRelayMinerConfig, error) {
+	if relayMinerConfig == nil {
+		return nil, nil
+	}
+
+	content, err := yaml.Marshal(relayMinerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal RelayMiner config: %w", err)
+	}
+
+	clone := &poktrollconfig.YAMLRelayMinerConfig{}
+	if err := yaml.Unmarshal(content, clone); err != nil {
+		return nil, fmt.Errorf("unable to unmarshall RelayMiner config: %w", err)
+	}
+	return clone, nil
+}
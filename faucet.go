@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pokt-network/poktroll/app/pocket"
+	"github.com/rs/zerolog/log"
+)
+
+// faucetChainIds are the testnets the faucet may be used on. LocalNet shares its chain id with MainNet, see
+// isLocalChainNode.
+var faucetChainIds = []string{pocket.AlphaTestNetChainId, pocket.BetaTestNetChainId}
+
+// validateFaucet makes sure FaucetURL is only ever used against a devnet or testnet: CHAIN_ID must be a testnet, or
+// the chain id of LocalNet with a chain node on a loopback, private or in-cluster address, since MainNet shares it.
+func validateFaucet(appConfig *AppConfig) error {
+	faucetUrl, err := url.Parse(appConfig.FaucetURL)
+	if err != nil || (faucetUrl.Scheme != "http" && faucetUrl.Scheme != "https") || faucetUrl.Host == "" {
+		return fmt.Errorf("invalid faucet url: %s", redactSetting("FAUCET_URL", appConfig.FaucetURL))
+	}
+	if faucetUrl.Host == strings.TrimPrefix(pocket.MainNetFaucetBaseURL, "https://") {
+		return fmt.Errorf("FAUCET_URL can't be the MainNet faucet")
+	}
+	if appConfig.ChainGRPCUrl == "" || appConfig.ChainID == "" {
+		return fmt.Errorf("CHAIN_GRPC_URL and CHAIN_ID are required when FAUCET_URL is set")
+	}
+	if slices.Contains(faucetChainIds, appConfig.ChainID) {
+		return nil
+	}
+	if appConfig.ChainID == pocket.LocalNetChainId {
+		target, _, err := chainGRPCTarget(appConfig.ChainGRPCUrl)
+		if err != nil {
+			return err
+		}
+		if isLocalChainNode(target) {
+			return nil
+		}
+		return fmt.Errorf("FAUCET_URL with CHAIN_ID=%s requires a LocalNet chain node, on a loopback, private or in-cluster address", appConfig.ChainID)
+	}
+	return fmt.Errorf("FAUCET_URL is only allowed on LocalNet and the testnets (%s), not CHAIN_ID=%s", strings.Join(faucetChainIds, ", "), appConfig.ChainID)
+}
+
+// isLocalChainNode reports whether the host:port target is a loopback or private address, or a hostname that only
+// resolves inside a cluster or a LAN.
+func isLocalChainNode(target string) bool {
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate()
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".svc", ".svc.cluster.local"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fundAccounts requests FaucetDenom from the faucet at FaucetURL for every imported key without an account on chain,
+// i.e. never funded, then waits up to TxTimeout for their accounts to show up so the checks and transactions of the
+// pass find them. Requests are FaucetInterval apart and at most FaucetMaxRequests per pass, sparing the faucet
+// keys.json entries deriving many keys. The funded addresses are returned.
+func fundAccounts(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey) ([]string, error) {
+	if appConfig.FaucetURL == "" || len(importedKeys) == 0 {
+		return nil, nil
+	}
+
+	unfunded := make([]string, 0)
+	for _, importedKey := range importedKeys {
+		if slices.Contains(unfunded, importedKey.Address) {
+			continue
+		}
+		found, err := chain.account(importedKey.Address)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			unfunded = append(unfunded, importedKey.Address)
+		}
+	}
+	if len(unfunded) == 0 {
+		log.Debug().Int("keys", len(importedKeys)).Msg("All accounts already funded")
+		return nil, nil
+	}
+	if len(unfunded) > appConfig.FaucetMaxRequests {
+		log.Warn().
+			Int("unfunded", len(unfunded)).
+			Int("max_requests", appConfig.FaucetMaxRequests).
+			Msg("More accounts to fund than faucet requests allowed, funding the first ones")
+		unfunded = unfunded[:appConfig.FaucetMaxRequests]
+	}
+
+	funded := make([]string, 0, len(unfunded))
+	for i, address := range unfunded {
+		if i > 0 {
+			select {
+			case <-appConfig.runContext().Done():
+				return funded, appConfig.runContext().Err()
+			case <-time.After(appConfig.FaucetInterval):
+			}
+		}
+		if err := requestFaucetFunds(appConfig, address); err != nil {
+			return funded, err
+		}
+		funded = append(funded, address)
+	}
+
+	deadline := time.Now().Add(appConfig.TxTimeout)
+	for _, address := range funded {
+		for {
+			found, err := chain.account(address)
+			if err != nil {
+				return funded, err
+			}
+			if found {
+				break
+			}
+			if time.Now().After(deadline) {
+				return funded, withExitCode(ExitSourceError, fmt.Errorf("account %s not funded by the faucet within %s", address, appConfig.TxTimeout))
+			}
+			select {
+			case <-appConfig.runContext().Done():
+				return funded, appConfig.runContext().Err()
+			case <-time.After(appConfig.TxPollInterval):
+			}
+		}
+	}
+	log.Info().Strs("addresses", funded).Str("denom", appConfig.FaucetDenom).Msg("Accounts funded by the faucet")
+	return funded, nil
+}
+
+// requestFaucetFunds asks the faucet to fund address, like `pocketd faucet fund`: the faucet answers 202 when it
+// sends the funds and 304 when the address was already funded.
+func requestFaucetFunds(appConfig *AppConfig, address string) error {
+	fundUrl := strings.TrimSuffix(appConfig.FaucetURL, "/") + "/" + url.PathEscape(appConfig.FaucetDenom) + "/" + address
+
+	ctx, cancel := context.WithTimeout(appConfig.runContext(), appConfig.ChainQueryTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fundUrl, nil)
+	if err != nil {
+		return fmt.Errorf("unable to build faucet request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return withExitCode(ExitSourceError, fmt.Errorf("unable to reach faucet: %w", err))
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+	switch response.StatusCode {
+	case http.StatusAccepted:
+		log.Info().Str("address", address).Str("denom", appConfig.FaucetDenom).Msg("Faucet funding requested")
+		return nil
+	case http.StatusNotModified:
+		log.Info().Str("address", address).Str("denom", appConfig.FaucetDenom).Msg("Account already funded by the faucet")
+		return nil
+	default:
+		return withExitCode(ExitSourceError, fmt.Errorf("faucet refused to fund %s with status %d: %s", address, response.StatusCode, strings.TrimSpace(string(body))))
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/fips140"
+	"errors"
+	"fmt"
+)
+
+// fipsRejectedKeyringBackends are the keyring backends not allowed in FIPS mode, with the reason.
+var fipsRejectedKeyringBackends = map[string]string{
+	"test": "it stores the keys unencrypted",
+}
+
+// checkFIPS makes sure a loader running in FIPS_MODE uses the Go FIPS 140-3 cryptographic module for all the
+// cryptography it implements (TLS to the API server and webhooks, hashing, random numbers), and rejects the keyring
+// backends whose encryption isn't approved. The `os` and `pass` backends delegate it to the OS keychain and gpg,
+// which have to be validated on their own. The secp256k1 keys and their BIP-39/BIP-32 derivation are dictated by the
+// Shannon protocol and not covered.
+func checkFIPS(appConfig *AppConfig) error {
+	if !appConfig.FIPSMode {
+		return nil
+	}
+	if !fips140.Enabled() {
+		return errors.New("FIPS_MODE needs the Go FIPS 140-3 module: build with GOFIPS140=v1.0.0, or run with GODEBUG=fips140=on")
+	}
+	if reason, rejected := fipsRejectedKeyringBackends[appConfig.KeyringBackend]; rejected {
+		return fmt.Errorf("keyring backend %s isn't allowed in FIPS_MODE: %s", appConfig.KeyringBackend, reason)
+	}
+	return nil
+}
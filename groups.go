@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// ServiceGroups maps a group name to the service IDs (or service ID patterns) it stands for.
+// Example document:
+//
+//	evm: [eth, polygon, bsc]
+//	cosmos: ["^cosmos-.*"]
+type ServiceGroups map[string][]string
+
+// serviceGroupsEnabled reports whether a service groups document is configured for the current config source.
+func serviceGroupsEnabled(appConfig *AppConfig) bool {
+	if appConfig.ConfigSource == KubernetesSource {
+		return appConfig.ServiceGroupsName != ""
+	}
+	return appConfig.ServiceGroupsFilePath != ""
+}
+
+// loadServiceGroups loads the service groups document from a file or Kubernetes ConfigMap.
+// Returns an empty set of groups when no document is configured.
+func loadServiceGroups(appConfig *AppConfig) (ServiceGroups, error) {
+	groups := ServiceGroups{}
+
+	if !serviceGroupsEnabled(appConfig) {
+		log.Debug().Msg("Skipping service groups as no document is configured")
+		return groups, nil
+	}
+
+	data, err := loadConfigData(
+		appConfig,
+		ConfigMapSource,
+		appConfig.ServiceGroupsNamespace,
+		appConfig.ServiceGroupsName,
+		appConfig.ServiceGroupsKey,
+		appConfig.ServiceGroupsFilePath,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load service groups")
+		return groups, fmt.Errorf("error loading service groups: %w", err)
+	}
+
+	log.Debug().Int("data_size", len(data)).Msg("Parsing service groups YAML data")
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		log.Error().Err(err).Msg("Failed to parse service groups YAML data")
+		return groups, withExitCode(ExitValidationError, fmt.Errorf("unable to unmarshall service groups: %w", err))
+	}
+
+	log.Info().Int("group_count", len(groups)).Msg("Service groups loaded successfully")
+	return groups, nil
+}
+
+// resolveServiceGroups expands the service_group references of every key entry into its service_id list.
+// Service IDs already present on the entry are kept and duplicates are dropped.
+func resolveServiceGroups(keys []WalletKeySpec, groups ServiceGroups) error {
+	for i := range keys {
+		entry := &keys[i]
+
+		for _, groupName := range entry.ServiceGroup {
+			serviceIds, ok := groups[groupName]
+			if !ok {
+				return fmt.Errorf("service group not found: %s (entry index: %d)", groupName, i)
+			}
+
+			for _, serviceId := range serviceIds {
+				if !slices.Contains(entry.ServiceID, serviceId) {
+					entry.ServiceID = append(entry.ServiceID, serviceId)
+				}
+			}
+
+			log.Debug().
+				Int("index", i).
+				Str("group", groupName).
+				Strs("service_ids", serviceIds).
+				Msg("Resolved service group")
+		}
+	}
+
+	return nil
+}
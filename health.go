@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// healthServer answers liveness and readiness probes of the long-lived run modes.
+type healthServer struct {
+	server *http.Server
+	ready  atomic.Bool
+	// liveness and readiness replace the default checks when set: always alive, ready once setReady(true).
+	liveness  func() error
+	readiness func() error
+}
+
+// probeHandler answers 200 when check passes and 503 with its error otherwise.
+func probeHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+// newHealthServer creates the probe server listening on HealthListenAddress:
+// `/healthz` answers 200 while the process is alive and `/readyz` answers 200 once marked ready, 503 before.
+func newHealthServer(appConfig *AppConfig) *healthServer {
+	health := &healthServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", probeHandler(func() error {
+		if health.liveness != nil {
+			return health.liveness()
+		}
+		return nil
+	}))
+	mux.HandleFunc("/readyz", probeHandler(func() error {
+		if health.readiness != nil {
+			return health.readiness()
+		}
+		if !health.ready.Load() {
+			return errors.New("not ready")
+		}
+		return nil
+	}))
+
+	health.server = &http.Server{
+		Addr:              appConfig.HealthListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return health
+}
+
+// newLoopHealthServer creates the probe server of the watch and daemon modes, reflecting the loop recorded by
+// tracker: `/healthz` fails once a pass has been running for longer than HealthStuckAfter, `/readyz` until a pass
+// succeeded and after a failed one, until the next success.
+func newLoopHealthServer(appConfig *AppConfig, tracker *statusTracker) *healthServer {
+	health := newHealthServer(appConfig)
+	health.liveness = func() error { return tracker.live(appConfig.HealthStuckAfter) }
+	health.readiness = tracker.ready
+	return health
+}
+
+// start serves the probes in the background.
+func (h *healthServer) start() {
+	go func() {
+		log.Info().Str("address", h.server.Addr).Msg("Serving health probes")
+		if err := h.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Health server failed")
+		}
+	}()
+}
+
+// setReady marks the process ready (or not) for `/readyz`.
+func (h *healthServer) setReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// stop shuts the probe server down.
+func (h *healthServer) stop(ctx context.Context) error {
+	if err := h.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("error stopping health server: %w", err)
+	}
+	return nil
+}
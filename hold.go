@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// HoldRunMode runs a single pass and then keeps the process alive, answering health probes, until it is stopped.
+const HoldRunMode string = "hold"
+
+// hold runs a single import/generation pass and then sleeps until SIGTERM/SIGINT, for platforms where the loader
+// must run as a regular sidecar container instead of an init container that exits. `/readyz` turns ready once the
+// pass succeeds, a failed pass exits with an error so the container is restarted.
+func hold(appConfig *AppConfig) error {
+	health := newHealthServer(appConfig)
+	health.start()
+	defer servePprof(appConfig)()
+
+	if err := run(appConfig); err != nil {
+		return err
+	}
+	health.setReady(true)
+
+	ctx, stop := shutdownContext()
+	defer stop()
+	dumpStateOnSignal(ctx, appConfig)
+
+	log.Info().Msg("Pass completed, holding until stopped")
+	<-ctx.Done()
+	log.Info().Msg("Stop signal received, exiting")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return health.stop(shutdownCtx)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Hooks run around the import of a pass
+const (
+	PreImportHook  string = "pre_import"
+	PostImportHook string = "post_import"
+)
+
+// HookEntry describes a keys.json entry to the hooks, without its key material. The derivation range is only
+// meaningful for mnemonic entries.
+type HookEntry struct {
+	Index      int      `json:"index"`
+	Kind       string   `json:"kind"`
+	StartIndex int      `json:"start_index"`
+	EndIndex   int      `json:"end_index"`
+	ServiceIds []string `json:"service_ids,omitempty"`
+	Role       string   `json:"role"`
+	Disabled   bool     `json:"disabled"`
+}
+
+// HookSummary is the JSON document written to the stdin of a hook.
+type HookSummary struct {
+	Hook           string      `json:"hook"`
+	RunMode        string      `json:"run_mode"`
+	KeyringBackend string      `json:"keyring_backend"`
+	KeyringDir     string      `json:"keyring_dir"`
+	Entries        []HookEntry `json:"entries"`
+	// Keys are the keys imported by the pass, only known to the post-import hook.
+	Keys []ImportedKey `json:"keys,omitempty"`
+	// RelayMinerConfigGenerated tells whether the pass wrote the relay miner config.
+	RelayMinerConfigGenerated bool `json:"relayminer_config_generated"`
+}
+
+// newHookSummary describes a pass to the hooks.
+func newHookSummary(appConfig *AppConfig, hook string, keys []WalletKeySpec, importedKeys []ImportedKey) HookSummary {
+	summary := HookSummary{
+		Hook:                      hook,
+		RunMode:                   appConfig.RunMode,
+		KeyringBackend:            appConfig.KeyringBackend,
+		KeyringDir:                appConfig.KeyringDir,
+		Entries:                   make([]HookEntry, 0, len(keys)),
+		Keys:                      importedKeys,
+		RelayMinerConfigGenerated: appConfig.GenerateRelayMinerConfig && hook == PostImportHook,
+	}
+	for i, entry := range keys {
+		hookEntry := HookEntry{Index: i, Kind: "hex", ServiceIds: entry.ServiceID, Role: orDefault(entry.Role, OperatorRole), Disabled: entry.Disabled}
+		if entry.Mnemonic != "" {
+			hookEntry.Kind = "mnemonic"
+			hookEntry.StartIndex = entry.StartIndex
+			hookEntry.EndIndex = entry.EndIndex
+		}
+		summary.Entries = append(summary.Entries, hookEntry)
+	}
+	return summary
+}
+
+// runHook runs command with `sh -c` (`cmd /C` on Windows), writing summary to its stdin and forwarding its output to stderr. The hook is
+// killed after HookTimeout (or at the RunTimeout deadline), a non-zero exit fails the pass.
+func runHook(appConfig *AppConfig, command string, summary HookSummary) error {
+	if command == "" {
+		return nil
+	}
+
+	input, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s hook summary: %w", summary.Hook, err)
+	}
+
+	ctx, cancel := context.WithTimeout(appConfig.runContext(), appConfig.HookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hookShell[0], append(hookShell[1:], command)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "KEYRING_LOADER_HOOK="+summary.Hook)
+
+	log.Info().Str("hook", summary.Hook).Str("command", command).Msg("Running hook")
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s hook did not complete within %s", summary.Hook, appConfig.HookTimeout)
+		}
+		return fmt.Errorf("%s hook failed: %w", summary.Hook, err)
+	}
+	log.Info().Str("hook", summary.Hook).Msg("Hook completed")
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+// InteractiveCommand prompts for a key instead of reading the keys source, then runs a pass with it.
+const InteractiveCommand string = "interactive"
+
+// prompter asks questions on stderr, keeping stdout clean, and reads the answers from stdin. Secrets are read
+// without echo when stdin is a terminal, answers may be piped otherwise.
+type prompter struct {
+	reader   *bufio.Reader
+	terminal bool
+}
+
+// newPrompter creates a prompter on the standard streams.
+func newPrompter() *prompter {
+	return &prompter{
+		reader:   bufio.NewReader(os.Stdin),
+		terminal: term.IsTerminal(int(os.Stdin.Fd())),
+	}
+}
+
+// ask prints question and returns the trimmed answer, or fallback when it is empty.
+func (p *prompter) ask(question, fallback string) (string, error) {
+	if fallback != "" {
+		question += " [" + fallback + "]"
+	}
+	_, _ = fmt.Fprint(os.Stderr, question+": ")
+
+	answer, err := p.reader.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+		return "", fmt.Errorf("unable to read answer: %w", err)
+	}
+	return orDefault(strings.TrimSpace(answer), fallback), nil
+}
+
+// askSecret prints question and returns the answer, without echoing it when stdin is a terminal.
+func (p *prompter) askSecret(question string) (string, error) {
+	if !p.terminal {
+		return p.ask(question, "")
+	}
+
+	_, _ = fmt.Fprint(os.Stderr, question+" (input hidden): ")
+	answer, err := term.ReadPassword(int(os.Stdin.Fd()))
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("unable to read answer: %w", err)
+	}
+	return strings.TrimSpace(string(answer)), nil
+}
+
+// askIndex asks for a HD derivation index.
+func (p *prompter) askIndex(question string, fallback int) (int, error) {
+	answer, err := p.ask(question, strconv.Itoa(fallback))
+	if err != nil {
+		return 0, err
+	}
+	index, err := strconv.Atoi(answer)
+	if err != nil {
+		return 0, withExitCode(ExitValidationError, fmt.Errorf("invalid index: %s", answer))
+	}
+	return index, nil
+}
+
+// promptWalletKey asks for the key material, its derivation range and the services it signs for, and returns the
+// keys.json entry they make up. serviceIds are the suppliers of the base relay miner config, offered as default.
+func promptWalletKey(p *prompter, serviceIds []string) (WalletKeySpec, error) {
+	entry := WalletKeySpec{Role: OperatorRole}
+
+	secret, err := p.askSecret("Mnemonic or hex private key")
+	if err != nil {
+		return entry, err
+	}
+	if secret == "" {
+		return entry, withExitCode(ExitValidationError, errors.New("no mnemonic or hex key given"))
+	}
+
+	// a mnemonic has words, a hex key doesn't
+	if words := strings.Fields(secret); len(words) > 1 {
+		entry.Mnemonic = strings.Join(words, " ")
+		if !bip39.IsMnemonicValid(entry.Mnemonic) {
+			return entry, withExitCode(ExitValidationError, ErrInvalidMnemonic)
+		}
+		if entry.StartIndex, err = p.askIndex("First HD index", 0); err != nil {
+			return entry, err
+		}
+		if entry.EndIndex, err = p.askIndex("Last HD index", entry.StartIndex); err != nil {
+			return entry, err
+		}
+	} else {
+		entry.Hex = secret
+	}
+
+	services, err := p.ask("Service IDs, comma-separated (none registers default signing keys)", strings.Join(serviceIds, ","))
+	if err != nil {
+		return entry, err
+	}
+	for _, service := range strings.Split(services, ",") {
+		if service = strings.TrimSpace(service); service != "" {
+			entry.ServiceID = append(entry.ServiceID, service)
+		}
+	}
+	return entry, nil
+}
+
+// interactive prompts for a single keys.json entry and runs a pass with it in place of the keys source: the keys are
+// imported into the keyring and, with GENERATE_RELAYMINER_CONFIG, registered in the generated relay miner config.
+// Nothing but the usual outputs is written, the key material is never stored outside the keyring.
+func interactive(appConfig *AppConfig) error {
+	relayMinerConfig, err := loadRelayMinerConfig(appConfig)
+	if err != nil {
+		return fmt.Errorf("error loading relay miner config: %w", err)
+	}
+	serviceIds := make([]string, 0)
+	if relayMinerConfig != nil {
+		for _, supplier := range relayMinerConfig.Suppliers {
+			serviceIds = append(serviceIds, supplier.ServiceId)
+		}
+	}
+
+	entry, err := promptWalletKey(newPrompter(), serviceIds)
+	if err != nil {
+		return err
+	}
+
+	// reject bad input before the keyring is touched, as verify would
+	report := &VerifyReport{Findings: make([]Finding, 0)}
+	verifyEntry(report, 0, entry, relayMinerConfig)
+	if report.Errors > 0 {
+		return withExitCode(ExitValidationError, errors.New("invalid key entry"))
+	}
+
+	keysData, err := json.Marshal([]WalletKeySpec{entry})
+	if err != nil {
+		return fmt.Errorf("unable to marshal keys: %w", err)
+	}
+	config := *appConfig
+	config.KeysData = keysData
+
+	log.Info().Int("services", len(entry.ServiceID)).Msg("Importing the entered key")
+	return run(&config)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+// secretServiceName is the D-Bus name of the Secret Service provider (gnome-keyring, KeePassXC...).
+const secretServiceName = "org.freedesktop.secrets"
+
+// missingKeyringDependencies lists what the keyring backend needs outside the loader and is missing: binaries,
+// daemons and stores. The list is empty for the backends without any dependency.
+func missingKeyringDependencies(backend string) []string {
+	missing := make([]string, 0)
+	switch backend {
+	case "pass":
+		// gpg starts gpg-agent on demand, it only has to be installed
+		for _, binary := range []string{"pass", "gpg", "gpg-agent"} {
+			if _, err := exec.LookPath(binary); err != nil {
+				missing = append(missing, fmt.Sprintf("%s not found in PATH", binary))
+			}
+		}
+		if store, err := passwordStoreDir(); err != nil {
+			missing = append(missing, err.Error())
+		} else if _, err := os.Stat(filepath.Join(store, ".gpg-id")); err != nil {
+			missing = append(missing, fmt.Sprintf("password store %s is not initialized (run `pass init <gpg-id>`)", store))
+		}
+	case "os":
+		// the macOS Keychain and the Windows Credential Manager are always there; on Linux, the keys would silently
+		// end up in the kernel keyring, lost on reboot, without a Secret Service
+		if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+			if err := checkSecretService(); err != nil {
+				missing = append(missing, err.Error())
+			}
+		}
+	}
+	return missing
+}
+
+// passwordStoreDir returns the directory of the pass password store.
+func passwordStoreDir() (string, error) {
+	if store := os.Getenv("PASSWORD_STORE_DIR"); store != "" {
+		return store, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".password-store"), nil
+}
+
+// checkSecretService checks that a D-Bus session bus is reachable and that a Secret Service provider runs on it, or
+// can be activated.
+func checkSecretService() error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return fmt.Errorf("no D-Bus session bus (DBUS_SESSION_BUS_ADDRESS=%q): %w", os.Getenv("DBUS_SESSION_BUS_ADDRESS"), err)
+	}
+	bus := conn.BusObject()
+
+	var owned bool
+	if err := bus.Call("org.freedesktop.DBus.NameHasOwner", 0, secretServiceName).Store(&owned); err != nil {
+		return fmt.Errorf("unable to query the D-Bus session bus: %w", err)
+	}
+	if owned {
+		return nil
+	}
+	var activatable []string
+	if err := bus.Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable); err != nil {
+		return fmt.Errorf("unable to query the D-Bus session bus: %w", err)
+	}
+	if !slices.Contains(activatable, secretServiceName) {
+		return fmt.Errorf("no Secret Service (%s) on the D-Bus session bus, start gnome-keyring-daemon or another provider", secretServiceName)
+	}
+	return nil
+}
+
+// preflightKeyringDependencies fails with everything the keyring backend is missing, rather than with the first
+// import running into it.
+func preflightKeyringDependencies(appConfig *AppConfig) error {
+	missing := missingKeyringDependencies(appConfig.KeyringBackend)
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("keyring backend %s is missing dependencies: %s", appConfig.KeyringBackend, strings.Join(missing, "; "))
+}
+
+// checkKeyringDependencies reports what the keyring backend is missing, for doctor.
+func checkKeyringDependencies(backend string) error {
+	missing := missingKeyringDependencies(backend)
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(missing, "; "))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// keyringDirBackends are the keyring backends storing the keys under KEYRING_DIR (the os backend falls back to an
+// encrypted file store there when no OS keychain is available).
+var keyringDirBackends = map[string]bool{"test": true, "os": true}
+
+// preflightKeyringDir makes sure the keys can be written under KEYRING_DIR before anything is imported: the
+// directory is created with KEYRING_DIR_MODE when missing, then checked to be writable by the current user and to
+// have KEYRING_MIN_FREE_MB left. A read-only or full volume otherwise fails deep inside the Cosmos keyring.
+func preflightKeyringDir(appConfig *AppConfig) error {
+	if !keyringDirBackends[appConfig.KeyringBackend] {
+		return nil
+	}
+	dir := appConfig.KeyringDir
+
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if err := mkdirAllMode(dir, appConfig.KeyringDirMode); err != nil {
+			return fmt.Errorf("unable to create keyring dir '%s': %w", dir, err)
+		}
+		log.Info().Str("dir", dir).Str("mode", appConfig.KeyringDirMode.String()).Msg("Keyring dir created")
+	} else if err != nil {
+		return fmt.Errorf("unable to stat keyring dir '%s': %w", dir, err)
+	}
+
+	if err := checkWritableDir(dir); err != nil {
+		return fmt.Errorf("keyring dir is not writable by uid %d: %w", os.Getuid(), err)
+	}
+
+	return checkFreeSpace(dir, appConfig.KeyringMinFreeMB)
+}
+
+// checkFreeSpace fails when the filesystem of dir has less than minFreeMB left, 0 disables the check.
+func checkFreeSpace(dir string, minFreeMB int) error {
+	if minFreeMB <= 0 {
+		return nil
+	}
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return fmt.Errorf("unable to read the free space of '%s': %w", dir, err)
+	}
+	if freeMB := free / (1 << 20); freeMB < uint64(minFreeMB) {
+		return fmt.Errorf("only %d MB left on the filesystem of '%s', less than KEYRING_MIN_FREE_MB (%d)", freeMB, dir, minFreeMB)
+	}
+	return nil
+}
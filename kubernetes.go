@@ -0,0 +1,561 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// reloaderMatchAnnotation opts a ConfigMap/Secret into stakater/Reloader `reloader.stakater.com/search` workloads.
+const reloaderMatchAnnotation = "reloader.stakater.com/match"
+
+// newKubernetesConfig loads the in-cluster Kubernetes client configuration.
+func newKubernetesConfig() (*rest.Config, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create in-cluster config")
+		return nil, fmt.Errorf("error creating in-cluster config: %w", err)
+	}
+	return config, nil
+}
+
+// newKubernetesClient creates a Kubernetes clientset from the in-cluster configuration.
+func newKubernetesClient() (kubernetes.Interface, error) {
+	config, err := newKubernetesConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create Kubernetes clientset")
+		return nil, fmt.Errorf("error creating Kubernetes clientset: %w", err)
+	}
+
+	return clientset, nil
+}
+
+// newDynamicClient creates a Kubernetes dynamic client from the in-cluster configuration, used for custom resources.
+func newDynamicClient() (dynamic.Interface, error) {
+	config, err := newKubernetesConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create Kubernetes dynamic client")
+		return nil, fmt.Errorf("error creating Kubernetes dynamic client: %w", err)
+	}
+
+	return client, nil
+}
+
+// apiWaitInterval is how often the API server is probed while waiting for it at startup.
+const apiWaitInterval = 2 * time.Second
+
+// usesKubernetes reports whether the configuration talks to the Kubernetes API at all.
+func usesKubernetes(appConfig *AppConfig) bool {
+	// the webhook only answers admission requests
+	if appConfig.RunMode == WebhookRunMode {
+		return false
+	}
+	return appConfig.ConfigSource == KubernetesSource ||
+		appConfig.RelayMinerConfigOutputTarget != FileSource ||
+		appConfig.RunMode == OperatorRunMode ||
+		appConfig.LeaderElection ||
+		appConfig.RunLock == RunLockLease ||
+		appConfig.CompletionEvent ||
+		appConfig.BackendDiscovery ||
+		appConfig.ReportConfigMapName != "" ||
+		len(appConfig.RolloutTargets) > 0
+}
+
+// waitForAPIServer blocks until the Kubernetes API server answers, for up to APIWaitTimeout. Init containers often
+// start while the node's CNI or the control plane is still coming up, so connection errors are retried instead of
+// failing the pod. Does nothing when the configuration doesn't use the API or the wait is disabled.
+func waitForAPIServer(appConfig *AppConfig) error {
+	if !usesKubernetes(appConfig) || appConfig.APIWaitTimeout <= 0 {
+		return nil
+	}
+
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.APIWaitTimeout)
+	defer cancel()
+
+	attempts := 0
+	var lastErr error
+	for {
+		attempts++
+		// a raw request, unlike ServerVersion, is bound to the wait deadline
+		err := clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+		if err == nil {
+			log.Info().Int("attempts", attempts).Msg("Kubernetes API server available")
+			return nil
+		}
+		lastErr = err
+		log.Warn().Err(err).Int("attempt", attempts).Msg("Kubernetes API server not available yet, retrying")
+
+		select {
+		case <-ctx.Done():
+			return withExitCode(ExitSourceError, fmt.Errorf("kubernetes API server not available after %s: %w", appConfig.APIWaitTimeout, lastErr))
+		case <-time.After(apiWaitInterval):
+		}
+	}
+}
+
+// configAnnotations returns the annotations set on the output ConfigMap/Secret for the given content.
+func configAnnotations(appConfig *AppConfig, content []byte) map[string]string {
+	annotations := map[string]string{
+		appConfig.RelayMinerConfigHashAnnotation: contentHash(content),
+	}
+	if appConfig.RelayMinerConfigReloaderMatch {
+		annotations[reloaderMatchAnnotation] = "true"
+	}
+	return annotations
+}
+
+// writeRelayMinerConfigResource creates or updates the output ConfigMap or Secret with the generated config,
+// annotating it with the content hash so dependent workloads can be restarted when it changes.
+// Conflicting concurrent updates and transient API errors are retried.
+func writeRelayMinerConfigResource(appConfig *AppConfig, updatedContent []byte) error {
+	return retryKubernetes(appConfig, "write relay miner config", func() error {
+		return putRelayMinerConfigResource(appConfig, updatedContent)
+	})
+}
+
+// putRelayMinerConfigResource makes a single attempt at writing the output ConfigMap or Secret.
+func putRelayMinerConfigResource(appConfig *AppConfig, updatedContent []byte) error {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	namespace := appConfig.RelayMinerConfigOutputNamespace
+	name := appConfig.RelayMinerConfigOutputName
+	key := appConfig.RelayMinerConfigOutputKey
+	annotations := configAnnotations(appConfig, updatedContent)
+	ctx := appConfig.runContext()
+
+	log.Info().
+		Str("target", appConfig.RelayMinerConfigOutputTarget).
+		Str("namespace", namespace).
+		Str("name", name).
+		Str("key", key).
+		Msg("Writing relay miner configuration to Kubernetes")
+
+	switch appConfig.RelayMinerConfigOutputTarget {
+	case ConfigMapSource:
+		configMaps := clientset.CoreV1().ConfigMaps(namespace)
+		configmap, err := configMaps.Get(ctx, name, v1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if err := reportConfigDiff(appConfig, nil, updatedContent); err != nil {
+				return err
+			}
+			configmap = &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+				Data:       map[string]string{key: string(updatedContent)},
+			}
+			if _, err := configMaps.Create(ctx, configmap, v1.CreateOptions{}); err != nil {
+				return fmt.Errorf("error creating configmap '%s' in namespace '%s': %w", name, namespace, err)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching configmap '%s' in namespace '%s': %w", name, namespace, err)
+		}
+
+		if err := reportConfigDiff(appConfig, []byte(configmap.Data[key]), updatedContent); err != nil {
+			return err
+		}
+		if configmap.Data == nil {
+			configmap.Data = map[string]string{}
+		}
+		configmap.Data[key] = string(updatedContent)
+		if configmap.Annotations == nil {
+			configmap.Annotations = map[string]string{}
+		}
+		for annotation, value := range annotations {
+			configmap.Annotations[annotation] = value
+		}
+		if _, err := configMaps.Update(ctx, configmap, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("error updating configmap '%s' in namespace '%s': %w", name, namespace, err)
+		}
+	case SecretSource:
+		secrets := clientset.CoreV1().Secrets(namespace)
+		secret, err := secrets.Get(ctx, name, v1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if err := reportConfigDiff(appConfig, nil, updatedContent); err != nil {
+				return err
+			}
+			secret = &corev1.Secret{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+				Data:       map[string][]byte{key: updatedContent},
+			}
+			if _, err := secrets.Create(ctx, secret, v1.CreateOptions{}); err != nil {
+				return fmt.Errorf("error creating secret '%s' in namespace '%s': %w", name, namespace, err)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching secret '%s' in namespace '%s': %w", name, namespace, err)
+		}
+
+		if err := reportConfigDiff(appConfig, secret.Data[key], updatedContent); err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = updatedContent
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		for annotation, value := range annotations {
+			secret.Annotations[annotation] = value
+		}
+		if _, err := secrets.Update(ctx, secret, v1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("error updating secret '%s' in namespace '%s': %w", name, namespace, err)
+		}
+	default:
+		return fmt.Errorf("unsupported output target: %s", appConfig.RelayMinerConfigOutputTarget)
+	}
+
+	log.Info().
+		Str("namespace", namespace).
+		Str("name", name).
+		Str(appConfig.RelayMinerConfigHashAnnotation, annotations[appConfig.RelayMinerConfigHashAnnotation]).
+		Msg("Relay miner configuration resource updated successfully")
+	return nil
+}
+
+// readRelayMinerConfigResource returns the config currently stored in the output ConfigMap or Secret,
+// or nil when the resource or key doesn't exist yet.
+func readRelayMinerConfigResource(appConfig *AppConfig) ([]byte, error) {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := appConfig.RelayMinerConfigOutputNamespace
+	name := appConfig.RelayMinerConfigOutputName
+	key := appConfig.RelayMinerConfigOutputKey
+	ctx := appConfig.runContext()
+
+	switch appConfig.RelayMinerConfigOutputTarget {
+	case ConfigMapSource:
+		var configmap *corev1.ConfigMap
+		err := retryKubernetes(appConfig, "get configmap", func() (err error) {
+			configmap, err = clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, v1.GetOptions{})
+			return err
+		})
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error fetching configmap '%s' in namespace '%s': %w", name, namespace, err)
+		}
+		if content, ok := configmap.Data[key]; ok {
+			return []byte(content), nil
+		}
+		return nil, nil
+	case SecretSource:
+		var secret *corev1.Secret
+		err := retryKubernetes(appConfig, "get secret", func() (err error) {
+			secret, err = clientset.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+			return err
+		})
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error fetching secret '%s' in namespace '%s': %w", name, namespace, err)
+		}
+		return secret.Data[key], nil
+	default:
+		return nil, fmt.Errorf("unsupported output target: %s", appConfig.RelayMinerConfigOutputTarget)
+	}
+}
+
+// emitEvent records a Kubernetes Event on a Pod, ConfigMap or Secret (see eventObject), so it shows up in
+// `kubectl describe` and event-based tooling.
+func emitEvent(involvedObject corev1.ObjectReference, eventType, reason, message string) error {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	namespace := involvedObject.Namespace
+	name := involvedObject.Name
+	ctx := context.Background()
+
+	// the uid lets `kubectl describe` match the event, but the event is still useful without it
+	var object v1.Object
+	switch involvedObject.Kind {
+	case "Pod":
+		object, err = clientset.CoreV1().Pods(namespace).Get(ctx, name, v1.GetOptions{})
+	case "ConfigMap":
+		object, err = clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, v1.GetOptions{})
+	case "Secret":
+		object, err = clientset.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+	}
+	if err == nil && object != nil {
+		involvedObject.UID = object.GetUID()
+	} else {
+		log.Debug().Err(err).Str("kind", involvedObject.Kind).Str("name", name).Msg("Unable to fetch object for event")
+	}
+
+	now := v1.Now()
+	event := &corev1.Event{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: name + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject:      involvedObject,
+		Type:                eventType,
+		Reason:              reason,
+		Message:             message,
+		Source:              corev1.EventSource{Component: "shannon-keyring-loader"},
+		ReportingController: "shannon-keyring-loader",
+		ReportingInstance:   podName(),
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+	}
+	if _, err := clientset.CoreV1().Events(namespace).Create(ctx, event, v1.CreateOptions{}); err != nil {
+		return fmt.Errorf("error creating event in namespace '%s': %w", namespace, err)
+	}
+
+	log.Debug().Str("reason", reason).Str("kind", involvedObject.Kind).Str("name", name).Msg("Kubernetes event emitted")
+	return nil
+}
+
+// Workload kinds that can be rolled out after a config update
+const (
+	DeploymentRolloutKind  string = "deployment"
+	StatefulSetRolloutKind string = "statefulset"
+)
+
+// parseRolloutTarget splits a `<kind>/<name>` rollout target.
+func parseRolloutTarget(target string) (string, string, error) {
+	kind, name, ok := strings.Cut(target, "/")
+	kind = strings.ToLower(kind)
+	if !ok || name == "" || (kind != DeploymentRolloutKind && kind != StatefulSetRolloutKind) {
+		return "", "", fmt.Errorf("invalid rollout target '%s', expected deployment/<name> or statefulset/<name>", target)
+	}
+	return kind, name, nil
+}
+
+// triggerRollout patches the pod template of every rollout target with the hash of the generated config.
+// Kubernetes only rolls the pods when the hash actually changes, so unchanged configs cause no restarts.
+func triggerRollout(appConfig *AppConfig, updatedContent []byte) error {
+	if len(appConfig.RolloutTargets) == 0 {
+		return nil
+	}
+
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	hash := contentHash(updatedContent)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						appConfig.RelayMinerConfigHashAnnotation: hash,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to build rollout patch: %w", err)
+	}
+
+	namespace := appConfig.RolloutNamespace
+	for _, target := range appConfig.RolloutTargets {
+		kind, name, err := parseRolloutTarget(target)
+		if err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("namespace", namespace).
+			Str("kind", kind).
+			Str("name", name).
+			Str("hash", hash).
+			Msg("Triggering rollout")
+
+		err = retryKubernetes(appConfig, "patch "+kind, func() (err error) {
+			switch kind {
+			case DeploymentRolloutKind:
+				_, err = clientset.AppsV1().Deployments(namespace).Patch(appConfig.runContext(), name, k8stypes.StrategicMergePatchType, patch, v1.PatchOptions{})
+			case StatefulSetRolloutKind:
+				_, err = clientset.AppsV1().StatefulSets(namespace).Patch(appConfig.runContext(), name, k8stypes.StrategicMergePatchType, patch, v1.PatchOptions{})
+			}
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Str("target", target).Msg("Failed to trigger rollout")
+			return fmt.Errorf("error patching %s '%s' in namespace '%s': %w", kind, name, namespace, err)
+		}
+	}
+
+	log.Info().Int("targets", len(appConfig.RolloutTargets)).Msg("Rollout triggered successfully")
+	return nil
+}
+
+// putRunReport stores a run report (and its signature) under the keys of data in the report ConfigMap, creating the
+// ConfigMap when missing. Only those keys are patched, so the loaders of a fleet can share the ConfigMap without
+// conflicting.
+func putRunReport(appConfig *AppConfig, data map[string]string) error {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	namespace := appConfig.ReportConfigMapNamespace
+	name := appConfig.ReportConfigMapName
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+	ctx := appConfig.runContext()
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": data,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal report patch: %w", err)
+	}
+	_, err = configMaps.Patch(ctx, name, k8stypes.MergePatchType, patch, v1.PatchOptions{})
+	if k8serrors.IsNotFound(err) {
+		configmap := &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       data,
+		}
+		_, err = configMaps.Create(ctx, configmap, v1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			// another loader created it in the meantime, patch it on the next attempt
+			return k8serrors.NewConflict(corev1.Resource("configmaps"), name, err)
+		}
+		if err != nil {
+			return fmt.Errorf("error creating report configmap '%s' in namespace '%s': %w", name, namespace, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error patching report configmap '%s' in namespace '%s': %w", name, namespace, err)
+	}
+	return nil
+}
+
+// KeyIdentity identifies a key imported from the keys Secret on the Secret itself, without any key material.
+type KeyIdentity struct {
+	Entry   int    `json:"entry"`
+	Address string `json:"address"`
+	Role    string `json:"role"`
+	// PubKeyFingerprint is the first 16 bytes of the SHA-256 of the public key, hex encoded.
+	PubKeyFingerprint string `json:"pubkey_fingerprint"`
+}
+
+// keyIdentities returns the identities of the imported keys, read back from the keyring.
+func keyIdentities(walletKeyring keyring.Keyring, importedKeys []ImportedKey) ([]KeyIdentity, error) {
+	identities := make([]KeyIdentity, 0, len(importedKeys))
+	for _, key := range importedKeys {
+		record, err := walletKeyring.Key(key.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key %s: %w", key.Name, err)
+		}
+		pubKey, err := record.GetPubKey()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read public key of key %s: %w", key.Name, err)
+		}
+		digest := sha256.Sum256(pubKey.Bytes())
+		identities = append(identities, KeyIdentity{
+			Entry:             key.EntryIndex,
+			Address:           key.Address,
+			Role:              key.Role,
+			PubKeyFingerprint: hex.EncodeToString(digest[:16]),
+		})
+	}
+	return identities, nil
+}
+
+// annotateKeysSecret records the identities of the imported keys as a JSON annotation of the keys Secret, mapping
+// the Secret to the keyring at a glance. The Secret is only patched when the annotation changes, so watch mode isn't
+// woken up by its own patch.
+func annotateKeysSecret(appConfig *AppConfig, walletKeyring keyring.Keyring, importedKeys []ImportedKey) error {
+	if appConfig.KeysSecretAnnotation == "" || appConfig.ConfigSource != KubernetesSource || len(appConfig.KeysData) > 0 {
+		return nil
+	}
+
+	identities, err := keyIdentities(walletKeyring, importedKeys)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(identities)
+	if err != nil {
+		return fmt.Errorf("unable to marshal key identities: %w", err)
+	}
+
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+	namespace := appConfig.KeysNamespace
+	name := appConfig.KeysSecretName
+	secrets := clientset.CoreV1().Secrets(namespace)
+	ctx := appConfig.runContext()
+
+	err = retryKubernetes(appConfig, "annotate keys secret", func() error {
+		secret, err := secrets.Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error reading secret '%s' in namespace '%s': %w", name, namespace, err)
+		}
+		if secret.Annotations[appConfig.KeysSecretAnnotation] == string(value) {
+			return nil
+		}
+
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{appConfig.KeysSecretAnnotation: string(value)},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("unable to marshal annotation patch: %w", err)
+		}
+		_, err = secrets.Patch(ctx, name, k8stypes.MergePatchType, patch, v1.PatchOptions{})
+		if err != nil {
+			return fmt.Errorf("error annotating secret '%s' in namespace '%s': %w", name, namespace, err)
+		}
+		log.Info().
+			Str("namespace", namespace).
+			Str("name", name).
+			Str("annotation", appConfig.KeysSecretAnnotation).
+			Int("keys", len(identities)).
+			Msg("Keys secret annotated")
+		return nil
+	})
+	if err != nil {
+		return withExitCode(ExitSourceError, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// runWithLeaderElection runs loop once this replica acquires the leader Lease, so only one replica of a
+// multi-replica Deployment writes keyrings and configs at a time. Standby replicas block until they are elected.
+// Losing the lease returns an error, so the process exits and restarts as a standby instead of racing the new leader.
+// A shutdown signal makes a standby return right away, while the leader releases the lease once loop returned.
+func runWithLeaderElection(appConfig *AppConfig, loop func() error) error {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	namespace := orDefault(appConfig.LeaderElectionNamespace, podNamespace())
+	identity := podName()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: v1.ObjectMeta{
+			Name:      appConfig.LeaderElectionLeaseName,
+			Namespace: namespace,
+		},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := make(chan error, 1)
+	var elected atomic.Bool
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   appConfig.LeaderElectionLeaseDuration,
+		RenewDeadline:   appConfig.LeaderElectionRenewDeadline,
+		RetryPeriod:     appConfig.LeaderElectionRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            appConfig.LeaderElectionLeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				elected.Store(true)
+				log.Info().Str("identity", identity).Msg("Acquired leadership, starting")
+				result <- loop()
+			},
+			OnStoppedLeading: func() {
+				log.Warn().Str("identity", identity).Msg("Lost leadership")
+				select {
+				case result <- fmt.Errorf("leader election lost by %s", identity):
+				default:
+				}
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					log.Info().Str("leader", leader).Msg("Waiting for leadership")
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error configuring leader election: %w", err)
+	}
+
+	log.Info().
+		Str("namespace", namespace).
+		Str("lease", appConfig.LeaderElectionLeaseName).
+		Str("identity", identity).
+		Msg("Starting leader election")
+
+	shutdown, stop := shutdownContext()
+	defer stop()
+
+	go elector.Run(ctx)
+	select {
+	case err := <-result:
+		return err
+	case <-shutdown.Done():
+		if !elected.Load() {
+			log.Info().Msg("Shutdown requested while waiting for leadership, exiting")
+			return nil
+		}
+		// loop handles the signal itself, wait for it before releasing the lease
+		return <-result
+	}
+}
+
+// leading runs loop under leader election when it is enabled, or directly otherwise.
+func leading(appConfig *AppConfig, loop func() error) error {
+	if !appConfig.LeaderElection {
+		return loop()
+	}
+	return runWithLeaderElection(appConfig, loop)
+}
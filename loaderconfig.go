@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loaderConfigFileEnv points at the YAML document holding the loader's own settings.
+const loaderConfigFileEnv = "LOADER_CONFIG_FILE"
+
+// settingEnvs returns the environment variables settable from the loader config file.
+func settingEnvs() map[string]bool {
+	envs := make(map[string]bool, len(settingFlags))
+	for _, setting := range settingFlags {
+		envs[setting.Env] = true
+	}
+	delete(envs, loaderConfigFileEnv)
+	return envs
+}
+
+// settingValue formats a YAML value the way its environment variable is written: lists are comma-separated.
+func settingValue(key string, value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			formatted, err := settingValue(key, item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, formatted)
+		}
+		return strings.Join(items, ","), nil
+	case map[interface{}]interface{}:
+		return "", fmt.Errorf("setting %s: expected a scalar or a list", key)
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// applyLoaderConfigFile exports the settings of the LOADER_CONFIG_FILE document to their environment variables,
+// unless they are already set: flags and environment variables (including the .env file) override the file.
+// Keys are the environment variable names in lower case, e.g. `keys_file_path: ./keys.json`.
+// Example document:
+//
+//	config_source: kubernetes
+//	keys_secret_name: pocket-keys
+//	keyring_backend: test
+//	keyring_dir: /home/pocket/.pocket
+//	relayminer_config_output_target: configmap
+//	rollout_targets:
+//	  - deployment/relayminer
+func applyLoaderConfigFile() error {
+	path := expandHome(os.Getenv(loaderConfigFileEnv))
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read loader config file '%s': %w", path, err)
+	}
+
+	settings := make(map[string]interface{})
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		return fmt.Errorf("unable to unmarshall loader config file '%s': %w", path, err)
+	}
+
+	envs := settingEnvs()
+	unknown := make([]string, 0)
+	for key, value := range settings {
+		env := strings.ToUpper(key)
+		if !envs[env] {
+			unknown = append(unknown, key)
+			continue
+		}
+		if _, ok := os.LookupEnv(env); ok {
+			continue
+		}
+
+		formatted, err := settingValue(key, value)
+		if err != nil {
+			return fmt.Errorf("invalid loader config file '%s': %w", path, err)
+		}
+		if err := os.Setenv(env, formatted); err != nil {
+			return err
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown settings in loader config file '%s': %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Run lock modes keeping concurrent loader instances from interleaving writes
+const (
+	// RunLockNone takes no lock.
+	RunLockNone string = "none"
+	// RunLockFile takes an exclusive flock on a file next to the keyring (default).
+	RunLockFile string = "file"
+	// RunLockLease holds a coordination.k8s.io Lease for the duration of the pass.
+	RunLockLease string = "lease"
+)
+
+// runLockPollInterval is how often a busy file lock is retried.
+const runLockPollInterval = 200 * time.Millisecond
+
+// runLockPath returns the lock file of the pass, RUN_LOCK_PATH or a file in the keyring directory.
+func runLockPath(appConfig *AppConfig) string {
+	return orDefault(appConfig.RunLockPath, filepath.Join(appConfig.KeyringDir, ".keyring-loader.lock"))
+}
+
+// acquireFileLock takes an exclusive lock (flock, LockFileEx on Windows) on the lock file, waiting up to RunLockTimeout for another instance to
+// release it. The lock is released by the returned function, or by the kernel if the process dies.
+func acquireFileLock(appConfig *AppConfig) (func(), error) {
+	path := runLockPath(appConfig)
+	if err := mkdirAllMode(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("unable to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file '%s': %w", path, err)
+	}
+
+	deadline := time.Now().Add(appConfig.RunLockTimeout)
+	waiting := false
+	for {
+		busy, err := tryLockFile(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("unable to lock '%s': %w", path, err)
+		}
+		if !busy {
+			break
+		}
+		if err := appConfig.runContext().Err(); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("stopped waiting for lock '%s': %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			_ = file.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for lock '%s': %w", appConfig.RunLockTimeout, path, ErrRunLockHeld)
+		}
+		if !waiting {
+			log.Info().Str("path", path).Msg("Another instance holds the run lock, waiting")
+			waiting = true
+		}
+		time.Sleep(runLockPollInterval)
+	}
+
+	log.Debug().Str("path", path).Msg("Run lock acquired")
+	return func() {
+		unlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+// acquireLeaseLock holds the RUN_LOCK_LEASE_NAME Lease, waiting up to RunLockTimeout for another instance to
+// release it. The returned function releases the Lease; a crashed holder's Lease expires after the lease duration.
+func acquireLeaseLock(appConfig *AppConfig) (func(), error) {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := orDefault(appConfig.LeaderElectionNamespace, podNamespace())
+	identity := podName()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: v1.ObjectMeta{
+			Name:      appConfig.RunLockLeaseName,
+			Namespace: namespace,
+		},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	acquired := make(chan struct{})
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   appConfig.LeaderElectionLeaseDuration,
+		RenewDeadline:   appConfig.LeaderElectionRenewDeadline,
+		RetryPeriod:     appConfig.LeaderElectionRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            appConfig.RunLockLeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				close(acquired)
+				<-ctx.Done()
+			},
+			OnStoppedLeading: func() {},
+			OnNewLeader: func(holder string) {
+				if holder != identity {
+					log.Info().Str("holder", holder).Str("lease", appConfig.RunLockLeaseName).Msg("Another instance holds the run lock, waiting")
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error configuring run lock: %w", err)
+	}
+
+	// the lease outlives the pass deadline: it is only released by the returned function, once the pass has returned
+	ctx, cancel := context.WithCancel(context.WithoutCancel(appConfig.runContext()))
+	stopped := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-acquired:
+	case <-time.After(appConfig.RunLockTimeout):
+		cancel()
+		<-stopped
+		return nil, fmt.Errorf("timed out after %s waiting for lease %s/%s: %w", appConfig.RunLockTimeout, namespace, appConfig.RunLockLeaseName, ErrRunLockHeld)
+	case <-appConfig.runContext().Done():
+		cancel()
+		<-stopped
+		return nil, fmt.Errorf("stopped waiting for lease %s/%s: %w", namespace, appConfig.RunLockLeaseName, appConfig.runContext().Err())
+	case <-stopped:
+		cancel()
+		return nil, fmt.Errorf("stopped waiting for lease %s/%s: %w", namespace, appConfig.RunLockLeaseName, ctx.Err())
+	}
+
+	log.Debug().Str("namespace", namespace).Str("lease", appConfig.RunLockLeaseName).Msg("Run lock acquired")
+	return func() {
+		cancel()
+		<-stopped
+	}, nil
+}
+
+// acquireRunLock takes the run lock configured by RUN_LOCK, so two loader instances targeting the same keyring
+// and outputs can't interleave their writes. The returned function releases it.
+func acquireRunLock(appConfig *AppConfig) (func(), error) {
+	switch appConfig.RunLock {
+	case RunLockFile:
+		return acquireFileLock(appConfig)
+	case RunLockLease:
+		return acquireLeaseLock(appConfig)
+	default:
+		return func() {}, nil
+	}
+}
@@ -5,9 +5,10 @@ package main
 // Also, populates relay miner configuration as required.
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/codec/types"
@@ -16,27 +17,90 @@ import (
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
 	"github.com/cosmos/go-bip39"
 	"github.com/joho/godotenv"
 	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	k8syaml "sigs.k8s.io/yaml"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // AppConfig centralizes all environment-driven settings.
 type AppConfig struct {
+	// RunMode is either once (default) or watch.
+	RunMode       string
+	WatchDebounce time.Duration
+	// ResyncInterval re-runs the pass periodically in watch/daemon modes (0 disables resyncs).
+	ResyncInterval time.Duration
+	// StatusSocketPath is the unix socket serving the status of the watch and daemon modes (empty disables it).
+	StatusSocketPath string
+	// status tracks the passes of the watch and daemon modes for the status socket, nil otherwise.
+	status *statusTracker
+	// ShutdownTimeout bounds how long the long-lived modes wait for the in-flight pass on SIGTERM/SIGINT.
+	ShutdownTimeout time.Duration
+	// OperatorNamespace limits the operator to WalletKeyImports in a namespace (empty watches all namespaces).
+	OperatorNamespace string
+
+	// LeaderElection makes the replicas of the long-lived modes elect a single writer through a Lease.
+	LeaderElection              bool
+	LeaderElectionNamespace     string
+	LeaderElectionLeaseName     string
+	LeaderElectionLeaseDuration time.Duration
+	LeaderElectionRenewDeadline time.Duration
+	LeaderElectionRetryPeriod   time.Duration
+
+	// RBACPreflight reviews the Kubernetes permissions a pass needs before it starts.
+	RBACPreflight bool
+	// HealthListenAddress is where the probe server of the hold, watch and daemon run modes listens.
+	HealthListenAddress string
+	// PprofListenAddress serves the pprof profiles in the long-lived run modes (empty disables it), loopback only.
+	PprofListenAddress string
+	// HealthStuckAfter fails the liveness probe of the watch and daemon modes when a pass runs longer (0 disables it).
+	HealthStuckAfter time.Duration
+
+	// Webhook settings, used by the webhook run mode to inject the loader into labeled pods.
+	WebhookListenAddress      string
+	WebhookTLSCertFile        string
+	WebhookTLSKeyFile         string
+	WebhookLoaderImage        string
+	WebhookLoaderEnvConfigMap string
+	WebhookKeyringMountPath   string
+	WebhookConfigMountPath    string
+
 	GenerateRelayMinerConfig bool
 	AddressPrefix            string
-	KeyringAppName           string
-	KeyringBackend           string
+	// AddressAllowlist, when set, are the only addresses keys may be imported as; AddressDenylist are never imported.
+	AddressAllowlist []string
+	AddressDenylist  []string
+	KeyringAppName   string
+	KeyringBackend   string
+	// KeyringDirMode is the mode KeyringDir is created with when missing.
+	KeyringDirMode os.FileMode
+	// Umask is the umask of the process, PermissionsCheck what to do with key material other users can read.
+	Umask            os.FileMode
+	PermissionsCheck string
+	// KeyringMinFreeMB is the free space KeyringDir must have left before a pass imports keys (0 disables it).
+	KeyringMinFreeMB int
+	// FIPSMode requires the Go FIPS 140-3 module and a keyring backend whose encryption is approved, see checkFIPS.
+	FIPSMode bool
+	// LockMemory locks the process memory so key material is never swapped out, CoreDumps allows core dumps of it.
+	LockMemory bool
+	CoreDumps  bool
+	// KeySelfTest signs and verifies a random payload with every imported key, see selfTestKeys.
+	KeySelfTest bool
 	/*
 	 * Directory for storing the keyring (default: shannon-keyring-loader)
 	 * IMPORTANT: this will work only for test which will write to this path
@@ -51,12 +115,228 @@ type AppConfig struct {
 	KeysSecretName string
 	KeysSecretKey  string
 	KeysFilePath   string
+	// KeysSecretAnnotation is set on the keys Secret with the addresses and public key fingerprints of the imported
+	// keys (empty disables it).
+	KeysSecretAnnotation string
 
 	RelayMinerConfigNamespace      string
 	RelayMinerConfigName           string
 	RelayMinerConfigKey            string
 	RelayMinerConfigFilePath       string
 	RelayMinerConfigFileOutputPath string
+
+	// RelayMinerConfigOutputTarget is where the generated config goes: a file, a configmap or a secret.
+	RelayMinerConfigOutputTarget    string
+	RelayMinerConfigOutputNamespace string
+	RelayMinerConfigOutputName      string
+	RelayMinerConfigOutputKey       string
+	// RelayMinerConfigHashAnnotation is set on the output ConfigMap/Secret with the SHA-256 of the config.
+	RelayMinerConfigHashAnnotation string
+	// RelayMinerConfigReloaderMatch adds `reloader.stakater.com/match: "true"` to the output ConfigMap/Secret.
+	RelayMinerConfigReloaderMatch bool
+
+	// RolloutTargets are `deployment/<name>` or `statefulset/<name>` workloads whose pod template gets
+	// annotated with the config hash after a successful write (empty disables rollouts).
+	RolloutTargets   []string
+	RolloutNamespace string
+
+	// BackendDiscovery resolves supplier backend urls from Services labeled with BackendDiscoveryLabel=<service_id>.
+	BackendDiscovery          bool
+	BackendDiscoveryNamespace string
+	BackendDiscoveryLabel     string
+	BackendDiscoveryScheme    string
+
+	// SupplierStakeConfigOutputDir receives a stake config per operator key (empty disables them).
+	SupplierStakeConfigOutputDir string
+	SupplierStakeAmount          string
+	// SupplierAutoStake stakes the operator keys that aren't staked yet, from their supplier stake config.
+	SupplierAutoStake bool
+	// UnsignedTxOutputDir receives the transactions of SupplierAutoStake and ApplicationAutoDelegate unsigned, for
+	// offline signing, instead of broadcasting them (empty broadcasts them).
+	UnsignedTxOutputDir string
+	// OnchainSuppliers adds (add) or also removes (sync) suppliers to match the services the imported operator keys
+	// are staked for on chain (off disables it). The added ones listen on OnchainSuppliersListenUrl and forward to
+	// OnchainSuppliersBackendUrl, where {service_id} is replaced.
+	OnchainSuppliers           string
+	OnchainSuppliersListenUrl  string
+	OnchainSuppliersBackendUrl string
+
+	// ApplicationConfigOutputDir receives an AppGate-style config per application key (empty disables them).
+	ApplicationConfigOutputDir   string
+	ApplicationListeningEndpoint string
+	ApplicationQueryNodeRPCUrl   string
+	ApplicationQueryNodeGRPCUrl  string
+	// ApplicationGatewayAddresses are the gateways application keys are delegated to, unless their entry lists its own.
+	ApplicationGatewayAddresses []string
+	// ApplicationAutoDelegate delegates the application keys to the gateways they aren't delegated to yet.
+	ApplicationAutoDelegate bool
+
+	// Service groups are optional, leaving the name (or path) empty disables them.
+	ServiceGroupsNamespace string
+	ServiceGroupsName      string
+	ServiceGroupsKey       string
+	ServiceGroupsFilePath  string
+
+	// Tenants manifest of the batch mode, leaving the name (or path) empty processes a single keyring.
+	TenantsNamespace string
+	TenantsName      string
+	TenantsKey       string
+	TenantsFilePath  string
+
+	// Kubernetes requests failing with retriable errors are retried with exponential backoff and jitter.
+	KubernetesRetryAttempts     int
+	KubernetesRetryInitialDelay time.Duration
+	KubernetesRetryMaxDelay     time.Duration
+
+	// RunTimeout bounds a whole pass: source fetches, keyring operations and writes (0 disables it).
+	RunTimeout time.Duration
+	// ctx carries the RunTimeout deadline of the current pass, see runContext.
+	ctx context.Context
+	// configOutputPath is the file the current pass wrote the relay miner config to, recorded in the state file.
+	configOutputPath string
+
+	// APIWaitTimeout bounds how long startup waits for the Kubernetes API server (0 disables the wait).
+	APIWaitTimeout time.Duration
+
+	// RunLock keeps concurrent instances from interleaving writes: none, file (flock) or lease.
+	RunLock          string
+	RunLockPath      string
+	RunLockLeaseName string
+	RunLockTimeout   time.Duration
+
+	// StateFilePath records the last successful pass, so unchanged inputs skip the pass (empty disables it).
+	StateFilePath string
+
+	// CompletionFilePath receives a sentinel file after every successful pass (empty disables it).
+	CompletionFilePath string
+	// CompletionEvent emits a Kubernetes Event after every pass, on success and on failure.
+	CompletionEvent bool
+	// ReportFilePath receives a JSON summary of every pass, successful or not (empty disables it).
+	ReportFilePath string
+	// ReportConfigMapName receives the same summary under ReportConfigMapKey (empty disables it), the loaders of a
+	// fleet sharing the ConfigMap with a key each.
+	ReportConfigMapNamespace string
+	ReportConfigMapName      string
+	ReportConfigMapKey       string
+	// ExportAgeRecipients encrypt the run reports and the relay miner config backups (empty leaves them in clear).
+	ExportAgeRecipients []string
+	// ReportSigningKeyFile (a hex secp256k1 private key) or ReportSigningKeyName (a keyring key) signs the reports,
+	// see signRunReport.
+	ReportSigningKeyFile string
+	ReportSigningKeyName string
+	// AuditLogPath receives a JSON line for every key imported or deleted and every config written (empty disables it).
+	AuditLogPath string
+	// NotifyWebhookURL receives a JSON or Slack notification after the passes selected by NotifyOn (empty disables it).
+	NotifyWebhookURL string
+	NotifyFormat     string
+	NotifyOn         string
+	// EventTarget is the object the Events are recorded on: the loader's pod or the generated config's output.
+	EventTarget string
+
+	// PushgatewayURL receives the run metrics of one-shot runs (empty disables it), grouped by job and instance.
+	PushgatewayURL      string
+	PushgatewayJob      string
+	PushgatewayInstance string
+
+	// PreImportHook and PostImportHook are shell commands run before and after the import of a pass, with a JSON
+	// summary of the pass on stdin (empty disables them). HookTimeout bounds each of them.
+	PreImportHook  string
+	PostImportHook string
+	HookTimeout    time.Duration
+
+	// MaxKeys caps the number of keys the entries may derive, catching fat-fingered ranges (0 disables it).
+	MaxKeys int
+
+	// FailMode decides whether a failing keys.json entry aborts the pass (abort) or is skipped and reported at the end (continue).
+	FailMode string
+
+	// EmptySupplierMode decides what happens when suppliers end up without signing keys (warn or fail).
+	EmptySupplierMode string
+	// KeyExpiryMode decides what happens when keys are past their expires_at (warn or fail), KeyExpiryWarning how
+	// long before it to start warning.
+	KeyExpiryMode    string
+	KeyExpiryWarning time.Duration
+
+	// ChainGRPCUrl is the gRPC endpoint of the full node the on-chain features query through the ChainClient (empty
+	// disables them), trusting ChainGRPCCAFile over TLS when set. Each query is bounded by ChainQueryTimeout and tried
+	// up to ChainRetryAttempts times, from ChainRetryInitialDelay up to ChainRetryMaxDelay apart. ChainRPCUrl is the
+	// CometBFT RPC endpoint of the node, the default of the query node urls of the generated configs. ChainRESTUrl is
+	// its REST (LCD) API, the read-only queries fall back to when the gRPC endpoint is unavailable.
+	ChainGRPCUrl           string
+	ChainGRPCCAFile        string
+	ChainRPCUrl            string
+	ChainRESTUrl           string
+	ChainQueryTimeout      time.Duration
+	ChainRetryAttempts     int
+	ChainRetryInitialDelay time.Duration
+	ChainRetryMaxDelay     time.Duration
+	// AccountCheck decides what happens when imported keys have no account on chain (off, warn or fail).
+	AccountCheck string
+	// ServiceCheck decides what happens when keys are registered to services that don't exist on chain (off, warn or
+	// fail).
+	ServiceCheck string
+	// ChainID is the chain the node must run and the transactions are signed for. TxGasLimit is their gas limit (0 simulates it,
+	// scaled by TxGasAdjustment) and TxGasPrices their fees per unit of gas, unless TxFees sets them outright.
+	ChainID         string
+	TxGasLimit      int
+	TxGasAdjustment float64
+	TxGasPrices     string
+	TxFees          string
+	TxMemo          string
+	// TxBroadcastMode decides how long a broadcast waits (sync, async or block); in block, the transactions are looked
+	// up every TxPollInterval until included in a block, for up to TxTimeout.
+	TxBroadcastMode string
+	TxPollInterval  time.Duration
+	TxTimeout       time.Duration
+	// BalanceCheck decides what happens when supplier keys hold less than MinBalance (off, warn or fail).
+	BalanceCheck string
+	MinBalance   string
+	// StakeCheck decides what happens when the supplier stake of operator keys doesn't match their services (off, warn
+	// or fail).
+	StakeCheck string
+	// DelegationCheck decides what happens when application keys aren't delegated to their gateways (off, warn or
+	// fail).
+	DelegationCheck string
+	// ParamsCheck decides what happens when the generated configs hold values the chain params would reject (off,
+	// warn or fail).
+	ParamsCheck string
+	// FaucetURL is the faucet funding the imported keys never funded, on LocalNet and the testnets only (empty
+	// disables it), with FaucetDenom, at most FaucetMaxRequests per pass and FaucetInterval apart.
+	FaucetURL         string
+	FaucetDenom       string
+	FaucetInterval    time.Duration
+	FaucetMaxRequests int
+
+	// Backend preflight probes every supplier backend_url after generating the config.
+	BackendPreflight        bool
+	BackendPreflightTimeout time.Duration
+	BackendPreflightFail    bool
+
+	// RelayMinerOutputFormat is the format of the generated relay miner config (yaml or json).
+	RelayMinerOutputFormat string
+
+	// RelayMinerConfigDiff logs the paths changed against the previously generated config before overwriting it,
+	// optionally writing the changes as JSON to RelayMinerConfigDiffOutputPath. RelayMinerConfigDiffValues also logs
+	// their old and new values.
+	RelayMinerConfigDiff           bool
+	RelayMinerConfigDiffValues     bool
+	RelayMinerConfigDiffOutputPath string
+
+	// RelayMinerConfigBackups is how many timestamped backups of the output config are kept (0 disables backups).
+	RelayMinerConfigBackups int
+
+	// RelayMinerConfigFileMode overrides the output file mode (0 keeps the input file mode).
+	RelayMinerConfigFileMode os.FileMode
+	// OutputUid and OutputGid set the owner of the output file and keyring dir (-1 leaves them unchanged).
+	OutputUid int
+	OutputGid int
+
+	// RelayMinerConfigData is set by the operator from a RelayMinerConfigTemplate, replacing the base config source.
+	RelayMinerConfigData []byte
+	// KeysData is set by the interactive command from the entered key, replacing the keys source.
+	KeysData []byte
+	// KeySelection is set by the operator from a RelayMinerConfigTemplate, restricting the keys taken from keys.json.
+	KeySelection *KeySelection
 }
 
 // WalletKeySpec represents the structure for key definition and import.
@@ -67,8 +347,68 @@ type WalletKeySpec struct {
 	EndIndex   int      `json:"end_index,omitempty"`
 	Hex        string   `json:"hex,omitempty"`
 	ServiceID  []string `json:"service_id,omitempty"`
+	// ServiceGroup references named groups of service IDs defined in the service groups document.
+	ServiceGroup []string `json:"service_group,omitempty"`
+	// Distribution controls how the derived keys of a mnemonic range are assigned to the listed services.
+	Distribution string `json:"distribution,omitempty"`
+	// Role is either operator (default, registered as relay miner signing key) or owner (imported only).
+	Role string `json:"role,omitempty"`
+	// OwnerAddress is the owner of the suppliers operated by this entry's keys (defaults to the operator itself).
+	OwnerAddress string `json:"owner_address,omitempty"`
+	// Endpoints are the publicly exposed endpoints written to the supplier stake configs of this entry's keys.
+	Endpoints []StakeEndpoint `json:"endpoints,omitempty"`
+	// GatewayAddresses are the gateways the application keys of this entry are delegated to, overriding
+	// APPLICATION_GATEWAY_ADDRESSES.
+	GatewayAddresses []string `json:"gateway_addresses,omitempty"`
+	// SupplierOverrides tunes the suppliers of the services this entry's keys are registered to.
+	SupplierOverrides *SupplierOverrides `json:"supplier_overrides,omitempty"`
+	// ExpectedAddresses are the addresses the operator recorded for the keys of the entry, in derivation order,
+	// checked before any of them is imported.
+	ExpectedAddresses []string `json:"expected_addresses,omitempty"`
+	// RotateAfter and ExpiresAt are the rotation and expiry deadlines of the entry's keys, see checkKeyDeadlines.
+	RotateAfter *Deadline `json:"rotate_after,omitempty"`
+	ExpiresAt   *Deadline `json:"expires_at,omitempty"`
+	// Disabled excludes the entry from import and registration without removing it from keys.json.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// ImportedKey records a key imported into the keyring and where it was registered.
+type ImportedKey struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Role    string `json:"role"`
+	// OwnerAddress is only set for operator keys.
+	OwnerAddress string `json:"owner_address,omitempty"`
+	// ServiceIds are the concrete supplier service IDs the key signs for; empty means default signing keys.
+	ServiceIds []string `json:"service_ids,omitempty"`
+	// GatewayAddresses are the gateways an application key is delegated to.
+	GatewayAddresses []string `json:"gateway_addresses,omitempty"`
+	EntryIndex       int      `json:"entry_index"`
+	// DerivationIndex is the HD index for mnemonic entries and -1 for hex entries.
+	DerivationIndex int `json:"derivation_index"`
+	// RotateAfter and ExpiresAt are the deadlines of the entry.
+	RotateAfter *Deadline `json:"rotate_after,omitempty"`
+	ExpiresAt   *Deadline `json:"expires_at,omitempty"`
 }
 
+// Key roles following Shannon's supplier operator/owner model
+const (
+	// OperatorRole keys sign relays and are registered in the relay miner config (default).
+	OperatorRole string = "operator"
+	// OwnerRole keys own the supplier stake; they are imported but never registered as signing keys.
+	OwnerRole string = "owner"
+	// ApplicationRole keys are application keys, they get an AppGate-style config instead of relay miner registration.
+	ApplicationRole string = "application"
+)
+
+// Distribution modes for keys derived from a mnemonic range
+const (
+	// DistributionAll registers every derived key to every listed service (default).
+	DistributionAll string = "all"
+	// DistributionRoundRobin assigns derived keys to the listed services in turn (key 0→svc A, key 1→svc B, …).
+	DistributionRoundRobin string = "round_robin"
+)
+
 // Source types for config loader
 const (
 	KubernetesSource string = "kubernetes"
@@ -80,9 +420,103 @@ const (
 // getenv returns env value or fallback.
 func getenv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
-		return v
+		return recordSetting(key, v)
+	}
+	return recordSetting(key, fallback)
+}
+
+// getenvList returns env value split on commas, with blank items dropped. Returns nil when unset.
+func getenvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	recordSetting(key, strings.Join(items, ","))
+	return items
+}
+
+// getenvPath returns env value or fallback as a local path, expanding a leading `~` to the home directory, which
+// shells don't do in flags and config files.
+func getenvPath(key, fallback string) string {
+	return expandHome(getenv(key, fallback))
+}
+
+// expandHome replaces a leading `~` of path with the home directory of the user.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// getenvDuration returns env value parsed as a duration (e.g. 5s, 1m) or fallback.
+func getenvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		recordSetting(key, fallback.String())
+		return fallback, nil
+	}
+
+	recordSetting(key, v)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getenvInt returns env value parsed as an integer or fallback.
+func getenvInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		recordSetting(key, strconv.Itoa(fallback))
+		return fallback, nil
+	}
+
+	recordSetting(key, v)
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer for %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// getenvFloat returns env value parsed as a float or fallback.
+func getenvFloat(key string, fallback float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		recordSetting(key, strconv.FormatFloat(fallback, 'f', -1, 64))
+		return fallback, nil
+	}
+
+	recordSetting(key, v)
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number for %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// getenvFileMode returns env value parsed as an octal file mode (e.g. 0640) or fallback.
+func getenvFileMode(key string, fallback os.FileMode) (os.FileMode, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		recordSetting(key, fmt.Sprintf("%04o", fallback))
+		return fallback, nil
+	}
+
+	recordSetting(key, v)
+	mode, err := strconv.ParseUint(v, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode for %s: %s", key, v)
 	}
-	return fallback
+	return os.FileMode(mode), nil
 }
 
 // loadEnv loads environment variables from a .env file if it exists in the current directory and returns an error if loading fails.
@@ -95,8 +529,53 @@ func loadEnv() error {
 	return nil
 }
 
+// Log formats
+const (
+	// LogFormatConsole writes human-readable, optionally colored, lines (default).
+	LogFormatConsole string = "console"
+	// LogFormatJSON writes one JSON object per line.
+	LogFormatJSON string = "json"
+)
+
+// keyLog logs the per-key lines of the import (one or more per key), sampled when LOG_SAMPLE_BURST is set so passes
+// over thousands of keys don't drown the log aggregators. Warnings and errors are never sampled.
+var keyLog = log.Logger
+
+// configureKeyLog derives keyLog from the global logger: the first LOG_SAMPLE_BURST debug and info lines of every
+// LOG_SAMPLE_PERIOD are logged, then one out of LOG_SAMPLE_EVERY (0 drops them).
+func configureKeyLog() error {
+	burst, err := getenvInt("LOG_SAMPLE_BURST", 0)
+	if err != nil {
+		return err
+	}
+	period, err := getenvDuration("LOG_SAMPLE_PERIOD", time.Second)
+	if err != nil {
+		return err
+	}
+	every, err := getenvInt("LOG_SAMPLE_EVERY", 100)
+	if err != nil {
+		return err
+	}
+
+	keyLog = log.Logger
+	if burst <= 0 {
+		return nil
+	}
+	if every < 0 {
+		return fmt.Errorf("invalid LOG_SAMPLE_EVERY: %d", every)
+	}
+	sampler := &zerolog.BurstSampler{
+		Burst:       uint32(burst),
+		Period:      period,
+		NextSampler: &zerolog.BasicSampler{N: uint32(every)},
+	}
+	keyLog = log.Logger.Sample(zerolog.LevelSampler{TraceSampler: sampler, DebugSampler: sampler, InfoSampler: sampler})
+	return nil
+}
+
 // configureLogger initializes global logging configuration based on environment variables and application config.
-// It sets log level, console output format, and log colorization. Returns an error if log level parsing fails.
+// It sets log level, output format (console or JSON), and log colorization. Returns an error if log level parsing
+// fails or the format is unknown.
 func configureLogger() error {
 	// this will log the envs on his own because need to be set up before app config.
 	level, err := zerolog.ParseLevel(getenv("LOG_LEVEL", "info"))
@@ -106,144 +585,777 @@ func configureLogger() error {
 	// Set the global log level
 	zerolog.SetGlobalLevel(level)
 
-	logColor := getenv("LOG_COLOR", "true") == "true"
+	// secrets never reach the logs, even when a debug line slips one in
+	output := scrubbingWriter{out: os.Stderr}
+
+	switch format := getenv("LOG_FORMAT", LogFormatConsole); format {
+	case LogFormatJSON:
+		// one JSON object per line, as zerolog writes them, for Loki/Elasticsearch pipelines
+		log.Logger = zerolog.New(output).With().Timestamp().Logger()
+	case LogFormatConsole:
+		logColor := getenv("LOG_COLOR", "true") == "true"
+
+		consoleWriter := zerolog.ConsoleWriter{
+			Out:        output,
+			TimeFormat: time.RFC3339,
+			NoColor:    !logColor,
+		}
 
-	consoleWriter := zerolog.ConsoleWriter{
-		Out:        os.Stderr,
-		TimeFormat: time.RFC3339,
-		NoColor:    !logColor,
+		log.Logger = log.With().Timestamp().Logger().Output(consoleWriter)
+	default:
+		return fmt.Errorf("unsupported log format: %s", format)
 	}
 
-	log.Logger = log.With().Timestamp().Logger().Output(consoleWriter)
+	return configureKeyLog()
+}
+
+// runContext returns the context of the current pass, canceled at its RunTimeout deadline.
+func (c *AppConfig) runContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
 
+// checkRunContext fails once the pass is canceled, e.g. at its RunTimeout deadline. Checked before every write and
+// broadcast, so a pass overrunning its deadline stops at the next one instead of writing behind the caller's back.
+func checkRunContext(appConfig *AppConfig) error {
+	if err := appConfig.runContext().Err(); err != nil {
+		return withExitCode(ExitSourceError, fmt.Errorf("%w (%s): %w", ErrRunTimeout, appConfig.RunTimeout, err))
+	}
 	return nil
 }
 
 // loadAppConfig loads and returns all configs from the environment (with defaults).
-func loadAppConfig() *AppConfig {
-	return &AppConfig{
+// Returns an error if a typed value (e.g. a duration) cannot be parsed.
+func loadAppConfig() (*AppConfig, error) {
+	var err error
+
+	// unset namespaces default to the pod's own (Downward API or service account), not to `default`
+	namespace := orDefault(podNamespace(), "default")
+
+	appConfig := &AppConfig{
+		RunMode:           getenv("RUN_MODE", OnceRunMode),
+		OperatorNamespace: getenv("OPERATOR_NAMESPACE", ""),
+
+		LeaderElection:          getenv("LEADER_ELECTION", "false") == "true",
+		LeaderElectionNamespace: getenv("LEADER_ELECTION_NAMESPACE", ""),
+		LeaderElectionLeaseName: getenv("LEADER_ELECTION_LEASE_NAME", "shannon-keyring-loader"),
+
+		RBACPreflight:       getenv("RBAC_PREFLIGHT", "true") == "true",
+		HealthListenAddress: getenv("HEALTH_LISTEN_ADDRESS", ":8081"),
+		PprofListenAddress:  getenv("PPROF_LISTEN_ADDRESS", ""),
+		StatusSocketPath:    getenvPath("STATUS_SOCKET_PATH", ""),
+
+		WebhookListenAddress:      getenv("WEBHOOK_LISTEN_ADDRESS", ":8443"),
+		WebhookTLSCertFile:        getenv("WEBHOOK_TLS_CERT_FILE", "/tls/tls.crt"),
+		WebhookTLSKeyFile:         getenv("WEBHOOK_TLS_KEY_FILE", "/tls/tls.key"),
+		WebhookLoaderImage:        getenv("WEBHOOK_LOADER_IMAGE", ""),
+		WebhookLoaderEnvConfigMap: getenv("WEBHOOK_LOADER_ENV_CONFIGMAP", ""),
+		WebhookKeyringMountPath:   getenv("WEBHOOK_KEYRING_MOUNT_PATH", "/home/pocket/.pocket"),
+		WebhookConfigMountPath:    getenv("WEBHOOK_CONFIG_MOUNT_PATH", "/home/pocket/.pocket/config"),
+
 		GenerateRelayMinerConfig: getenv("GENERATE_RELAYMINER_CONFIG", "true") == "true",
 		AddressPrefix:            getenv("ADDRESS_PREFIX", "pokt"),
+		AddressAllowlist:         getenvList("ADDRESS_ALLOWLIST"),
+		AddressDenylist:          getenvList("ADDRESS_DENYLIST"),
 
 		KeyringAppName: getenv("KEYRING_APP_NAME", "pocket"),
 		KeyringBackend: getenv("KEYRING_BACKEND", "test"),
-		KeyringDir:     getenv("KEYRING_DIR", "shannon-keyring-loader"),
+		FIPSMode:       getenv("FIPS_MODE", "false") == "true",
+		LockMemory:     getenv("LOCK_MEMORY", "false") == "true",
+		CoreDumps:      getenv("CORE_DUMPS", "false") == "true",
+		KeySelfTest:    getenv("KEY_SELF_TEST", "true") == "true",
+		KeyringDir:     getenvPath("KEYRING_DIR", "shannon-keyring-loader"),
 
 		ConfigSource: getenv("CONFIG_SOURCE", "file"),
 
-		KeysNamespace:  getenv("KEYS_NAMESPACE", "default"),
+		KeysNamespace:  getenv("KEYS_NAMESPACE", namespace),
 		KeysSecretName: getenv("KEYS_SECRET_NAME", "pocket-keys"),
 		KeysSecretKey:  getenv("KEYS_SECRET_KEY", "keys.json"),
-		KeysFilePath:   getenv("KEYS_FILE_PATH", "keys.json"),
+		KeysFilePath:   getenvPath("KEYS_FILE_PATH", "keys.json"),
+
+		KeysSecretAnnotation: getenv("KEYS_SECRET_ANNOTATION", ""),
 
-		RelayMinerConfigNamespace:      getenv("RELAYMINER_CONFIG_NAMESPACE", "default"),
+		RelayMinerConfigNamespace:      getenv("RELAYMINER_CONFIG_NAMESPACE", namespace),
 		RelayMinerConfigName:           getenv("RELAYMINER_CONFIG_NAME", "pocket-relayminer-config"),
 		RelayMinerConfigKey:            getenv("RELAYMINER_CONFIG_KEY", "config.yaml"),
-		RelayMinerConfigFilePath:       getenv("RELAYMINER_CONFIG_FILE_PATH", "config.yaml"),
-		RelayMinerConfigFileOutputPath: getenv("RELAYMINER_CONFIG_FILE_OUTPUT_PATH", "generated.config.yaml"),
+		RelayMinerConfigFilePath:       getenvPath("RELAYMINER_CONFIG_FILE_PATH", "config.yaml"),
+		RelayMinerConfigFileOutputPath: getenvPath("RELAYMINER_CONFIG_FILE_OUTPUT_PATH", "generated.config.yaml"),
+
+		RelayMinerConfigOutputTarget:    getenv("RELAYMINER_CONFIG_OUTPUT_TARGET", FileSource),
+		RelayMinerConfigOutputNamespace: getenv("RELAYMINER_CONFIG_OUTPUT_NAMESPACE", namespace),
+		RelayMinerConfigOutputName:      getenv("RELAYMINER_CONFIG_OUTPUT_NAME", "pocket-relayminer-generated-config"),
+		RelayMinerConfigOutputKey:       getenv("RELAYMINER_CONFIG_OUTPUT_KEY", "config.yaml"),
+		RelayMinerConfigHashAnnotation:  getenv("RELAYMINER_CONFIG_HASH_ANNOTATION", "pokt.network/config-hash"),
+		RelayMinerConfigReloaderMatch:   getenv("RELAYMINER_CONFIG_RELOADER_MATCH", "false") == "true",
+
+		RolloutTargets:   getenvList("ROLLOUT_TARGETS"),
+		RolloutNamespace: getenv("ROLLOUT_NAMESPACE", namespace),
+
+		BackendDiscovery:          getenv("BACKEND_DISCOVERY", "false") == "true",
+		BackendDiscoveryNamespace: getenv("BACKEND_DISCOVERY_NAMESPACE", namespace),
+		BackendDiscoveryLabel:     getenv("BACKEND_DISCOVERY_LABEL", "pokt.network/service-id"),
+		BackendDiscoveryScheme:    getenv("BACKEND_DISCOVERY_SCHEME", "http"),
+
+		SupplierStakeConfigOutputDir: getenvPath("SUPPLIER_STAKE_CONFIG_OUTPUT_DIR", ""),
+		SupplierStakeAmount:          getenv("SUPPLIER_STAKE_AMOUNT", ""),
+		SupplierAutoStake:            getenv("SUPPLIER_AUTO_STAKE", "false") == "true",
+		UnsignedTxOutputDir:          getenvPath("UNSIGNED_TX_OUTPUT_DIR", ""),
+
+		OnchainSuppliers:           getenv("ONCHAIN_SUPPLIERS", OnchainSuppliersOff),
+		OnchainSuppliersListenUrl:  getenv("ONCHAIN_SUPPLIERS_LISTEN_URL", ""),
+		OnchainSuppliersBackendUrl: getenv("ONCHAIN_SUPPLIERS_BACKEND_URL", ""),
+
+		ApplicationConfigOutputDir:   getenvPath("APPLICATION_CONFIG_OUTPUT_DIR", ""),
+		ApplicationListeningEndpoint: getenv("APPLICATION_LISTENING_ENDPOINT", "http://0.0.0.0:42069"),
+		ApplicationGatewayAddresses:  getenvList("APPLICATION_GATEWAY_ADDRESSES"),
+		ApplicationAutoDelegate:      getenv("APPLICATION_AUTO_DELEGATE", "false") == "true",
+		ApplicationQueryNodeRPCUrl:   getenv("APPLICATION_QUERY_NODE_RPC_URL", ""),
+		ApplicationQueryNodeGRPCUrl:  getenv("APPLICATION_QUERY_NODE_GRPC_URL", ""),
+
+		ServiceGroupsNamespace: getenv("SERVICE_GROUPS_NAMESPACE", namespace),
+		ServiceGroupsName:      getenv("SERVICE_GROUPS_NAME", ""),
+		ServiceGroupsKey:       getenv("SERVICE_GROUPS_KEY", "service-groups.yaml"),
+		ServiceGroupsFilePath:  getenvPath("SERVICE_GROUPS_FILE_PATH", ""),
+
+		TenantsNamespace: getenv("TENANTS_NAMESPACE", namespace),
+		TenantsName:      getenv("TENANTS_NAME", ""),
+		TenantsKey:       getenv("TENANTS_KEY", "tenants.yaml"),
+		TenantsFilePath:  getenvPath("TENANTS_FILE_PATH", ""),
+
+		FailMode: getenv("FAIL_MODE", FailModeAbort),
+
+		RunLock:          getenv("RUN_LOCK", RunLockFile),
+		RunLockPath:      getenvPath("RUN_LOCK_PATH", ""),
+		RunLockLeaseName: getenv("RUN_LOCK_LEASE_NAME", "shannon-keyring-loader-lock"),
+
+		StateFilePath:      getenvPath("STATE_FILE_PATH", ""),
+		CompletionFilePath: getenvPath("COMPLETION_FILE_PATH", ""),
+		CompletionEvent:    getenv("COMPLETION_EVENT", "false") == "true",
+		EventTarget:        getenv("EVENT_TARGET", EventTargetPod),
+
+		ReportFilePath:           getenvPath("REPORT_FILE_PATH", ""),
+		ReportConfigMapNamespace: getenv("REPORT_CONFIGMAP_NAMESPACE", namespace),
+		ReportConfigMapName:      getenv("REPORT_CONFIGMAP_NAME", ""),
+		ReportConfigMapKey:       getenv("REPORT_CONFIGMAP_KEY", ""),
+		ReportSigningKeyFile:     getenvPath("REPORT_SIGNING_KEY_FILE", ""),
+		ReportSigningKeyName:     getenv("REPORT_SIGNING_KEY_NAME", ""),
+		ExportAgeRecipients:      getenvList("EXPORT_AGE_RECIPIENTS"),
+		AuditLogPath:             getenvPath("AUDIT_LOG_PATH", ""),
+
+		NotifyWebhookURL: getenv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyFormat:     getenv("NOTIFY_FORMAT", NotifyFormatJSON),
+		NotifyOn:         getenv("NOTIFY_ON", NotifyOnAlways),
+
+		PushgatewayURL:      getenv("PUSHGATEWAY_URL", ""),
+		PushgatewayJob:      getenv("PUSHGATEWAY_JOB", "shannon-keyring-loader"),
+		PushgatewayInstance: getenv("PUSHGATEWAY_INSTANCE", ""),
+
+		PreImportHook:  getenv("PRE_IMPORT_HOOK", ""),
+		PostImportHook: getenv("POST_IMPORT_HOOK", ""),
+
+		EmptySupplierMode: getenv("EMPTY_SUPPLIER_MODE", EmptySupplierWarn),
+		KeyExpiryMode:     getenv("KEY_EXPIRY_MODE", KeyExpiryWarn),
+		PermissionsCheck:  getenv("PERMISSIONS_CHECK", PermissionsFix),
+
+		ChainGRPCUrl:    getenv("CHAIN_GRPC_URL", ""),
+		ChainGRPCCAFile: getenvPath("CHAIN_GRPC_CA_FILE", ""),
+		ChainRPCUrl:     getenv("CHAIN_RPC_URL", ""),
+		ChainRESTUrl:    getenv("CHAIN_REST_URL", ""),
+		AccountCheck:    getenv("ACCOUNT_CHECK", AccountCheckOff),
+		ServiceCheck:    getenv("SERVICE_CHECK", ServiceCheckOff),
+		ChainID:         getenv("CHAIN_ID", ""),
+		TxGasPrices:     getenv("TX_GAS_PRICES", "1upokt"),
+		TxFees:          getenv("TX_FEES", ""),
+		TxMemo:          getenv("TX_MEMO", ""),
+		TxBroadcastMode: getenv("TX_BROADCAST_MODE", TxBroadcastBlock),
+		BalanceCheck:    getenv("BALANCE_CHECK", BalanceCheckOff),
+		MinBalance:      getenv("MIN_BALANCE", "1000000upokt"),
+		StakeCheck:      getenv("STAKE_CHECK", StakeCheckOff),
+
+		DelegationCheck: getenv("DELEGATION_CHECK", DelegationCheckOff),
+		ParamsCheck:     getenv("PARAMS_CHECK", ParamsCheckOff),
+
+		FaucetURL:   getenv("FAUCET_URL", ""),
+		FaucetDenom: getenv("FAUCET_DENOM", "upokt"),
+
+		BackendPreflight:     getenv("BACKEND_PREFLIGHT", "false") == "true",
+		BackendPreflightFail: getenv("BACKEND_PREFLIGHT_FAIL", "false") == "true",
+
+		RelayMinerOutputFormat: getenv("RELAYMINER_OUTPUT_FORMAT", YAMLOutputFormat),
+
+		RelayMinerConfigDiff:           getenv("RELAYMINER_CONFIG_DIFF", "true") == "true",
+		RelayMinerConfigDiffValues:     getenv("RELAYMINER_CONFIG_DIFF_VALUES", "false") == "true",
+		RelayMinerConfigDiffOutputPath: getenvPath("RELAYMINER_CONFIG_DIFF_OUTPUT_PATH", ""),
 	}
-}
 
-// validateConfig ensures that the provided AppConfig has valid settings for a keyring backend and configuration source.
-// Returns an error if the keyring backend or config source is invalid.
-func validateConfig(appConfig *AppConfig) error {
-	log.Debug().Msg("Validating application configuration")
+	appConfig.KeyExpiryWarning, err = getenvDuration("KEY_EXPIRY_WARNING", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.ChainQueryTimeout, err = getenvDuration("CHAIN_QUERY_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.ChainRetryAttempts, err = getenvInt("CHAIN_RETRY_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.ChainRetryInitialDelay, err = getenvDuration("CHAIN_RETRY_INITIAL_DELAY", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.ChainRetryMaxDelay, err = getenvDuration("CHAIN_RETRY_MAX_DELAY", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.TxGasLimit, err = getenvInt("TX_GAS_LIMIT", 0)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.TxGasAdjustment, err = getenvFloat("TX_GAS_ADJUSTMENT", 1.5)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.TxPollInterval, err = getenvDuration("TX_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.TxTimeout, err = getenvDuration("TX_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.FaucetInterval, err = getenvDuration("FAUCET_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.FaucetMaxRequests, err = getenvInt("FAUCET_MAX_REQUESTS", 10)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.WatchDebounce, err = getenvDuration("WATCH_DEBOUNCE", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-	// TBD(@jorgecuesta) should we validate the k8s resources or files here or leave it to fail on the read?
-	if appConfig.KeyringBackend != "test" &&
-		appConfig.KeyringBackend != "pass" &&
-		appConfig.KeyringBackend != "os" {
-		log.Error().Str("backend", appConfig.KeyringBackend).Msg("Unsupported keyring backend")
-		return fmt.Errorf("unsupported keyring backend: %s", appConfig.KeyringBackend)
+	appConfig.ResyncInterval, err = getenvDuration("RESYNC_INTERVAL", 0)
+	if err != nil {
+		return nil, err
 	}
 
-	if appConfig.ConfigSource != KubernetesSource && appConfig.ConfigSource != FileSource {
-		log.Error().Str("source", appConfig.ConfigSource).Msg("Invalid config source")
-		return fmt.Errorf("invalid config source: %s", appConfig.ConfigSource)
+	appConfig.KubernetesRetryAttempts, err = getenvInt("KUBERNETES_RETRY_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
 	}
 
-	if !filepath.IsAbs(appConfig.KeyringDir) {
-		absPath, err := filepath.Abs(appConfig.KeyringDir)
-		if err != nil {
-			return fmt.Errorf("failed to convert to absolute path: %w", err)
-		}
-		appConfig.KeyringDir = absPath
+	appConfig.KubernetesRetryInitialDelay, err = getenvDuration("KUBERNETES_RETRY_INITIAL_DELAY", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Debug().Msg("Configuration validation successful")
-	return nil
-}
+	appConfig.KubernetesRetryMaxDelay, err = getenvDuration("KUBERNETES_RETRY_MAX_DELAY", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-// getCodec initializes protobuf codec with cosmos crypto interfaces.
-func getCodec() codec.Codec {
-	// Create a new interface registry
-	interfaceRegistry := types.NewInterfaceRegistry()
+	appConfig.RunTimeout, err = getenvDuration("RUN_TIMEOUT", 0)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create a new ProtoCodec
-	marshaler := codec.NewProtoCodec(interfaceRegistry)
+	appConfig.HookTimeout, err = getenvDuration("HOOK_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
 
-	// Register crypto interfaces
-	cryptocodec.RegisterInterfaces(interfaceRegistry)
+	appConfig.MaxKeys, err = getenvInt("MAX_KEYS", 10000)
+	if err != nil {
+		return nil, err
+	}
 
-	return marshaler
-}
+	appConfig.HealthStuckAfter, err = getenvDuration("HEALTH_STUCK_AFTER", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
 
-// configureSdk sets Cosmos bech32 prefixes (account, validator, consensus).
-func configureSdk(appConfig *AppConfig) {
-	log.Debug().Msg("Configuring Cosmos SDK")
+	appConfig.APIWaitTimeout, err = getenvDuration("API_WAIT_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
 
-	// Get address prefix from env var, default to "pokt"
-	prefix := appConfig.AddressPrefix
+	appConfig.RunLockTimeout, err = getenvDuration("RUN_LOCK_TIMEOUT", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Info().Str("address_prefix", prefix).Msg("Setting Cosmos SDK address prefix")
+	appConfig.ShutdownTimeout, err = getenvDuration("SHUTDOWN_TIMEOUT", 25*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-	// Configure SDK to use the specified prefix
-	config := sdk.GetConfig()
-	config.SetBech32PrefixForAccount(prefix, prefix+"pub")
-	config.SetBech32PrefixForValidator(prefix+"valoper", prefix+"valoperpub")
-	config.SetBech32PrefixForConsensusNode(prefix+"valcons", prefix+"valconspub")
-	config.Seal()
+	appConfig.LeaderElectionLeaseDuration, err = getenvDuration("LEADER_ELECTION_LEASE_DURATION", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug().Msg("Cosmos SDK configuration completed")
-}
+	appConfig.LeaderElectionRenewDeadline, err = getenvDuration("LEADER_ELECTION_RENEW_DEADLINE", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-// derivePrivateKeyFromMnemonic derives a secp256k1 key from a mnemonic and index.
-func derivePrivateKeyFromMnemonic(mnemonic string, index uint32) (*secp256k1.PrivKey, error) {
-	// Convert mnemonic to seed
-	seed := bip39.NewSeed(mnemonic, "") // Empty password for seed generation
+	appConfig.LeaderElectionRetryPeriod, err = getenvDuration("LEADER_ELECTION_RETRY_PERIOD", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-	// Define the HD path. For the Cosmos, it's typically "m/44'/118'/0'/0/index"
-	hdPath := hd.NewFundraiserParams(0, sdk.CoinType, index).String()
+	appConfig.BackendPreflightTimeout, err = getenvDuration("BACKEND_PREFLIGHT_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-	// Derive the private key using the seed and path
-	masterPriv, ch := hd.ComputeMastersFromSeed(seed)
-	derivedPriv, err := hd.DerivePrivateKeyForPath(masterPriv, ch, hdPath)
+	appConfig.RelayMinerConfigBackups, err = getenvInt("RELAYMINER_CONFIG_BACKUPS", 0)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a new private key from the derived bytes
-	privKey := &secp256k1.PrivKey{Key: derivedPriv}
+	appConfig.KeyringDirMode, err = getenvFileMode("KEYRING_DIR_MODE", 0700)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.Umask, err = getenvFileMode("UMASK", 0077)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.KeyringMinFreeMB, err = getenvInt("KEYRING_MIN_FREE_MB", 10)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.RelayMinerConfigFileMode, err = getenvFileMode("RELAYMINER_CONFIG_FILE_MODE", 0)
+	if err != nil {
+		return nil, err
+	}
 
-	return privKey, nil
-}
+	appConfig.OutputUid, err = getenvInt("OUTPUT_UID", -1)
+	if err != nil {
+		return nil, err
+	}
 
-// newKeyring initializes and returns a keyring instance based on environment variables and a codec.
-func newKeyring(appConfig *AppConfig) (keyring.Keyring, error) {
-	log.Debug().Msg("Initializing keyring")
+	appConfig.OutputGid, err = getenvInt("OUTPUT_GID", -1)
+	if err != nil {
+		return nil, err
+	}
 
-	// Get the codec
-	cdc := getCodec()
+	return appConfig, nil
+}
 
-	log.Info().
-		Str("app_name", appConfig.KeyringAppName).
-		Str("backend", appConfig.KeyringBackend).
-		Str("dir", appConfig.KeyringDir).
-		Msg("Creating new keyring")
+// validateConfig ensures that the provided AppConfig has valid settings for a keyring backend and configuration source.
+// Returns an error if the keyring backend or config source is invalid.
+func validateConfig(appConfig *AppConfig) error {
+	log.Debug().Msg("Validating application configuration")
 
-	// Initialize Cosmos SDK keyring
-	kr, err := keyring.New(
-		appConfig.KeyringAppName,
+	// TBD(@jorgecuesta) should we validate the k8s resources or files here or leave it to fail on the read?
+	if appConfig.KeyringBackend != "test" &&
+		appConfig.KeyringBackend != "pass" &&
+		appConfig.KeyringBackend != "os" {
+		log.Error().Str("backend", appConfig.KeyringBackend).Msg("Unsupported keyring backend")
+		return fmt.Errorf("unsupported keyring backend: %s", appConfig.KeyringBackend)
+	}
+
+	if appConfig.ReportSigningKeyFile != "" && appConfig.ReportSigningKeyName != "" {
+		log.Error().Msg("Both a report signing key file and a report signing key name are set")
+		return fmt.Errorf("REPORT_SIGNING_KEY_FILE and REPORT_SIGNING_KEY_NAME are mutually exclusive")
+	}
+
+	if _, err := parseAgeRecipients(appConfig.ExportAgeRecipients); err != nil {
+		log.Error().Err(err).Msg("Invalid export recipients")
+		return err
+	}
+
+	if err := validateAddressLists(appConfig); err != nil {
+		log.Error().Err(err).Msg("Invalid address lists")
+		return err
+	}
+
+	if err := checkFIPS(appConfig); err != nil {
+		log.Error().Err(err).Msg("FIPS mode requirements not met")
+		return err
+	}
+
+	if _, err := renderOutputPath(appConfig.RelayMinerConfigFileOutputPath, nil); err != nil {
+		log.Error().Err(err).Str("path", appConfig.RelayMinerConfigFileOutputPath).Msg("Invalid output path template")
+		return fmt.Errorf("invalid RELAYMINER_CONFIG_FILE_OUTPUT_PATH: %w", err)
+	}
+
+	if appConfig.RunMode != OnceRunMode &&
+		appConfig.RunMode != WatchRunMode &&
+		appConfig.RunMode != DaemonRunMode &&
+		appConfig.RunMode != OperatorRunMode &&
+		appConfig.RunMode != WebhookRunMode &&
+		appConfig.RunMode != HoldRunMode {
+		log.Error().Str("mode", appConfig.RunMode).Msg("Invalid run mode")
+		return fmt.Errorf("invalid run mode: %s", appConfig.RunMode)
+	}
+
+	if appConfig.ResyncInterval < 0 || (appConfig.RunMode == DaemonRunMode && appConfig.ResyncInterval == 0) {
+		log.Error().Dur("resync_interval", appConfig.ResyncInterval).Msg("Invalid resync interval")
+		return fmt.Errorf("invalid resync interval: %s (daemon mode requires a positive RESYNC_INTERVAL)", appConfig.ResyncInterval)
+	}
+
+	if appConfig.RunMode == WebhookRunMode && appConfig.WebhookLoaderImage == "" {
+		log.Error().Msg("Loader image is required in webhook mode")
+		return fmt.Errorf("WEBHOOK_LOADER_IMAGE is required when RUN_MODE is %s", WebhookRunMode)
+	}
+
+	if appConfig.LeaderElection {
+		if appConfig.RunMode == OnceRunMode || appConfig.RunMode == WebhookRunMode || appConfig.RunMode == HoldRunMode {
+			log.Error().Str("mode", appConfig.RunMode).Msg("Leader election is not supported in this run mode")
+			return fmt.Errorf("leader election requires RUN_MODE %s, %s or %s", WatchRunMode, DaemonRunMode, OperatorRunMode)
+		}
+		if appConfig.LeaderElectionRenewDeadline >= appConfig.LeaderElectionLeaseDuration ||
+			appConfig.LeaderElectionRetryPeriod <= 0 {
+			log.Error().
+				Dur("lease_duration", appConfig.LeaderElectionLeaseDuration).
+				Dur("renew_deadline", appConfig.LeaderElectionRenewDeadline).
+				Dur("retry_period", appConfig.LeaderElectionRetryPeriod).
+				Msg("Invalid leader election timings")
+			return fmt.Errorf("invalid leader election timings: renew deadline must be shorter than the lease duration")
+		}
+	}
+
+	if appConfig.ConfigSource != KubernetesSource && appConfig.ConfigSource != FileSource {
+		log.Error().Str("source", appConfig.ConfigSource).Msg("Invalid config source")
+		return fmt.Errorf("invalid config source: %s", appConfig.ConfigSource)
+	}
+
+	if appConfig.KeysSecretAnnotation != "" && isMultiNamespace(appConfig.KeysNamespace) {
+		log.Error().Str("namespaces", appConfig.KeysNamespace).Msg("The keys Secret annotation requires a single keys namespace")
+		return fmt.Errorf("KEYS_SECRET_ANNOTATION requires a single KEYS_NAMESPACE")
+	}
+
+	if tenantsEnabled(appConfig) && appConfig.RunMode != OnceRunMode {
+		log.Error().Str("mode", appConfig.RunMode).Msg("A tenants manifest requires the once run mode")
+		return fmt.Errorf("a tenants manifest requires RUN_MODE=%s", OnceRunMode)
+	}
+
+	if appConfig.FailMode != FailModeAbort && appConfig.FailMode != FailModeContinue {
+		log.Error().Str("mode", appConfig.FailMode).Msg("Invalid fail mode")
+		return fmt.Errorf("invalid fail mode: %s", appConfig.FailMode)
+	}
+
+	if appConfig.KubernetesRetryAttempts < 1 {
+		log.Error().Int("attempts", appConfig.KubernetesRetryAttempts).Msg("Invalid Kubernetes retry attempts")
+		return fmt.Errorf("KUBERNETES_RETRY_ATTEMPTS must be at least 1")
+	}
+
+	if appConfig.RunLock != RunLockNone && appConfig.RunLock != RunLockFile && appConfig.RunLock != RunLockLease {
+		log.Error().Str("mode", appConfig.RunLock).Msg("Invalid run lock mode")
+		return fmt.Errorf("invalid run lock mode: %s", appConfig.RunLock)
+	}
+
+	if appConfig.NotifyFormat != NotifyFormatJSON && appConfig.NotifyFormat != NotifyFormatSlack {
+		log.Error().Str("format", appConfig.NotifyFormat).Msg("Invalid notification format")
+		return fmt.Errorf("invalid notification format: %s", appConfig.NotifyFormat)
+	}
+
+	if appConfig.NotifyOn != NotifyOnAlways && appConfig.NotifyOn != NotifyOnFailure {
+		log.Error().Str("on", appConfig.NotifyOn).Msg("Invalid notification filter")
+		return fmt.Errorf("invalid NOTIFY_ON: %s", appConfig.NotifyOn)
+	}
+
+	// profiles expose the process internals, they are only served to port-forwards and the pod itself
+	if appConfig.PprofListenAddress != "" {
+		if err := checkLoopbackAddress(appConfig.PprofListenAddress); err != nil {
+			log.Error().Str("address", appConfig.PprofListenAddress).Msg("Invalid pprof listen address")
+			return fmt.Errorf("invalid PPROF_LISTEN_ADDRESS: %w", err)
+		}
+	}
+
+	if appConfig.EventTarget != EventTargetPod && appConfig.EventTarget != EventTargetOutput {
+		log.Error().Str("target", appConfig.EventTarget).Msg("Invalid event target")
+		return fmt.Errorf("invalid event target: %s", appConfig.EventTarget)
+	}
+
+	if appConfig.EmptySupplierMode != EmptySupplierWarn && appConfig.EmptySupplierMode != EmptySupplierFail {
+		log.Error().Str("mode", appConfig.EmptySupplierMode).Msg("Invalid empty supplier mode")
+		return fmt.Errorf("invalid empty supplier mode: %s", appConfig.EmptySupplierMode)
+	}
+
+	if appConfig.PermissionsCheck != PermissionsFail && appConfig.PermissionsCheck != PermissionsFix && appConfig.PermissionsCheck != PermissionsOff {
+		log.Error().Str("mode", appConfig.PermissionsCheck).Msg("Invalid permissions check")
+		return fmt.Errorf("invalid permissions check: %s", appConfig.PermissionsCheck)
+	}
+
+	if appConfig.KeyExpiryMode != KeyExpiryWarn && appConfig.KeyExpiryMode != KeyExpiryFail {
+		log.Error().Str("mode", appConfig.KeyExpiryMode).Msg("Invalid key expiry mode")
+		return fmt.Errorf("invalid key expiry mode: %s", appConfig.KeyExpiryMode)
+	}
+
+	if appConfig.AccountCheck != AccountCheckOff && appConfig.AccountCheck != AccountCheckWarn && appConfig.AccountCheck != AccountCheckFail {
+		log.Error().Str("mode", appConfig.AccountCheck).Msg("Invalid account check mode")
+		return fmt.Errorf("invalid account check mode: %s", appConfig.AccountCheck)
+	}
+	if appConfig.ChainGRPCUrl != "" {
+		_, secure, err := chainGRPCTarget(appConfig.ChainGRPCUrl)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid chain gRPC url")
+			return err
+		}
+		if appConfig.ChainGRPCCAFile != "" && !secure {
+			log.Error().Str("ca_file", appConfig.ChainGRPCCAFile).Msg("Chain gRPC CA file set for a plaintext chain gRPC url")
+			return fmt.Errorf("CHAIN_GRPC_CA_FILE requires an https:// CHAIN_GRPC_URL")
+		}
+	}
+	if appConfig.ChainRPCUrl != "" {
+		if parsed, err := url.Parse(appConfig.ChainRPCUrl); err != nil || parsed.Host == "" {
+			log.Error().Str("url", redactSetting("CHAIN_RPC_URL", appConfig.ChainRPCUrl)).Msg("Invalid chain RPC url")
+			return fmt.Errorf("invalid chain RPC url: %s", redactSetting("CHAIN_RPC_URL", appConfig.ChainRPCUrl))
+		}
+	}
+	if appConfig.ChainRESTUrl != "" {
+		if parsed, err := url.Parse(appConfig.ChainRESTUrl); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			log.Error().Str("url", redactSetting("CHAIN_REST_URL", appConfig.ChainRESTUrl)).Msg("Invalid chain REST url")
+			return fmt.Errorf("invalid chain REST url: %s", redactSetting("CHAIN_REST_URL", appConfig.ChainRESTUrl))
+		}
+		if appConfig.ChainGRPCUrl == "" {
+			log.Error().Msg("The chain REST url is a fallback of the chain gRPC url")
+			return fmt.Errorf("CHAIN_REST_URL requires CHAIN_GRPC_URL")
+		}
+	}
+	if appConfig.ChainRetryAttempts < 1 {
+		log.Error().Int("attempts", appConfig.ChainRetryAttempts).Msg("Invalid chain retry attempts")
+		return fmt.Errorf("CHAIN_RETRY_ATTEMPTS must be at least 1")
+	}
+	if appConfig.AccountCheck != AccountCheckOff && appConfig.ChainGRPCUrl == "" {
+		log.Error().Str("mode", appConfig.AccountCheck).Msg("The account check requires a chain gRPC url")
+		return fmt.Errorf("ACCOUNT_CHECK=%s requires CHAIN_GRPC_URL", appConfig.AccountCheck)
+	}
+
+	if appConfig.ServiceCheck != ServiceCheckOff && appConfig.ServiceCheck != ServiceCheckWarn && appConfig.ServiceCheck != ServiceCheckFail {
+		log.Error().Str("mode", appConfig.ServiceCheck).Msg("Invalid service check mode")
+		return fmt.Errorf("invalid service check mode: %s", appConfig.ServiceCheck)
+	}
+	if appConfig.ServiceCheck != ServiceCheckOff && appConfig.ChainGRPCUrl == "" {
+		log.Error().Str("mode", appConfig.ServiceCheck).Msg("The service check requires a chain gRPC url")
+		return fmt.Errorf("SERVICE_CHECK=%s requires CHAIN_GRPC_URL", appConfig.ServiceCheck)
+	}
+
+	if appConfig.BalanceCheck != BalanceCheckOff && appConfig.BalanceCheck != BalanceCheckWarn && appConfig.BalanceCheck != BalanceCheckFail {
+		log.Error().Str("mode", appConfig.BalanceCheck).Msg("Invalid balance check mode")
+		return fmt.Errorf("invalid balance check mode: %s", appConfig.BalanceCheck)
+	}
+	if _, err := sdk.ParseCoinNormalized(appConfig.MinBalance); err != nil {
+		log.Error().Err(err).Str("min_balance", appConfig.MinBalance).Msg("Invalid minimum balance")
+		return fmt.Errorf("invalid minimum balance %q: %w", appConfig.MinBalance, err)
+	}
+	if appConfig.BalanceCheck != BalanceCheckOff && appConfig.ChainGRPCUrl == "" {
+		log.Error().Str("mode", appConfig.BalanceCheck).Msg("The balance check requires a chain gRPC url")
+		return fmt.Errorf("BALANCE_CHECK=%s requires CHAIN_GRPC_URL", appConfig.BalanceCheck)
+	}
+
+	if appConfig.StakeCheck != StakeCheckOff && appConfig.StakeCheck != StakeCheckWarn && appConfig.StakeCheck != StakeCheckFail {
+		log.Error().Str("mode", appConfig.StakeCheck).Msg("Invalid stake check mode")
+		return fmt.Errorf("invalid stake check mode: %s", appConfig.StakeCheck)
+	}
+	if appConfig.StakeCheck != StakeCheckOff && appConfig.ChainGRPCUrl == "" {
+		log.Error().Str("mode", appConfig.StakeCheck).Msg("The stake check requires a chain gRPC url")
+		return fmt.Errorf("STAKE_CHECK=%s requires CHAIN_GRPC_URL", appConfig.StakeCheck)
+	}
+
+	if appConfig.BackendPreflight && appConfig.BackendPreflightTimeout <= 0 {
+		log.Error().Dur("timeout", appConfig.BackendPreflightTimeout).Msg("Invalid backend preflight timeout")
+		return fmt.Errorf("invalid backend preflight timeout: %s", appConfig.BackendPreflightTimeout)
+	}
+
+	if appConfig.RelayMinerConfigOutputTarget != FileSource &&
+		appConfig.RelayMinerConfigOutputTarget != ConfigMapSource &&
+		appConfig.RelayMinerConfigOutputTarget != SecretSource {
+		log.Error().Str("target", appConfig.RelayMinerConfigOutputTarget).Msg("Invalid relay miner output target")
+		return fmt.Errorf("invalid relay miner output target: %s", appConfig.RelayMinerConfigOutputTarget)
+	}
+
+	for _, target := range appConfig.RolloutTargets {
+		if _, _, err := parseRolloutTarget(target); err != nil {
+			log.Error().Err(err).Str("target", target).Msg("Invalid rollout target")
+			return err
+		}
+	}
+
+	if appConfig.SupplierStakeConfigOutputDir != "" && appConfig.SupplierStakeAmount == "" {
+		log.Error().Msg("Supplier stake amount is required to write supplier stake configs")
+		return fmt.Errorf("SUPPLIER_STAKE_AMOUNT is required when SUPPLIER_STAKE_CONFIG_OUTPUT_DIR is set")
+	}
+
+	if appConfig.DelegationCheck != DelegationCheckOff && appConfig.DelegationCheck != DelegationCheckWarn && appConfig.DelegationCheck != DelegationCheckFail {
+		log.Error().Str("mode", appConfig.DelegationCheck).Msg("Invalid delegation check mode")
+		return fmt.Errorf("invalid delegation check mode: %s", appConfig.DelegationCheck)
+	}
+	if appConfig.DelegationCheck != DelegationCheckOff && appConfig.ChainGRPCUrl == "" {
+		log.Error().Str("mode", appConfig.DelegationCheck).Msg("The delegation check requires a chain gRPC url")
+		return fmt.Errorf("DELEGATION_CHECK=%s requires CHAIN_GRPC_URL", appConfig.DelegationCheck)
+	}
+
+	if appConfig.ParamsCheck != ParamsCheckOff && appConfig.ParamsCheck != ParamsCheckWarn && appConfig.ParamsCheck != ParamsCheckFail {
+		log.Error().Str("mode", appConfig.ParamsCheck).Msg("Invalid params check mode")
+		return fmt.Errorf("invalid params check mode: %s", appConfig.ParamsCheck)
+	}
+	if appConfig.ParamsCheck != ParamsCheckOff && appConfig.ChainGRPCUrl == "" {
+		log.Error().Str("mode", appConfig.ParamsCheck).Msg("The params check requires a chain gRPC url")
+		return fmt.Errorf("PARAMS_CHECK=%s requires CHAIN_GRPC_URL", appConfig.ParamsCheck)
+	}
+
+	if appConfig.SupplierAutoStake {
+		switch {
+		case appConfig.SupplierStakeAmount == "":
+			log.Error().Msg("Supplier stake amount is required to stake suppliers")
+			return fmt.Errorf("SUPPLIER_STAKE_AMOUNT is required when SUPPLIER_AUTO_STAKE is set")
+		case appConfig.ChainGRPCUrl == "" || appConfig.ChainID == "":
+			log.Error().Msg("Staking suppliers requires a chain gRPC url and chain id")
+			return fmt.Errorf("CHAIN_GRPC_URL and CHAIN_ID are required when SUPPLIER_AUTO_STAKE is set")
+		}
+	}
+	if err := validateOnchainSuppliers(appConfig); err != nil {
+		log.Error().Err(err).Str("mode", appConfig.OnchainSuppliers).Msg("Invalid on-chain suppliers sync")
+		return err
+	}
+	if appConfig.ApplicationAutoDelegate && (appConfig.ChainGRPCUrl == "" || appConfig.ChainID == "") {
+		log.Error().Msg("Delegating applications requires a chain gRPC url and chain id")
+		return fmt.Errorf("CHAIN_GRPC_URL and CHAIN_ID are required when APPLICATION_AUTO_DELEGATE is set")
+	}
+	if err := checkAddressPrefix(appConfig); err != nil {
+		log.Warn().Err(err).Str("chain_id", appConfig.ChainID).Msg("Address prefix doesn't match the chain, the derived addresses won't be usable on it")
+	}
+	if appConfig.UnsignedTxOutputDir != "" && !appConfig.SupplierAutoStake && !appConfig.ApplicationAutoDelegate {
+		log.Error().Msg("Unsigned transactions require a transaction-emitting feature")
+		return fmt.Errorf("UNSIGNED_TX_OUTPUT_DIR requires SUPPLIER_AUTO_STAKE or APPLICATION_AUTO_DELEGATE")
+	}
+	if appConfig.FaucetURL != "" {
+		if err := validateFaucet(appConfig); err != nil {
+			log.Error().Err(err).Str("chain_id", appConfig.ChainID).Msg("Invalid faucet")
+			return err
+		}
+		if appConfig.FaucetMaxRequests < 1 {
+			log.Error().Int("max_requests", appConfig.FaucetMaxRequests).Msg("Invalid faucet max requests")
+			return fmt.Errorf("invalid faucet max requests: %d", appConfig.FaucetMaxRequests)
+		}
+	}
+	if appConfig.TxGasLimit < 0 {
+		log.Error().Int("gas_limit", appConfig.TxGasLimit).Msg("Invalid transaction gas limit")
+		return fmt.Errorf("invalid transaction gas limit: %d", appConfig.TxGasLimit)
+	}
+	if appConfig.TxGasAdjustment <= 0 {
+		log.Error().Float64("gas_adjustment", appConfig.TxGasAdjustment).Msg("Invalid transaction gas adjustment")
+		return fmt.Errorf("invalid transaction gas adjustment: %g", appConfig.TxGasAdjustment)
+	}
+	if _, err := sdk.ParseDecCoins(appConfig.TxGasPrices); err != nil {
+		log.Error().Err(err).Str("gas_prices", appConfig.TxGasPrices).Msg("Invalid transaction gas prices")
+		return fmt.Errorf("invalid transaction gas prices %q: %w", appConfig.TxGasPrices, err)
+	}
+	if _, err := sdk.ParseCoinsNormalized(appConfig.TxFees); err != nil {
+		log.Error().Err(err).Str("fees", appConfig.TxFees).Msg("Invalid transaction fees")
+		return fmt.Errorf("invalid transaction fees %q: %w", appConfig.TxFees, err)
+	}
+	if appConfig.TxBroadcastMode != TxBroadcastSync && appConfig.TxBroadcastMode != TxBroadcastAsync && appConfig.TxBroadcastMode != TxBroadcastBlock {
+		log.Error().Str("mode", appConfig.TxBroadcastMode).Msg("Invalid transaction broadcast mode")
+		return fmt.Errorf("invalid transaction broadcast mode: %s", appConfig.TxBroadcastMode)
+	}
+	if appConfig.TxPollInterval <= 0 {
+		log.Error().Dur("poll_interval", appConfig.TxPollInterval).Msg("Invalid transaction poll interval")
+		return fmt.Errorf("invalid transaction poll interval: %s", appConfig.TxPollInterval)
+	}
+
+	if appConfig.RelayMinerOutputFormat != YAMLOutputFormat && appConfig.RelayMinerOutputFormat != JSONOutputFormat {
+		log.Error().Str("format", appConfig.RelayMinerOutputFormat).Msg("Invalid relay miner output format")
+		return fmt.Errorf("invalid relay miner output format: %s", appConfig.RelayMinerOutputFormat)
+	}
+
+	if appConfig.RelayMinerConfigBackups < 0 {
+		log.Error().Int("backups", appConfig.RelayMinerConfigBackups).Msg("Invalid relay miner config backups")
+		return fmt.Errorf("invalid relay miner config backups: %d", appConfig.RelayMinerConfigBackups)
+	}
+
+	if !filepath.IsAbs(appConfig.KeyringDir) {
+		absPath, err := filepath.Abs(appConfig.KeyringDir)
+		if err != nil {
+			return fmt.Errorf("failed to convert to absolute path: %w", err)
+		}
+		appConfig.KeyringDir = absPath
+	}
+
+	log.Debug().Msg("Configuration validation successful")
+	return nil
+}
+
+// getCodec initializes protobuf codec with cosmos crypto interfaces.
+func getCodec() codec.Codec {
+	// Create a new interface registry
+	interfaceRegistry := types.NewInterfaceRegistry()
+
+	// Create a new ProtoCodec
+	marshaler := codec.NewProtoCodec(interfaceRegistry)
+
+	// Register crypto interfaces
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+
+	return marshaler
+}
+
+// configureSdk sets Cosmos bech32 prefixes (account, validator, consensus).
+func configureSdk(appConfig *AppConfig) {
+	log.Debug().Msg("Configuring Cosmos SDK")
+
+	// Get address prefix from env var, default to "pokt"
+	prefix := appConfig.AddressPrefix
+
+	log.Info().Str("address_prefix", prefix).Msg("Setting Cosmos SDK address prefix")
+
+	// Configure SDK to use the specified prefix
+	config := sdk.GetConfig()
+	config.SetBech32PrefixForAccount(prefix, prefix+"pub")
+	config.SetBech32PrefixForValidator(prefix+"valoper", prefix+"valoperpub")
+	config.SetBech32PrefixForConsensusNode(prefix+"valcons", prefix+"valconspub")
+	config.Seal()
+
+	log.Debug().Msg("Cosmos SDK configuration completed")
+}
+
+// derivePrivateKeyFromMnemonic derives a secp256k1 key from a mnemonic and index.
+func derivePrivateKeyFromMnemonic(mnemonic string, index uint32) (*secp256k1.PrivKey, error) {
+	// Convert mnemonic to seed
+	seed := bip39.NewSeed(mnemonic, "") // Empty password for seed generation
+
+	// Define the HD path. For the Cosmos, it's typically "m/44'/118'/0'/0/index"
+	hdPath := hd.NewFundraiserParams(0, sdk.CoinType, index).String()
+
+	// Derive the private key using the seed and path
+	masterPriv, ch := hd.ComputeMastersFromSeed(seed)
+	derivedPriv, err := hd.DerivePrivateKeyForPath(masterPriv, ch, hdPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new private key from the derived bytes
+	privKey := &secp256k1.PrivKey{Key: derivedPriv}
+
+	return privKey, nil
+}
+
+// newKeyring initializes and returns a keyring instance based on environment variables and a codec.
+func newKeyring(appConfig *AppConfig) (keyring.Keyring, error) {
+	log.Debug().Msg("Initializing keyring")
+
+	// Get the codec
+	cdc := getCodec()
+
+	log.Info().
+		Str("app_name", appConfig.KeyringAppName).
+		Str("backend", appConfig.KeyringBackend).
+		Str("dir", appConfig.KeyringDir).
+		Msg("Creating new keyring")
+
+	// Initialize Cosmos SDK keyring
+	kr, err := keyring.New(
+		appConfig.KeyringAppName,
 		appConfig.KeyringBackend,
 		appConfig.KeyringDir,
 		nil,
@@ -251,7 +1363,7 @@ func newKeyring(appConfig *AppConfig) (keyring.Keyring, error) {
 	)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialize keyring")
-		return nil, fmt.Errorf("error initializing keyring: %w", err)
+		return nil, withExitCode(ExitKeyringError, fmt.Errorf("error initializing keyring: %w", err))
 	}
 
 	log.Debug().Msg("Keyring initialized successfully")
@@ -263,7 +1375,7 @@ func importSecp256k1PrivateKey(kr keyring.Keyring, privKey *secp256k1.PrivKey) (
 	address := sdk.AccAddress(privKey.PubKey().Address())
 	name := address.String()
 
-	log.Debug().Str("address", address.String()).Msg("Attempting to import private key")
+	keyLog.Debug().Str("address", address.String()).Msg("Attempting to import private key")
 
 	if acc, err := kr.KeyByAddress(address); err == nil {
 		if acc.Name != name {
@@ -272,7 +1384,7 @@ func importSecp256k1PrivateKey(kr keyring.Keyring, privKey *secp256k1.PrivKey) (
 				Str("calculated_name", name).
 				Msg("Key already exists with a different name")
 		} else {
-			log.Debug().Str("name", name).Msg("Key already exists in keyring")
+			keyLog.Debug().Str("name", name).Msg("Key already exists in keyring")
 		}
 		// respect the name of the key if it's different from the address,
 		// who knows why the user set it
@@ -281,19 +1393,19 @@ func importSecp256k1PrivateKey(kr keyring.Keyring, privKey *secp256k1.PrivKey) (
 	} else if !strings.Contains(err.Error(), "not found") {
 		// not found is ok - anything else is not
 		log.Error().Err(err).Str("address", address.String()).Msg("Error checking key existence")
-		return "", err
+		return "", withExitCode(ExitKeyringError, err)
 	}
 
-	log.Debug().Str("name", name).Msg("Key not found in keyring, importing")
+	keyLog.Debug().Str("name", name).Msg("Key not found in keyring, importing")
 
 	// the address isn't found, so let's import it
 	err := kr.ImportPrivKeyHex(name, hex.EncodeToString(privKey.Key), "secp256k1")
 	if err != nil {
 		log.Error().Err(err).Str("name", name).Msg("Failed to import private key")
-		return "", err
+		return "", withExitCode(ExitKeyringError, err)
 	}
 
-	log.Info().Str("name", name).Msg("Successfully imported key")
+	keyLog.Info().Str("name", name).Msg("Successfully imported key")
 	return name, nil
 }
 
@@ -330,16 +1442,9 @@ func loadConfigData(appConfig *AppConfig, source, namespace, name, key, configPa
 	switch appConfig.ConfigSource {
 	case KubernetesSource:
 		// Initialize Kubernetes client
-		config, err := rest.InClusterConfig()
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to create in-cluster config")
-			return nil, fmt.Errorf("error creating in-cluster config: %w", err)
-		}
-
-		clientset, err := kubernetes.NewForConfig(config)
+		clientset, err := newKubernetesClient()
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to create Kubernetes clientset")
-			return nil, fmt.Errorf("error creating Kubernetes clientset: %w", err)
+			return nil, err
 		}
 
 		// Fetch the file from Kubernetes
@@ -352,15 +1457,19 @@ func loadConfigData(appConfig *AppConfig, source, namespace, name, key, configPa
 				Str("key", key).
 				Msg("Loading from ConfigMap")
 
-			configmap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, v1.GetOptions{})
+			var configmap *corev1.ConfigMap
+			err := retryKubernetes(appConfig, "get configmap", func() (err error) {
+				configmap, err = clientset.CoreV1().ConfigMaps(namespace).Get(appConfig.runContext(), name, v1.GetOptions{})
+				return err
+			})
 			if err != nil {
 				log.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to fetch ConfigMap")
-				return nil, fmt.Errorf("error fetching configmap '%s' in namespace '%s': %w", name, namespace, err)
+				return nil, withExitCode(ExitSourceError, fmt.Errorf("error fetching configmap '%s' in namespace '%s': %w", name, namespace, err))
 			}
 			_data, ok := configmap.Data[key]
 			if !ok {
 				log.Error().Str("name", name).Str("key", key).Msg("ConfigMap does not contain key")
-				return nil, fmt.Errorf("error: ConfigMap '%s' does not contain key '%s'", name, key)
+				return nil, withExitCode(ExitValidationError, fmt.Errorf("error: ConfigMap '%s' does not contain key '%s'", name, key))
 			}
 
 			data = []byte(_data)
@@ -372,17 +1481,21 @@ func loadConfigData(appConfig *AppConfig, source, namespace, name, key, configPa
 				Str("key", key).
 				Msg("Loading from Secret")
 
-			secret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), name, v1.GetOptions{})
+			var secret *corev1.Secret
+			err := retryKubernetes(appConfig, "get secret", func() (err error) {
+				secret, err = clientset.CoreV1().Secrets(namespace).Get(appConfig.runContext(), name, v1.GetOptions{})
+				return err
+			})
 			if err != nil {
 				log.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to fetch Secret")
-				return nil, fmt.Errorf("error fetching secret '%s' in namespace '%s': %w", name, namespace, err)
+				return nil, withExitCode(ExitSourceError, fmt.Errorf("error fetching secret '%s' in namespace '%s': %w", name, namespace, err))
 			}
 
 			// Extract JSON data from the secret
 			_data, ok := secret.Data[key]
 			if !ok {
 				log.Error().Str("name", name).Str("key", key).Msg("Secret does not contain key")
-				return nil, fmt.Errorf("error: Secret '%s' does not contain key '%s'", name, key)
+				return nil, withExitCode(ExitValidationError, fmt.Errorf("error: Secret '%s' does not contain key '%s'", name, key))
 			}
 
 			data = _data
@@ -398,10 +1511,10 @@ func loadConfigData(appConfig *AppConfig, source, namespace, name, key, configPa
 		data, err := readFile(configPath)
 		if err != nil {
 			log.Error().Err(err).Str("path", configPath).Msg("Failed to read file")
-		} else {
-			log.Debug().Msg("File data loaded successfully")
+			return nil, withExitCode(ExitSourceError, err)
 		}
-		return data, err
+		log.Debug().Msg("File data loaded successfully")
+		return data, nil
 	default:
 		log.Error().Str("source", appConfig.ConfigSource).Msg("Unsupported configuration source")
 		return nil, fmt.Errorf("unsupported configuration source: %s", appConfig.ConfigSource)
@@ -413,25 +1526,34 @@ func loadConfigData(appConfig *AppConfig, source, namespace, name, key, configPa
 func loadWalletKeys(appConfig *AppConfig) ([]WalletKeySpec, error) {
 	keys := make([]WalletKeySpec, 0)
 
-	// Extract JSON file from the secret
-	jsonData, err := loadConfigData(
-		appConfig,
-		SecretSource,
-		appConfig.KeysNamespace,
-		appConfig.KeysSecretName,
-		appConfig.KeysSecretKey,
-		appConfig.KeysFilePath,
-	)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to load wallet keys configuration")
-		return keys, fmt.Errorf("error loading configuration: %w", err)
+	// A central instance may gather the keys Secrets of several namespaces
+	if len(appConfig.KeysData) == 0 && appConfig.ConfigSource == KubernetesSource && isMultiNamespace(appConfig.KeysNamespace) {
+		return loadWalletKeysFromNamespaces(appConfig)
+	}
+
+	// Extract JSON file from the secret, unless the interactive command provided it
+	jsonData := appConfig.KeysData
+	if len(jsonData) == 0 {
+		var err error
+		jsonData, err = loadConfigData(
+			appConfig,
+			SecretSource,
+			appConfig.KeysNamespace,
+			appConfig.KeysSecretName,
+			appConfig.KeysSecretKey,
+			appConfig.KeysFilePath,
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load wallet keys configuration")
+			return keys, fmt.Errorf("error loading configuration: %w", err)
+		}
 	}
 
-	// Parse JSON data
+	// Parse JSON data (YAML is accepted too, so keys files may carry comments)
 	log.Debug().Int("data_size", len(jsonData)).Msg("Parsing wallet keys JSON data")
-	if err := json.Unmarshal(jsonData, &keys); err != nil {
+	if err := k8syaml.Unmarshal(jsonData, &keys); err != nil {
 		log.Error().Err(err).Msg("Failed to parse wallet keys JSON data")
-		return keys, fmt.Errorf("error parsing JSON data from secret: %w", err)
+		return keys, withExitCode(ExitValidationError, fmt.Errorf("error parsing JSON data from secret: %w", err))
 	}
 
 	log.Info().Int("key_count", len(keys)).Msg("Wallet keys loaded successfully")
@@ -450,25 +1572,40 @@ func loadRelayMinerConfig(appConfig *AppConfig) (*poktrollconfig.YAMLRelayMinerC
 		return nil, nil
 	}
 
-	// Extract a config file from the source
-	log.Debug().
-		Str("namespace", appConfig.RelayMinerConfigNamespace).
-		Str("config_name", appConfig.RelayMinerConfigName).
-		Str("config_key", appConfig.RelayMinerConfigKey).
-		Str("file_path", appConfig.RelayMinerConfigFilePath).
-		Msg("Loading relay miner configuration data")
-
-	configContent, err := loadConfigData(
-		appConfig,
-		ConfigMapSource,
-		appConfig.RelayMinerConfigNamespace,
-		appConfig.RelayMinerConfigName,
-		appConfig.RelayMinerConfigKey,
-		appConfig.RelayMinerConfigFilePath,
-	)
+	// Extract a config file from the source, unless the operator provided it
+	configContent := appConfig.RelayMinerConfigData
+	if len(configContent) == 0 {
+		log.Debug().
+			Str("namespace", appConfig.RelayMinerConfigNamespace).
+			Str("config_name", appConfig.RelayMinerConfigName).
+			Str("config_key", appConfig.RelayMinerConfigKey).
+			Str("file_path", appConfig.RelayMinerConfigFilePath).
+			Msg("Loading relay miner configuration data")
+
+		var err error
+		if appConfig.ConfigSource == KubernetesSource && isMultiNamespace(appConfig.RelayMinerConfigNamespace) {
+			configContent, err = loadRelayMinerConfigFromNamespaces(appConfig)
+		} else {
+			configContent, err = loadConfigData(
+				appConfig,
+				ConfigMapSource,
+				appConfig.RelayMinerConfigNamespace,
+				appConfig.RelayMinerConfigName,
+				appConfig.RelayMinerConfigKey,
+				appConfig.RelayMinerConfigFilePath,
+			)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load relay miner configuration")
+			return nil, fmt.Errorf("error loading configuration: %w", err)
+		}
+	}
+
+	// Convert documents written for older schema versions before unmarshalling them
+	configContent, err := upgradeRelayMinerConfig(configContent)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to load relay miner configuration")
-		return nil, fmt.Errorf("error loading configuration: %w", err)
+		log.Error().Err(err).Msg("Failed to upgrade relay miner configuration schema")
+		return nil, withExitCode(ExitValidationError, err)
 	}
 
 	// Unmarshal the config file into a yamlRelayMinerConfig
@@ -476,86 +1613,298 @@ func loadRelayMinerConfig(appConfig *AppConfig) (*poktrollconfig.YAMLRelayMinerC
 	err = yaml.Unmarshal(configContent, yamlRelayMinerConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal relay miner YAML configuration")
-		return nil, fmt.Errorf("unable to unmarshall RelayMiner config file: %w", err)
+		return nil, withExitCode(ExitValidationError, fmt.Errorf("unable to unmarshall RelayMiner config file: %w", err))
+	}
+
+	// Warn about fields the schema doesn't know, they won't make it to the generated config
+	err = warnDroppedFields(configContent, yamlRelayMinerConfig)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check relay miner configuration for unknown fields")
+		return nil, err
 	}
 
 	log.Info().Msg("Relay miner configuration loaded successfully")
 	return yamlRelayMinerConfig, nil
 }
 
+// entryImports records the keys imported into the wrapped keyring, so the keys of a failing entry can be rolled back.
+type entryImports struct {
+	keyring.Keyring
+	names []string
+}
+
+// ImportPrivKeyHex imports the key, then records it. A key written despite an error (e.g. by an audited keyring
+// failing to record it) is recorded too.
+func (k *entryImports) ImportPrivKeyHex(uid, privKey, algoStr string) error {
+	err := k.Keyring.ImportPrivKeyHex(uid, privKey, algoStr)
+	if _, keyErr := k.Keyring.Key(uid); err == nil || keyErr == nil {
+		k.names = append(k.names, uid)
+	}
+	return err
+}
+
+// rollback deletes the recorded keys from the keyring.
+func (k *entryImports) rollback() error {
+	for _, name := range k.names {
+		record, err := k.Keyring.Key(name)
+		if err != nil {
+			return fmt.Errorf("unable to read key %s to roll back: %w", name, err)
+		}
+		address, err := record.GetAddress()
+		if err != nil {
+			return fmt.Errorf("unable to read address of key %s to roll back: %w", name, err)
+		}
+		if err := k.Keyring.DeleteByAddress(address); err != nil {
+			return fmt.Errorf("unable to roll back key %s: %w", name, err)
+		}
+		keyLog.Info().Str("name", name).Msg("Rolled back key of failed entry")
+	}
+	k.names = nil
+	return nil
+}
+
 // importAndRegisterKeys imports wallet keys into the keyring and registers them in the relay miner configuration.
-func importAndRegisterKeys(appConfig *AppConfig, keys []WalletKeySpec, walletKeyring keyring.Keyring, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+// Returns a record of every imported key along with the services it was registered to.
+// In FailModeContinue failing entries are rolled back (the keys they imported, their registrations and supplier
+// overrides) and skipped, the keys of the other entries are returned along with an EntryErrors listing the failures.
+func importAndRegisterKeys(appConfig *AppConfig, keys []WalletKeySpec, walletKeyring keyring.Keyring, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]ImportedKey, error) {
 	log.Info().
 		Int("keys", len(keys)).
 		Msg("Importing and registering keys")
 
-	name := ""
+	imported := make([]ImportedKey, 0)
+	overridden := make(map[string]bool)
+	failures := make(EntryErrors, 0)
 
 	for i, entry := range keys {
-		if entry.Mnemonic != "" {
-			// Process mnemonic
-			if !bip39.IsMnemonicValid(entry.Mnemonic) {
-				return fmt.Errorf("invalid mnemonic at index: %d", i)
-			}
-
-			for j := entry.StartIndex; j <= entry.EndIndex; j++ {
-				privKey, err := derivePrivateKeyFromMnemonic(entry.Mnemonic, uint32(j))
-				if err != nil {
-					return fmt.Errorf("error deriving private key at index %d: %w", j, err)
-				}
-
-				name, err = importSecp256k1PrivateKey(walletKeyring, privKey)
-				if err != nil {
-					return fmt.Errorf("error importing derived key at index %d: %w", j, err)
-				}
+		if entry.Disabled {
+			log.Info().Int("entry", i).Msg("Entry disabled, skipping")
+			continue
+		}
 
-				if entry.ServiceID == nil || len(entry.ServiceID) == 0 {
-					err = registerRelayMinerConfig(appConfig, name, "", relayMinerConfig)
-					if err != nil {
-						return err
-					}
-				} else {
-					for _, serviceId := range entry.ServiceID {
-						err = registerRelayMinerConfig(appConfig, name, serviceId, relayMinerConfig)
-						if err != nil {
-							return err
-						}
-					}
-				}
-			}
-		} else if entry.Hex != "" {
-			// Process hex private key
-			privKeyHex := strings.TrimPrefix(entry.Hex, "0x")
-			privKeyBytes, err := hex.DecodeString(privKeyHex)
-			if err != nil {
-				return fmt.Errorf("error decoding hex key: %w", err)
+		var snapshot *poktrollconfig.YAMLRelayMinerConfig
+		var overriddenSnapshot map[string]bool
+		var imports *entryImports
+		entryKeyring := walletKeyring
+		if appConfig.FailMode == FailModeContinue {
+			var err error
+			if snapshot, err = cloneRelayMinerConfig(relayMinerConfig); err != nil {
+				return imported, err
 			}
+			overriddenSnapshot = maps.Clone(overridden)
+			imports = &entryImports{Keyring: walletKeyring}
+			entryKeyring = imports
+		}
 
-			privKey := &secp256k1.PrivKey{Key: privKeyBytes}
-			name, err = importSecp256k1PrivateKey(walletKeyring, privKey)
-			if err != nil {
-				return fmt.Errorf("error importing hex key: %w", err)
+		// entry failures are invalid content, unless tagged otherwise (e.g. keyring write errors)
+		entryKeys, err := importAndRegisterEntry(appConfig, i, entry, entryKeyring, relayMinerConfig, overridden)
+		err = withExitCode(ExitValidationError, err)
+		if err != nil {
+			if appConfig.FailMode != FailModeContinue {
+				return imported, err
 			}
 
-			if entry.ServiceID == nil || len(entry.ServiceID) == 0 {
-				err = registerRelayMinerConfig(appConfig, name, "", relayMinerConfig)
-				if err != nil {
-					return err
-				}
-			} else {
-				for _, serviceId := range entry.ServiceID {
-					err = registerRelayMinerConfig(appConfig, name, serviceId, relayMinerConfig)
-					if err != nil {
-						return err
-					}
-				}
+			log.Error().Err(err).Int("entry", i).Msg("Entry failed, continuing with the next one")
+			if err := imports.rollback(); err != nil {
+				return imported, withExitCode(ExitKeyringError, fmt.Errorf("entry %d: %w", i, err))
 			}
-		} else {
-			return fmt.Errorf("invalid entry index: %d", i)
+			if snapshot != nil {
+				*relayMinerConfig = *snapshot
+			}
+			overridden = overriddenSnapshot
+			failures = append(failures, EntryError{Index: i, Err: err})
+			continue
 		}
+		imported = append(imported, entryKeys...)
 	}
 
-	return nil
+	if len(failures) > 0 {
+		return imported, failures
+	}
+	return imported, nil
+}
+
+// importAndRegisterEntry imports the keys of a single keys.json entry and registers them in the relay miner configuration.
+func importAndRegisterEntry(appConfig *AppConfig, i int, entry WalletKeySpec, walletKeyring keyring.Keyring, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig, overridden map[string]bool) ([]ImportedKey, error) {
+	imported := make([]ImportedKey, 0)
+
+	if err := validateRole(entry); err != nil {
+		return imported, fmt.Errorf("%w index %d: %w", ErrInvalidEntry, i, err)
+	}
+
+	if entry.Mnemonic != "" {
+		// Process mnemonic
+		if !bip39.IsMnemonicValid(entry.Mnemonic) {
+			return imported, fmt.Errorf("%w at index: %d", ErrInvalidMnemonic, i)
+		}
+
+		if err := validateDistribution(entry); err != nil {
+			return imported, fmt.Errorf("%w index %d: %w", ErrInvalidEntry, i, err)
+		}
+
+		if err := checkExpectedAddresses(entry); err != nil {
+			return imported, fmt.Errorf("entry %d: %w", i, err)
+		}
+
+		for j := entry.StartIndex; j <= entry.EndIndex; j++ {
+			privKey, err := derivePrivateKeyFromMnemonic(entry.Mnemonic, uint32(j))
+			if err != nil {
+				return imported, fmt.Errorf("error deriving private key at index %d: %w", j, err)
+			}
+
+			key, err := importAndRegisterKey(appConfig, entry, privKey, distributedServiceIds(entry, j), walletKeyring, relayMinerConfig)
+			if err != nil {
+				return imported, fmt.Errorf("error importing derived key at index %d: %w", j, err)
+			}
+			key.EntryIndex = i
+			key.DerivationIndex = j
+			imported = append(imported, key)
+		}
+	} else if entry.Hex != "" {
+		if entry.Distribution != "" && entry.Distribution != DistributionAll {
+			return imported, fmt.Errorf("%w index %d: distribution %s requires a mnemonic range", ErrInvalidEntry, i, entry.Distribution)
+		}
+
+		// Process hex private key
+		privKeyHex := strings.TrimPrefix(entry.Hex, "0x")
+		privKeyBytes, err := hex.DecodeString(privKeyHex)
+		if err != nil {
+			return imported, fmt.Errorf("%w: %w", ErrInvalidHexKey, err)
+		}
+
+		if err := checkExpectedAddresses(entry); err != nil {
+			return imported, fmt.Errorf("entry %d: %w", i, err)
+		}
+
+		privKey := &secp256k1.PrivKey{Key: privKeyBytes}
+		key, err := importAndRegisterKey(appConfig, entry, privKey, entry.ServiceID, walletKeyring, relayMinerConfig)
+		if err != nil {
+			return imported, fmt.Errorf("error importing hex key: %w", err)
+		}
+		key.EntryIndex = i
+		key.DerivationIndex = -1
+		imported = append(imported, key)
+	} else {
+		return imported, fmt.Errorf("%w index: %d", ErrInvalidEntry, i)
+	}
+
+	err := applySupplierOverrides(appConfig, i, entry, overridden, relayMinerConfig)
+	if err != nil {
+		return imported, err
+	}
+
+	return imported, nil
+}
+
+// entryPrivateKeys derives the private keys of an entry along with their derivation index (-1 for a hex key).
+func entryPrivateKeys(entry WalletKeySpec) ([]*secp256k1.PrivKey, []int, error) {
+	privKeys := make([]*secp256k1.PrivKey, 0)
+	indexes := make([]int, 0)
+	if entry.Mnemonic != "" {
+		for j := entry.StartIndex; j <= entry.EndIndex; j++ {
+			privKey, err := derivePrivateKeyFromMnemonic(entry.Mnemonic, uint32(j))
+			if err != nil {
+				return nil, nil, fmt.Errorf("error deriving private key at index %d: %w", j, err)
+			}
+			privKeys = append(privKeys, privKey)
+			indexes = append(indexes, j)
+		}
+	} else {
+		privKeyBytes, err := hex.DecodeString(strings.TrimPrefix(entry.Hex, "0x"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidHexKey, err)
+		}
+		privKeys = append(privKeys, &secp256k1.PrivKey{Key: privKeyBytes})
+		indexes = append(indexes, -1)
+	}
+	return privKeys, indexes, nil
+}
+
+// checkExpectedAddresses derives the keys of the entry and compares their addresses with its expected_addresses,
+// telling an address prefix mistake from a different key (mnemonic, passphrase or coin type mistakes).
+func checkExpectedAddresses(entry WalletKeySpec) error {
+	if len(entry.ExpectedAddresses) == 0 {
+		return nil
+	}
+
+	privKeys, indexes, err := entryPrivateKeys(entry)
+	if err != nil {
+		return err
+	}
+
+	if len(entry.ExpectedAddresses) != len(privKeys) {
+		return fmt.Errorf("%w: %d expected_addresses for %d keys", ErrInvalidEntry, len(entry.ExpectedAddresses), len(privKeys))
+	}
+
+	for k, privKey := range privKeys {
+		address := sdk.AccAddress(privKey.PubKey().Address())
+		expected := entry.ExpectedAddresses[k]
+		if address.String() == expected {
+			continue
+		}
+
+		prefix, expectedBytes, err := bech32.DecodeAndConvert(expected)
+		switch {
+		case err != nil:
+			return fmt.Errorf("%w: invalid expected address %s: %w", ErrInvalidEntry, expected, err)
+		case bytes.Equal(expectedBytes, address):
+			return fmt.Errorf("%w at index %d: derived %s, expected %s: same key with prefix %s, check ADDRESS_PREFIX", ErrAddressMismatch, indexes[k], address, expected, prefix)
+		default:
+			return fmt.Errorf("%w at index %d: derived %s, expected %s: different key, check the mnemonic, its passphrase and coin type", ErrAddressMismatch, indexes[k], address, expected)
+		}
+	}
+	return nil
+}
+
+// importAndRegisterKey imports a single private key and, unless it is an owner or application key, registers it as
+// a signing key for the given service IDs. Owner and application keys never sign relays, so they are only imported.
+func importAndRegisterKey(appConfig *AppConfig, entry WalletKeySpec, privKey *secp256k1.PrivKey, serviceIds []string, walletKeyring keyring.Keyring, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) (ImportedKey, error) {
+	key := ImportedKey{
+		Address:     sdk.AccAddress(privKey.PubKey().Address()).String(),
+		Role:        entryRole(entry),
+		RotateAfter: entry.RotateAfter,
+		ExpiresAt:   entry.ExpiresAt,
+	}
+
+	if err := checkAddressAllowed(appConfig, key.Address); err != nil {
+		return key, err
+	}
+	if err := checkRunContext(appConfig); err != nil {
+		return key, err
+	}
+
+	name, err := importSecp256k1PrivateKey(walletKeyring, privKey)
+	if err != nil {
+		return key, err
+	}
+	key.Name = name
+
+	switch key.Role {
+	case OwnerRole:
+		keyLog.Debug().Str("name", name).Msg("Skipping relay miner registration of owner key")
+		return key, nil
+	case ApplicationRole:
+		keyLog.Debug().Str("name", name).Msg("Skipping relay miner registration of application key")
+		key.ServiceIds = serviceIds
+		key.GatewayAddresses = entry.GatewayAddresses
+		if len(key.GatewayAddresses) == 0 {
+			key.GatewayAddresses = appConfig.ApplicationGatewayAddresses
+		}
+		return key, nil
+	}
+
+	key.OwnerAddress = entry.OwnerAddress
+	if key.OwnerAddress == "" {
+		// custodial setup, the operator owns its own stake
+		key.OwnerAddress = key.Address
+	}
+
+	key.ServiceIds, err = registerKey(appConfig, name, serviceIds, relayMinerConfig)
+	if err != nil {
+		return key, err
+	}
+
+	return key, nil
 }
 
 // writeRelayMinerConfig updates a Relay Miner configuration file with the provided YAMLRelayMinerConfig object.
@@ -564,12 +1913,15 @@ func importAndRegisterKeys(appConfig *AppConfig, keys []WalletKeySpec, walletKey
 func writeRelayMinerConfig(appConfig *AppConfig, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
 	var mode os.FileMode = 0644
 
-	// ignore generating relayminer config when GENERATE_RELAYMINER_CONFIG=false 
+	// ignore generating relayminer config when GENERATE_RELAYMINER_CONFIG=false
 	if !appConfig.GenerateRelayMinerConfig {
 		log.Debug().Msg("Skipping relay miner config generation as it is disabled")
 		return nil
 	}
-	
+	if err := checkRunContext(appConfig); err != nil {
+		return err
+	}
+
 	// only if we read the file from the disk, we can keep the original permissions
 	if appConfig.ConfigSource == FileSource {
 		// Get file info for original permissions
@@ -581,120 +1933,699 @@ func writeRelayMinerConfig(appConfig *AppConfig, relayMinerConfig *poktrollconfi
 		mode = fileInfo.Mode()
 	}
 
-	// Marshal the updated config back to YAML
-	updatedContent, err := yaml.Marshal(relayMinerConfig)
+	// an explicit mode always wins, the relayminer may run as a different user than this process
+	if appConfig.RelayMinerConfigFileMode != 0 {
+		mode = appConfig.RelayMinerConfigFileMode
+	}
+
+	// Marshal the updated config back to the configured output format
+	updatedContent, err := marshalRelayMinerConfig(appConfig.RelayMinerOutputFormat, relayMinerConfig)
 	if err != nil {
 		return fmt.Errorf("unable to marshal updated config: %w", err)
 	}
 
+	// Publish to a Kubernetes ConfigMap or Secret instead of a local file
+	if appConfig.RelayMinerConfigOutputTarget != FileSource {
+		err = writeRelayMinerConfigResource(appConfig, updatedContent)
+		if err != nil {
+			return withExitCode(ExitSourceError, err)
+		}
+		target := fmt.Sprintf("%s %s/%s", appConfig.RelayMinerConfigOutputTarget, appConfig.RelayMinerConfigOutputNamespace, appConfig.RelayMinerConfigOutputName)
+		if err := auditConfigWritten(appConfig, target, updatedContent); err != nil {
+			return err
+		}
+		return withExitCode(ExitSourceError, triggerRollout(appConfig, updatedContent))
+	}
+
+	// Resolve template variables (pod name, timestamp, config hash, ...) in the output path
+	outputPath, err := renderOutputPath(appConfig.RelayMinerConfigFileOutputPath, updatedContent)
+	if err != nil {
+		return err
+	}
+
+	// Report what changed compared to the previously generated config, which templated paths keep elsewhere
+	previousPath, err := previousOutputPath(appConfig)
+	if err != nil {
+		return err
+	}
+	var previousContent []byte
+	if previousPath != "" {
+		previousContent, err = os.ReadFile(previousPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", previousPath).Msg("Unable to read previous config for diff")
+		}
+	}
+	err = reportConfigDiff(appConfig, previousContent, updatedContent)
+	if err != nil {
+		return err
+	}
+
+	// Keep a copy of the previous config so a bad generation can be rolled back
+	err = backupRelayMinerConfig(appConfig, previousPath, outputPath, updatedContent)
+	if err != nil {
+		return err
+	}
+
+	// templated paths may point to directories that do not exist yet
+	if outputPath != appConfig.RelayMinerConfigFileOutputPath {
+		if err := mkdirAllMode(filepath.Dir(outputPath), 0755); err != nil {
+			return fmt.Errorf("unable to create output directory: %w", err)
+		}
+	}
+
 	// Write the updated content to the output file (input could be read-only in some environments)
-	err = os.WriteFile(appConfig.RelayMinerConfigFileOutputPath, updatedContent, mode)
+	// atomically, so the relayminer never mounts a truncated file
+	err = writeFileAtomic(outputPath, updatedContent, mode)
 	if err != nil {
 		return fmt.Errorf("unable to write updated config file: %w", err)
 	}
 
+	err = chownPath(outputPath, appConfig.OutputUid, appConfig.OutputGid, false)
+	if err != nil {
+		return fmt.Errorf("unable to change updated config file owner: %w", err)
+	}
+	appConfig.configOutputPath = outputPath
+
 	log.Info().
-		Str("path", appConfig.RelayMinerConfigFileOutputPath).
+		Str("path", outputPath).
 		Msg("Relay miner configuration file updated successfully")
 
-	return nil
+	err = auditConfigWritten(appConfig, outputPath, updatedContent)
+	if err != nil {
+		return err
+	}
+
+	// Restart the relayminer workloads so they pick up the new config
+	return triggerRollout(appConfig, updatedContent)
+}
+
+// registerKey registers a signing key name against every service ID listed for its entry.
+// An empty service ID list registers the key under the default signing key names.
+// Returns the concrete service IDs of the suppliers the key was registered to.
+func registerKey(appConfig *AppConfig, name string, serviceIds []string, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]string, error) {
+	if len(serviceIds) == 0 {
+		return registerRelayMinerConfig(appConfig, name, "", relayMinerConfig)
+	}
+
+	registered := make([]string, 0, len(serviceIds))
+	for _, serviceId := range serviceIds {
+		matched, err := registerRelayMinerConfig(appConfig, name, serviceId, relayMinerConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matched {
+			if !slices.Contains(registered, id) {
+				registered = append(registered, id)
+			}
+		}
+	}
+
+	return registered, nil
+}
+
+// entryRole returns the role of a key entry, defaulting to operator.
+func entryRole(entry WalletKeySpec) string {
+	if entry.Role == "" {
+		return OperatorRole
+	}
+	return entry.Role
+}
+
+// validateRole checks the role of an entry and the fields that only make sense for that role.
+func validateRole(entry WalletKeySpec) error {
+	switch entryRole(entry) {
+	case OperatorRole:
+		if entry.OwnerAddress != "" {
+			if _, err := sdk.AccAddressFromBech32(entry.OwnerAddress); err != nil {
+				return fmt.Errorf("invalid owner address '%s': %w", entry.OwnerAddress, err)
+			}
+		}
+		if len(entry.GatewayAddresses) > 0 {
+			return fmt.Errorf("operator keys can't have gateway addresses")
+		}
+		return nil
+	case OwnerRole:
+		if len(entry.ServiceID) > 0 || len(entry.ServiceGroup) > 0 || entry.OwnerAddress != "" || entry.SupplierOverrides != nil || len(entry.GatewayAddresses) > 0 {
+			return fmt.Errorf("owner keys can't have service ids, service groups, an owner address, supplier overrides or gateway addresses")
+		}
+		return nil
+	case ApplicationRole:
+		for _, gatewayAddress := range entry.GatewayAddresses {
+			if _, err := sdk.AccAddressFromBech32(gatewayAddress); err != nil {
+				return fmt.Errorf("invalid gateway address '%s': %w", gatewayAddress, err)
+			}
+		}
+		if entry.OwnerAddress != "" || entry.SupplierOverrides != nil || len(entry.Endpoints) > 0 {
+			return fmt.Errorf("application keys can't have an owner address, supplier overrides or endpoints")
+		}
+		if len(entry.ServiceID) == 0 {
+			return fmt.Errorf("application keys require at least one service id")
+		}
+		for _, serviceId := range entry.ServiceID {
+			if !serviceIdPattern.MatchString(serviceId) {
+				return fmt.Errorf("application keys can't use service id patterns: %s", serviceId)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported role: %s", entry.Role)
+	}
+}
+
+// validateDistribution checks the distribution mode of a mnemonic entry against its service IDs.
+func validateDistribution(entry WalletKeySpec) error {
+	switch entry.Distribution {
+	case "", DistributionAll:
+		return nil
+	case DistributionRoundRobin:
+		if len(entry.ServiceID) == 0 {
+			return fmt.Errorf("distribution %s requires at least one service id", entry.Distribution)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported distribution: %s", entry.Distribution)
+	}
+}
+
+// distributedServiceIds returns the service IDs the key derived at index should be registered to.
+// With round-robin distribution a single service is picked based on the key offset within the range.
+func distributedServiceIds(entry WalletKeySpec, index int) []string {
+	if entry.Distribution != DistributionRoundRobin {
+		return entry.ServiceID
+	}
+
+	offset := index - entry.StartIndex
+	return []string{entry.ServiceID[offset%len(entry.ServiceID)]}
+}
+
+// serviceIdPattern matches the characters allowed in a plain service ID.
+// Anything outside of this set turns a service_id entry into a regular expression.
+var serviceIdPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// newServiceIdMatcher returns a function matching suppliers[].service_id against a service_id entry.
+// Plain service IDs are compared for equality, while entries like `^eth-.*` are compiled as regular expressions.
+func newServiceIdMatcher(serviceId string) (func(string) bool, error) {
+	if serviceIdPattern.MatchString(serviceId) {
+		return func(candidate string) bool {
+			return candidate == serviceId
+		}, nil
+	}
+
+	re, err := regexp.Compile(serviceId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service id pattern '%s': %w", serviceId, err)
+	}
+
+	return re.MatchString, nil
 }
 
 // registerRelayMinerConfig updates the relay miner configuration with a signing key name for a service ID or default.
 // If serviceId is provided, it adds the key name to the corresponding supplier. Otherwise, it updates the default list.
-// The function exits early if GenerateRelayMinerConfig is false or if the service ID is not found among suppliers.
-func registerRelayMinerConfig(appConfig *AppConfig, name, serviceId string, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+// A serviceId may also be a regular expression, in which case every matching supplier receives the key name.
+// Returns the service IDs of the suppliers the key name was added to.
+// The function exits early if GenerateRelayMinerConfig is false, returning plain service IDs as is and rejecting
+// patterns, which only resolve against the suppliers of the relay miner config, or if the service ID is not found
+// among suppliers.
+func registerRelayMinerConfig(appConfig *AppConfig, name, serviceId string, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]string, error) {
 	if !appConfig.GenerateRelayMinerConfig {
-		return nil
+		if serviceId == "" {
+			return nil, nil
+		}
+		if !serviceIdPattern.MatchString(serviceId) {
+			return nil, fmt.Errorf("%w: %s", ErrServicePattern, serviceId)
+		}
+		return []string{serviceId}, nil
 	}
 
-	log.Debug().
+	keyLog.Debug().
 		Str("name", name).
 		Str("service_id", serviceId).
 		Msg("Registering wallet to relayminer config")
 	// if service id, add to service id signing key names
 	if serviceId != "" {
-		found := false
+		matches, err := newServiceIdMatcher(serviceId)
+		if err != nil {
+			return nil, err
+		}
+
+		matched := make([]string, 0)
 		for j := range relayMinerConfig.Suppliers {
 			supplierConfig := &relayMinerConfig.Suppliers[j]
-			if supplierConfig.ServiceId == serviceId {
+			if matches(supplierConfig.ServiceId) {
 				if supplierConfig.SigningKeyNames == nil {
 					supplierConfig.SigningKeyNames = []string{}
 				}
-				supplierConfig.SigningKeyNames = append(supplierConfig.SigningKeyNames, name)
-				found = true // mark if at least one service id is found.
+				// a key could be matched more than once by overlapping patterns
+				if !slices.Contains(supplierConfig.SigningKeyNames, name) {
+					supplierConfig.SigningKeyNames = append(supplierConfig.SigningKeyNames, name)
+				}
+				matched = append(matched, supplierConfig.ServiceId)
 			}
 		}
 
-		if !found {
-			return fmt.Errorf("service id not found under suppliers[].service_id: %s", serviceId)
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceId)
 		}
-	} else {
-		// if not service id, add to default signing key names
-		if relayMinerConfig.DefaultSigningKeyNames == nil {
-			relayMinerConfig.DefaultSigningKeyNames = []string{}
+		return matched, nil
+	}
+
+	// if not service id, add to default signing key names
+	if relayMinerConfig.DefaultSigningKeyNames == nil {
+		relayMinerConfig.DefaultSigningKeyNames = []string{}
+	}
+	relayMinerConfig.DefaultSigningKeyNames = append(relayMinerConfig.DefaultSigningKeyNames, name)
+
+	return nil, nil
+}
+
+// loadDesiredKeys loads the key entries from their source, expands their service groups and applies the
+// key selection of the RelayMinerConfigTemplate being rendered, if any.
+func loadDesiredKeys(appConfig *AppConfig) ([]WalletKeySpec, error) {
+	keys, err := loadWalletKeys(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading wallet keys: %w", err)
+	}
+
+	// Expand service group references into concrete service IDs
+	serviceGroups, err := loadServiceGroups(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading service groups: %w", err)
+	}
+
+	err = resolveServiceGroups(keys, serviceGroups)
+	if err != nil {
+		return nil, withExitCode(ExitValidationError, fmt.Errorf("error resolving service groups: %w", err))
+	}
+
+	// Keep only the keys selected by the RelayMinerConfigTemplate, if any
+	keys, err = selectKeys(keys, appConfig.KeySelection)
+	if err != nil {
+		return nil, withExitCode(ExitValidationError, fmt.Errorf("error selecting keys: %w", err))
+	}
+
+	// Refuse to grind through a mistyped end_index
+	err = checkMaxKeys(appConfig, keys)
+	if err != nil {
+		return nil, withExitCode(ExitValidationError, err)
+	}
+
+	// Nudge operators toward rotating their keys
+	err = checkKeyDeadlines(appConfig, keys)
+	if err != nil {
+		return nil, withExitCode(ExitValidationError, err)
+	}
+
+	return keys, nil
+}
+
+// disabledEntries returns the indexes of the disabled entries.
+func disabledEntries(keys []WalletKeySpec) []int {
+	disabled := make([]int, 0)
+	for i, entry := range keys {
+		if entry.Disabled {
+			disabled = append(disabled, i)
 		}
-		relayMinerConfig.DefaultSigningKeyNames = append(relayMinerConfig.DefaultSigningKeyNames, name)
 	}
+	return disabled
+}
 
-	return nil
+// countDerivedKeys returns the number of keys the enabled entries derive: the range of every mnemonic entry, one per
+// hex key.
+func countDerivedKeys(keys []WalletKeySpec) int {
+	count := 0
+	for _, entry := range keys {
+		switch {
+		case entry.Disabled:
+		case entry.Mnemonic != "":
+			if entry.EndIndex >= entry.StartIndex {
+				count += entry.EndIndex - entry.StartIndex + 1
+			}
+		case entry.Hex != "":
+			count++
+		}
+	}
+	return count
 }
 
-func main() {
+// checkMaxKeys fails when the entries would derive more than MaxKeys keys, naming the largest range.
+func checkMaxKeys(appConfig *AppConfig, keys []WalletKeySpec) error {
+	count := countDerivedKeys(keys)
+	if appConfig.MaxKeys <= 0 || count <= appConfig.MaxKeys {
+		return nil
+	}
+
+	largest := 0
+	for i := range keys {
+		if countDerivedKeys(keys[i:i+1]) > countDerivedKeys(keys[largest:largest+1]) {
+			largest = i
+		}
+	}
+	return fmt.Errorf("%w: keys entries would derive %d keys, more than MAX_KEYS (%d); the largest is entry %d with start_index %d and end_index %d, raise MAX_KEYS if this is intended",
+		ErrTooManyKeys, count, appConfig.MaxKeys, largest, keys[largest].StartIndex, keys[largest].EndIndex)
+}
+
+// runTimeoutGrace is how long a pass canceled at its RunTimeout deadline gets to stop at its next write or broadcast
+// before it is abandoned.
+var runTimeoutGrace = 10 * time.Second
+
+// run executes a full import and generation pass, bounded by RunTimeout when set. Kubernetes requests, probes and
+// lock waits are canceled at the deadline; keyring operations can't be, so a pass stuck in one stops at its next
+// write or broadcast. A pass still stuck after runTimeoutGrace (e.g. on a hung gpg-agent) is abandoned and fails with
+// ErrPassAbandoned: the process must then exit, dropping the flock or letting the Lease expire.
+func run(appConfig *AppConfig) error {
+	err := runBounded(appConfig)
+	if err != nil {
+		signalFailure(appConfig, err)
+	}
+	return err
+}
+
+// runBounded executes runPass, bounded by RunTimeout when set.
+func runBounded(appConfig *AppConfig) error {
+	return boundPass(appConfig, runPass)
+}
+
+// boundPass executes pass, canceling it at the RunTimeout deadline when set. It then waits up to runTimeoutGrace for
+// the pass to return, so the run lock is only released (and the next pass started) once it can no longer write.
+func boundPass(appConfig *AppConfig, pass func(*AppConfig) error) error {
+	if appConfig.RunTimeout <= 0 {
+		return pass(appConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(appConfig.runContext(), appConfig.RunTimeout)
+	defer cancel()
+	config := *appConfig
+	config.ctx = ctx
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pass(&config)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Warn().Dur("run_timeout", appConfig.RunTimeout).Dur("grace", runTimeoutGrace).Msg("Pass timed out, waiting for it to stop at its next write")
+	select {
+	case <-done:
+		return withExitCode(ExitSourceError, fmt.Errorf("%w (%s)", ErrRunTimeout, appConfig.RunTimeout))
+	case <-time.After(runTimeoutGrace):
+		log.Error().Dur("grace", runTimeoutGrace).Msg("Pass still running after the grace period, abandoning it")
+		return withExitCode(ExitSourceError, fmt.Errorf("%w (%s): %w", ErrRunTimeout, appConfig.RunTimeout, ErrPassAbandoned))
+	}
+}
+
+// runPass executes a full import and generation pass: it loads the keys and configs from their sources,
+// imports the keys into the keyring and writes the generated configs.
+func runPass(appConfig *AppConfig) (err error) {
 	var walletKeyring keyring.Keyring
 	var relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig
 	var keys []WalletKeySpec
-	var err error
 
-	err = loadEnv()
+	// Summarize the pass for downstream automation, whatever its outcome
+	report := newRunReport(appConfig)
+	defer func() {
+		if reportErr := publishRunReport(appConfig, report, err); reportErr != nil && err == nil {
+			err = withComponent(ComponentReport, reportErr)
+		}
+	}()
+
+	// Classify a failure by the step of the pipeline it happened in
+	var stage string
+	defer func() {
+		err = withComponent(stage, err)
+	}()
+
+	// Fail up front on missing RBAC permissions rather than halfway through
+	stage = ComponentKubernetes
+	err = preflightAccess(appConfig)
 	if err != nil {
-		log.Fatal().Err(err)
+		return fmt.Errorf("error reviewing RBAC permissions: %w", err)
 	}
 
-	err = configureLogger()
+	// Make sure the keys can be written before taking the lock, which lives in the keyring dir by default, and that
+	// the backend has the binaries and daemons it needs
+	stage = ComponentKeyring
+	err = preflightKeyringDir(appConfig)
 	if err != nil {
-		log.Fatal().Err(err)
+		return withExitCode(ExitKeyringError, fmt.Errorf("error checking keyring dir: %w", err))
 	}
-
-	appConfig := loadAppConfig()
-
-	err = validateConfig(appConfig)
+	err = preflightKeyringDependencies(appConfig)
+	if err != nil {
+		return withExitCode(ExitKeyringError, err)
+	}
+	err = checkPermissions(appConfig)
 	if err != nil {
-		log.Fatal().Err(err).Msg("error validating config")
+		return withExitCode(ExitConfigError, err)
 	}
 
-	// Configure the sdk to use the right account prefix
-	configureSdk(appConfig)
+	// Keep concurrent loader instances from interleaving writes to the keyring and outputs
+	stage = ComponentRunLock
+	unlock, err := acquireRunLock(appConfig)
+	if err != nil {
+		return withExitCode(ExitSourceError, fmt.Errorf("error acquiring run lock: %w", err))
+	}
+	defer unlock()
 
 	// Read keys from a local file or kubernetes secret depending on CONFIG_SOURCE
-	keys, err = loadWalletKeys(appConfig)
+	stage = ComponentKeys
+	keys, err = loadDesiredKeys(appConfig)
 	if err != nil {
-		log.Fatal().Err(err).Msg("error loading wallet keys")
+		return err
 	}
+	report.SkippedEntries = disabledEntries(keys)
 
 	// Initialize cosmos walletKeyring
+	stage = ComponentKeyring
 	walletKeyring, err = newKeyring(appConfig)
 	if err != nil {
-		log.Fatal().Err(err).Msg("error initializing keyring")
+		return fmt.Errorf("error initializing keyring: %w", err)
 	}
+	walletKeyring = newAuditedKeyring(appConfig, walletKeyring, keysSource(appConfig))
 
 	// Read relay miner config (will be nil if GenerateRelayMinerConfig is false)
+	stage = ComponentRelayMinerConfig
 	relayMinerConfig, err = loadRelayMinerConfig(appConfig)
 	if err != nil {
-		log.Fatal().Err(err).Msg("error loading relay miner config")
+		return fmt.Errorf("error loading relay miner config: %w", err)
+	}
+	err = report.recordInputs(appConfig, keys, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error hashing pass inputs: %w", err)
 	}
 
-	// Process keys
-	err = importAndRegisterKeys(appConfig, keys, walletKeyring, relayMinerConfig)
+	// Skip the pass when the inputs are unchanged and the outputs still match the last pass
+	stage = ComponentState
+	state, drift, err := checkState(appConfig, report.Inputs, walletKeyring)
 	if err != nil {
-		log.Fatal().Err(err).Msg("error processing keys")
+		return fmt.Errorf("error checking state: %w", err)
+	}
+	report.Drift = drift
+	if state != nil {
+		log.Info().Time("applied_at", state.AppliedAt).Msg("Inputs unchanged since the last pass, skipping")
+		report.Status = ReportUnchanged
+		report.Keys = state.Keys
+		return signalCompletion(appConfig, state.Keys)
+	}
+
+	// Let operators plug in their own steps before anything is imported
+	stage = ComponentHooks
+	err = runHook(appConfig, appConfig.PreImportHook, newHookSummary(appConfig, PreImportHook, keys, nil))
+	if err != nil {
+		return fmt.Errorf("error running pre-import hook: %w", err)
+	}
+
+	// Catch service IDs unknown to the chain before registering keys to them
+	stage = ComponentChain
+	chain, err := newChainClient(appConfig)
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+	report.UnknownServices, err = checkServiceIds(appConfig, chain, keys, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error checking services: %w", err)
+	}
+
+	// Process keys, failed entries are reported at the end in FailModeContinue
+	stage = ComponentKeys
+	var entryErrors EntryErrors
+	importedKeys, err := importAndRegisterKeys(appConfig, keys, walletKeyring, relayMinerConfig)
+	if err != nil && !errors.As(err, &entryErrors) {
+		return fmt.Errorf("error processing keys: %w", err)
+	}
+	report.Keys = importedKeys
+
+	// Make sure every imported key signs before anything depends on it
+	stage = ComponentKeyring
+	err = selfTestKeys(appConfig, walletKeyring, importedKeys)
+	if err != nil {
+		return withExitCode(ExitKeyringError, fmt.Errorf("error self-testing keys: %w", err))
+	}
+
+	// Fund the new keys on devnets and testnets, then catch keys never funded or derived wrong before the
+	// relayminer signs with them
+	stage = ComponentChain
+	report.FundedAddresses, err = fundAccounts(appConfig, chain, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error funding accounts: %w", err)
+	}
+	report.MissingAccounts, err = checkAccounts(appConfig, chain, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error checking accounts: %w", err)
+	}
+	report.LowBalances, err = checkBalances(appConfig, chain, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error checking balances: %w", err)
+	}
+
+	// Catch stakes and delegations the chain would reject before broadcasting them
+	report.ParamViolations, err = checkChainParams(appConfig, chain, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error checking chain params: %w", err)
+	}
+
+	// Bootstrap the suppliers and delegations missing on chain, before checking them against the keys
+	transactions, err := stakeSuppliers(appConfig, chain, walletKeyring, keys, importedKeys)
+	report.Transactions = append(report.Transactions, transactions...)
+	if err != nil {
+		return fmt.Errorf("error staking suppliers: %w", err)
+	}
+	report.StakeMismatches, err = checkSupplierStakes(appConfig, chain, importedKeys, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error checking supplier stakes: %w", err)
+	}
+	transactions, err = delegateApplications(appConfig, chain, walletKeyring, importedKeys)
+	report.Transactions = append(report.Transactions, transactions...)
+	if err != nil {
+		return fmt.Errorf("error delegating applications: %w", err)
+	}
+	report.MissingDelegations, err = checkDelegations(appConfig, chain, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error checking gateway delegations: %w", err)
+	}
+	// Once the transactions are broadcast, so the sequences are the next ones to sign with
+	report.Accounts, err = recordAccounts(chain, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error recording accounts: %w", err)
+	}
+
+	// Write a supplier stake config for every operator key
+	stage = ComponentOutputs
+	err = writeSupplierStakeConfigs(appConfig, keys, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error writing supplier stake configs: %w", err)
+	}
+
+	// Hand the keyring over to the user the relayminer runs as
+	err = checkRunContext(appConfig)
+	if err != nil {
+		return err
+	}
+	err = chownPath(appConfig.KeyringDir, appConfig.OutputUid, appConfig.OutputGid, true)
+	if err != nil {
+		return fmt.Errorf("error changing keyring dir owner: %w", err)
+	}
+
+	// Write an AppGate-style config for every application key
+	err = writeApplicationConfigs(appConfig, importedKeys, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error writing application configs: %w", err)
+	}
+
+	// Match the suppliers to the services the operator keys are staked for, before resolving their backends
+	stage = ComponentChain
+	report.SuppliersAdded, report.SuppliersRemoved, err = syncOnchainSuppliers(appConfig, chain, importedKeys, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error syncing suppliers from chain: %w", err)
+	}
+
+	// Point suppliers at the in-cluster Services serving them
+	stage = ComponentBackends
+	err = discoverSupplierBackends(appConfig, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error discovering supplier backends: %w", err)
+	}
+
+	// Make sure every supplier ends up with at least one signing key
+	stage = ComponentRelayMinerConfig
+	recordServiceCoverage(appConfig, report, relayMinerConfig)
+	err = checkEmptySuppliers(appConfig, relayMinerConfig)
+	if err != nil {
+		return withExitCode(ExitValidationError, fmt.Errorf("error checking suppliers signing keys: %w", err))
+	}
+
+	// Probe supplier backends so typos are caught before the relayminer starts
+	stage = ComponentBackends
+	err = preflightSupplierBackends(appConfig, relayMinerConfig)
+	if err != nil {
+		return withExitCode(ExitSourceError, fmt.Errorf("error probing supplier backends: %w", err))
 	}
 
 	// Update relay miner config
+	stage = ComponentOutputs
 	err = writeRelayMinerConfig(appConfig, relayMinerConfig)
 	if err != nil {
-		log.Fatal().Err(err).Msg("error writing relay miner config")
+		return fmt.Errorf("error writing relay miner config: %w", err)
 	}
 
-	log.Info().Msg("All keys processed successfully.")
+	// Map the keys Secret to the identities it was imported as
+	err = annotateKeysSecret(appConfig, walletKeyring, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error annotating keys secret: %w", err)
+	}
+
+	stage = ComponentKeys
+	if len(entryErrors) > 0 {
+		entryErrors.report()
+		return fmt.Errorf("error processing keys: %w", entryErrors)
+	}
+
+	// Record the pass so the next one can be skipped when nothing changes
+	stage = ComponentState
+	err = saveState(appConfig, report.Inputs, importedKeys, relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("error saving state: %w", err)
+	}
+
+	// Let sidecars and startup probes know provisioning finished
+	stage = ComponentSignals
+	err = signalCompletion(appConfig, importedKeys)
+	if err != nil {
+		return fmt.Errorf("error signaling completion: %w", err)
+	}
+	recordServiceRegistrations(report.Services)
+
+	// Notify, stake, reload a service... once the pass is complete
+	stage = ComponentHooks
+	err = runHook(appConfig, appConfig.PostImportHook, newHookSummary(appConfig, PostImportHook, keys, importedKeys))
+	if err != nil {
+		return fmt.Errorf("error running post-import hook: %w", err)
+	}
+
+	log.Info().
+		Int("keys", len(importedKeys)).
+		Ints("disabled_entries", disabledEntries(keys)).
+		Msg("All keys processed successfully.")
+	return nil
+}
+
+func main() {
+	root := newRootCommand()
+	root.SetArgs(normalizeArgs(os.Args[1:]))
+	if err := root.Execute(); err != nil {
+		code := exitCode(err)
+		classified := classifyError(err)
+		log.Error().
+			Err(err).
+			Int("exit_code", code).
+			Str("error_code", classified.Code).
+			Str("component", classified.Component).
+			Bool("retryable", classified.Retryable).
+			Msg("error running keyring loader")
+		os.Exit(code)
+	}
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+)
+
+func TestNewServiceIdMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		serviceId string
+		matches   []string
+		misses    []string
+		wantErr   bool
+	}{
+		{
+			name:      "plain service id",
+			serviceId: "anvil",
+			matches:   []string{"anvil"},
+			misses:    []string{"anvil-2", "xanvil", ""},
+		},
+		{
+			name:      "plain service id is not a pattern",
+			serviceId: "eth_mainnet-1",
+			matches:   []string{"eth_mainnet-1"},
+			misses:    []string{"eth_mainnet-10"},
+		},
+		{
+			name:      "anchored pattern",
+			serviceId: "^eth-.*",
+			matches:   []string{"eth-mainnet", "eth-"},
+			misses:    []string{"base-eth-mainnet", "eth"},
+		},
+		{
+			name:      "alternation",
+			serviceId: "^(anvil|ollama)$",
+			matches:   []string{"anvil", "ollama"},
+			misses:    []string{"anvil2", "llama"},
+		},
+		{
+			name:      "invalid pattern",
+			serviceId: "eth-(",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := newServiceIdMatcher(tt.serviceId)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("newServiceIdMatcher(%q) succeeded, want an error", tt.serviceId)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newServiceIdMatcher(%q): %v", tt.serviceId, err)
+			}
+			for _, candidate := range tt.matches {
+				if !matches(candidate) {
+					t.Errorf("%q doesn't match %q", tt.serviceId, candidate)
+				}
+			}
+			for _, candidate := range tt.misses {
+				if matches(candidate) {
+					t.Errorf("%q matches %q", tt.serviceId, candidate)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterRelayMinerConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		generate  bool
+		serviceId string
+		want      []string
+		wantErr   error
+	}{
+		{name: "default key", generate: true, want: nil},
+		{name: "plain service id", generate: true, serviceId: "anvil", want: []string{"anvil"}},
+		{name: "pattern", generate: true, serviceId: "^eth-.*", want: []string{"eth-mainnet", "eth-sepolia"}},
+		{name: "unknown service id", generate: true, serviceId: "ollama", wantErr: ErrServiceNotFound},
+		{name: "plain service id without config", serviceId: "ollama", want: []string{"ollama"}},
+		{name: "pattern without config", serviceId: "^eth-.*", wantErr: ErrServicePattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relayMinerConfig := &poktrollconfig.YAMLRelayMinerConfig{Suppliers: []poktrollconfig.YAMLRelayMinerSupplierConfig{
+				{ServiceId: "anvil"},
+				{ServiceId: "eth-mainnet"},
+				{ServiceId: "eth-sepolia"},
+			}}
+			appConfig := &AppConfig{GenerateRelayMinerConfig: tt.generate}
+
+			got, err := registerRelayMinerConfig(appConfig, "supplier1", tt.serviceId, relayMinerConfig)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("registerRelayMinerConfig error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("registerRelayMinerConfig: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("registerRelayMinerConfig = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportAndRegisterKeysContinueRollsBack(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	keys := []WalletKeySpec{
+		{Hex: "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", ServiceID: []string{"anvil"}},
+		// key 0 registers to anvil, key 1 fails on the missing service once imported
+		{Mnemonic: mnemonic, StartIndex: 0, EndIndex: 1, Distribution: DistributionRoundRobin, ServiceID: []string{"anvil", "missing"}},
+	}
+	relayMinerConfig := &poktrollconfig.YAMLRelayMinerConfig{Suppliers: []poktrollconfig.YAMLRelayMinerSupplierConfig{{ServiceId: "anvil"}}}
+	appConfig := &AppConfig{GenerateRelayMinerConfig: true, FailMode: FailModeContinue}
+	walletKeyring := keyring.NewInMemory(getCodec())
+
+	imported, err := importAndRegisterKeys(appConfig, keys, walletKeyring, relayMinerConfig)
+	var failures EntryErrors
+	if !errors.As(err, &failures) || len(failures) != 1 || failures[0].Index != 1 {
+		t.Fatalf("importAndRegisterKeys error = %v, want entry 1 to fail", err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("importAndRegisterKeys imported %d keys, want the key of entry 0", len(imported))
+	}
+
+	records, err := walletKeyring.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Name != imported[0].Name {
+		t.Errorf("keyring holds %d keys, want only the key of entry 0", len(records))
+	}
+	if names := relayMinerConfig.Suppliers[0].SigningKeyNames; !reflect.DeepEqual(names, []string{imported[0].Name}) {
+		t.Errorf("anvil signing keys = %v, want only the key of entry 0", names)
+	}
+}
+
+func TestCanceledPassStopsWriting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir := t.TempDir()
+	appConfig := &AppConfig{ctx: ctx, StateFilePath: filepath.Join(dir, "state.json"), CompletionFilePath: filepath.Join(dir, "complete")}
+	walletKeyring := keyring.NewInMemory(getCodec())
+	privKeys, _, err := entryPrivateKeys(WalletKeySpec{Hex: "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := importAndRegisterKey(appConfig, WalletKeySpec{}, privKeys[0], nil, walletKeyring, nil); !errors.Is(err, ErrRunTimeout) {
+		t.Errorf("importAndRegisterKey error = %v, want %v", err, ErrRunTimeout)
+	}
+	if records, _ := walletKeyring.List(); len(records) != 0 {
+		t.Errorf("keyring holds %d keys after the pass was canceled", len(records))
+	}
+	if err := saveState(appConfig, ReportInputs{}, nil, nil); !errors.Is(err, ErrRunTimeout) || exitCode(err) != ExitSourceError {
+		t.Errorf("saveState error = %v (exit code %d), want %v", err, exitCode(err), ErrRunTimeout)
+	}
+	if err := signalCompletion(appConfig, nil); !errors.Is(err, ErrRunTimeout) {
+		t.Errorf("signalCompletion error = %v, want %v", err, ErrRunTimeout)
+	}
+	for _, path := range []string{appConfig.StateFilePath, appConfig.CompletionFilePath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s written after the pass was canceled", path)
+		}
+	}
+}
+
+// blockingKeyring is a keyring whose imports hang until released, like on a hung gpg-agent.
+type blockingKeyring struct {
+	keyring.Keyring
+	release chan struct{}
+}
+
+func (k blockingKeyring) ImportPrivKeyHex(uid, privKey, algoStr string) error {
+	<-k.release
+	return k.Keyring.ImportPrivKeyHex(uid, privKey, algoStr)
+}
+
+func TestBoundPassAbandonsStuckPass(t *testing.T) {
+	grace := runTimeoutGrace
+	runTimeoutGrace = 50 * time.Millisecond
+	t.Cleanup(func() { runTimeoutGrace = grace })
+
+	privKeys, _, err := entryPrivateKeys(WalletKeySpec{Hex: "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletKeyring := blockingKeyring{Keyring: keyring.NewInMemory(getCodec()), release: make(chan struct{})}
+	t.Cleanup(func() { close(walletKeyring.release) })
+
+	returned := make(chan error, 1)
+	go func() {
+		returned <- boundPass(&AppConfig{RunTimeout: 50 * time.Millisecond}, func(appConfig *AppConfig) error {
+			_, err := importAndRegisterKey(appConfig, WalletKeySpec{}, privKeys[0], nil, walletKeyring, nil)
+			return err
+		})
+	}()
+
+	select {
+	case err := <-returned:
+		if !errors.Is(err, ErrRunTimeout) || !errors.Is(err, ErrPassAbandoned) || exitCode(err) != ExitSourceError {
+			t.Errorf("boundPass error = %v (exit code %d), want %v and %v", err, exitCode(err), ErrRunTimeout, ErrPassAbandoned)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("boundPass did not return while the keyring import hangs")
+	}
+}
+
+func TestBoundPassWaitsForCanceledPass(t *testing.T) {
+	stopped := false
+	err := boundPass(&AppConfig{RunTimeout: 10 * time.Millisecond}, func(appConfig *AppConfig) error {
+		<-appConfig.runContext().Done()
+		time.Sleep(20 * time.Millisecond)
+		stopped = true
+		return checkRunContext(appConfig)
+	})
+	if !errors.Is(err, ErrRunTimeout) || errors.Is(err, ErrPassAbandoned) {
+		t.Errorf("boundPass error = %v, want %v without %v", err, ErrRunTimeout, ErrPassAbandoned)
+	}
+	if !stopped {
+		t.Error("boundPass returned before the canceled pass stopped")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// protectMemory hardens the process holding key material: core dumps are disabled unless CoreDumps is set, and the
+// memory is locked so it is never swapped out when LockMemory is set. Failing to lock the memory is fatal since it
+// was asked for, failing to disable core dumps only warns.
+func protectMemory(appConfig *AppConfig) error {
+	if !appConfig.CoreDumps {
+		if err := disableCoreDumps(); err != nil {
+			log.Warn().Err(err).Msg("Unable to disable core dumps")
+		} else {
+			log.Debug().Msg("Core dumps disabled")
+		}
+	}
+
+	if appConfig.LockMemory {
+		if err := lockMemory(); err != nil {
+			return fmt.Errorf("unable to lock memory (the loader needs CAP_IPC_LOCK or a memlock ulimit covering the process): %w", err)
+		}
+		log.Info().Msg("Memory locked")
+	}
+	return nil
+}
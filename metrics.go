@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/rs/zerolog/log"
+)
+
+// pushTimeout bounds the push of the run metrics to the Pushgateway.
+const pushTimeout = 10 * time.Second
+
+// metricsRegistry holds the metrics of the loader, apart from the Go runtime ones of the default registry.
+var metricsRegistry = prometheus.NewRegistry()
+
+// Run metrics
+var (
+	lastRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "keyring_loader_last_run_timestamp_seconds",
+		Help: "Unix time the last run completed at.",
+	})
+	lastRunDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "keyring_loader_last_run_duration_seconds",
+		Help: "Duration of the last run.",
+	})
+	lastRunSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "keyring_loader_last_run_success",
+		Help: "1 when the last run succeeded, 0 otherwise.",
+	})
+	lastRunExitCode = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "keyring_loader_last_run_exit_code",
+		Help: "Exit code of the last run, 0 on success.",
+	})
+	keysProvisioned = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "keyring_loader_keys_provisioned_total",
+		Help: "Keys present in the keyring after every successful pass.",
+	})
+	serviceSigningKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "keyring_loader_service_signing_keys",
+		Help: "Signing keys of every supplier of the generated relay miner config, 0 for suppliers without keys.",
+	}, []string{"service_id"})
+	serviceKeysRegistered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "keyring_loader_service_keys_registered_total",
+		Help: "Keys registered to every supplier by the successful passes, default signing keys included.",
+	}, []string{"service_id"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(lastRunTimestamp, lastRunDuration, lastRunSuccess, lastRunExitCode, keysProvisioned,
+		serviceSigningKeys, serviceKeysRegistered)
+}
+
+// recordServiceMetrics replaces the signing keys of every supplier with coverage, dropping the services no longer
+// served.
+func recordServiceMetrics(coverage []ServiceCoverage) {
+	serviceSigningKeys.Reset()
+	for _, service := range coverage {
+		serviceSigningKeys.WithLabelValues(service.ServiceId).Set(float64(service.SigningKeys))
+	}
+}
+
+// recordServiceRegistrations counts the keys registered to every supplier of coverage by a successful pass.
+func recordServiceRegistrations(coverage []ServiceCoverage) {
+	for _, service := range coverage {
+		serviceKeysRegistered.WithLabelValues(service.ServiceId).Add(float64(service.SigningKeys))
+	}
+}
+
+// recordRun records the outcome of a run started at started.
+func recordRun(started time.Time, err error) {
+	lastRunTimestamp.SetToCurrentTime()
+	lastRunDuration.Set(time.Since(started).Seconds())
+	if err != nil {
+		lastRunSuccess.Set(0)
+		lastRunExitCode.Set(float64(exitCode(err)))
+		return
+	}
+	lastRunSuccess.Set(1)
+	lastRunExitCode.Set(0)
+}
+
+// pushRunMetrics pushes the run metrics to the Pushgateway at PushgatewayURL, grouped by the PushgatewayJob job and
+// PushgatewayInstance instance, replacing the previous push of the group. One-shot runs exit before any scrape, so
+// this is how they report. A failed push is logged but doesn't fail the run.
+func pushRunMetrics(appConfig *AppConfig) {
+	if appConfig.PushgatewayURL == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+
+	pusher := push.New(appConfig.PushgatewayURL, appConfig.PushgatewayJob).
+		Grouping("instance", orDefault(appConfig.PushgatewayInstance, podName())).
+		Gatherer(metricsRegistry)
+	if err := pusher.PushContext(ctx); err != nil {
+		log.Warn().Err(err).Str("url", redactSetting("PUSHGATEWAY_URL", appConfig.PushgatewayURL)).Msg("Unable to push run metrics")
+		return
+	}
+	log.Info().Str("url", redactSetting("PUSHGATEWAY_URL", appConfig.PushgatewayURL)).Str("job", appConfig.PushgatewayJob).Msg("Run metrics pushed")
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// namespacedData is the content of a ConfigMap or Secret key read from one of several namespaces.
+type namespacedData struct {
+	Namespace string
+	Data      []byte
+}
+
+// namespacePatterns splits a namespace setting into its comma-separated entries, which may be path.Match patterns.
+func namespacePatterns(namespaces string) []string {
+	patterns := make([]string, 0)
+	for _, pattern := range strings.Split(namespaces, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// isMultiNamespace reports whether a namespace setting selects several namespaces: a list or a wildcard.
+func isMultiNamespace(namespaces string) bool {
+	return strings.ContainsAny(namespaces, ",*?[")
+}
+
+// hasNamespaceWildcard reports whether a namespace setting holds a pattern that must be matched against the cluster.
+func hasNamespaceWildcard(namespaces string) bool {
+	return strings.ContainsAny(namespaces, "*?[")
+}
+
+// matchesNamespace reports whether namespace is selected by the namespaces setting.
+func matchesNamespace(namespaces, namespace string) bool {
+	for _, pattern := range namespacePatterns(namespaces) {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfigDataFromNamespaces loads the key of the ConfigMap or Secret called name from every namespace selected
+// by namespaces, sorted by namespace. Namespaces without the resource are skipped. Wildcards list the resource
+// across the cluster, which needs cluster-wide `list` RBAC on it.
+func loadConfigDataFromNamespaces(appConfig *AppConfig, source, namespaces, name, key string) ([]namespacedData, error) {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := appConfig.runContext()
+	contents := make(map[string]map[string][]byte)
+	collect := func(namespace string, data map[string][]byte) {
+		if matchesNamespace(namespaces, namespace) {
+			contents[namespace] = data
+		}
+	}
+
+	if hasNamespaceWildcard(namespaces) {
+		options := v1.ListOptions{FieldSelector: "metadata.name=" + name}
+		switch source {
+		case ConfigMapSource:
+			var configMaps *corev1.ConfigMapList
+			err := retryKubernetes(appConfig, "list configmaps", func() (err error) {
+				configMaps, err = clientset.CoreV1().ConfigMaps(v1.NamespaceAll).List(ctx, options)
+				return err
+			})
+			if err != nil {
+				return nil, withExitCode(ExitSourceError, fmt.Errorf("error listing configmaps '%s': %w", name, err))
+			}
+			for _, configMap := range configMaps.Items {
+				collect(configMap.Namespace, stringData(configMap.Data))
+			}
+		case SecretSource:
+			var secrets *corev1.SecretList
+			err := retryKubernetes(appConfig, "list secrets", func() (err error) {
+				secrets, err = clientset.CoreV1().Secrets(v1.NamespaceAll).List(ctx, options)
+				return err
+			})
+			if err != nil {
+				return nil, withExitCode(ExitSourceError, fmt.Errorf("error listing secrets '%s': %w", name, err))
+			}
+			for _, secret := range secrets.Items {
+				collect(secret.Namespace, secret.Data)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported configuration source: %s", source)
+		}
+	} else {
+		for _, namespace := range namespacePatterns(namespaces) {
+			var data map[string][]byte
+			switch source {
+			case ConfigMapSource:
+				var configMap *corev1.ConfigMap
+				err := retryKubernetes(appConfig, "get configmap", func() (err error) {
+					configMap, err = clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, v1.GetOptions{})
+					return err
+				})
+				if k8serrors.IsNotFound(err) {
+					log.Warn().Str("namespace", namespace).Str("name", name).Msg("ConfigMap not found, skipping namespace")
+					continue
+				}
+				if err != nil {
+					return nil, withExitCode(ExitSourceError, fmt.Errorf("error fetching configmap '%s' in namespace '%s': %w", name, namespace, err))
+				}
+				data = stringData(configMap.Data)
+			case SecretSource:
+				var secret *corev1.Secret
+				err := retryKubernetes(appConfig, "get secret", func() (err error) {
+					secret, err = clientset.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+					return err
+				})
+				if k8serrors.IsNotFound(err) {
+					log.Warn().Str("namespace", namespace).Str("name", name).Msg("Secret not found, skipping namespace")
+					continue
+				}
+				if err != nil {
+					return nil, withExitCode(ExitSourceError, fmt.Errorf("error fetching secret '%s' in namespace '%s': %w", name, namespace, err))
+				}
+				data = secret.Data
+			default:
+				return nil, fmt.Errorf("unsupported configuration source: %s", source)
+			}
+			collect(namespace, data)
+		}
+	}
+
+	results := make([]namespacedData, 0, len(contents))
+	for namespace, data := range contents {
+		content, ok := data[key]
+		if !ok {
+			return nil, withExitCode(ExitValidationError, fmt.Errorf("error: %s '%s' in namespace '%s' does not contain key '%s'", source, name, namespace, key))
+		}
+		results = append(results, namespacedData{Namespace: namespace, Data: content})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Namespace < results[j].Namespace
+	})
+
+	log.Info().
+		Str("source", source).
+		Str("namespaces", namespaces).
+		Str("name", name).
+		Int("found", len(results)).
+		Msg("Loaded from multiple namespaces")
+	return results, nil
+}
+
+// stringData converts ConfigMap data to the byte values of Secret data.
+func stringData(data map[string]string) map[string][]byte {
+	converted := make(map[string][]byte, len(data))
+	for key, value := range data {
+		converted[key] = []byte(value)
+	}
+	return converted
+}
+
+// loadWalletKeysFromNamespaces concatenates the keys.json entries of the keys Secrets of every KEYS_NAMESPACE.
+func loadWalletKeysFromNamespaces(appConfig *AppConfig) ([]WalletKeySpec, error) {
+	documents, err := loadConfigDataFromNamespaces(appConfig, SecretSource, appConfig.KeysNamespace, appConfig.KeysSecretName, appConfig.KeysSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	keys := make([]WalletKeySpec, 0)
+	for _, document := range documents {
+		namespaceKeys := make([]WalletKeySpec, 0)
+		if err := k8syaml.Unmarshal(document.Data, &namespaceKeys); err != nil {
+			log.Error().Err(err).Str("namespace", document.Namespace).Msg("Failed to parse wallet keys JSON data")
+			return nil, withExitCode(ExitValidationError, fmt.Errorf("error parsing JSON data from secret in namespace '%s': %w", document.Namespace, err))
+		}
+		log.Debug().Str("namespace", document.Namespace).Int("key_count", len(namespaceKeys)).Msg("Wallet keys loaded from namespace")
+		keys = append(keys, namespaceKeys...)
+	}
+
+	log.Info().Int("key_count", len(keys)).Int("namespaces", len(documents)).Msg("Wallet keys loaded successfully")
+	return keys, nil
+}
+
+// loadRelayMinerConfigFromNamespaces merges the base relay miner configs of every RELAYMINER_CONFIG_NAMESPACE into a
+// single document: the first namespace (in sorted order) provides the global settings, the suppliers of all of
+// them are concatenated.
+func loadRelayMinerConfigFromNamespaces(appConfig *AppConfig) ([]byte, error) {
+	documents, err := loadConfigDataFromNamespaces(appConfig, ConfigMapSource, appConfig.RelayMinerConfigNamespace, appConfig.RelayMinerConfigName, appConfig.RelayMinerConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	if len(documents) == 0 {
+		return nil, withExitCode(ExitSourceError, fmt.Errorf("configmap '%s' not found in namespaces '%s'", appConfig.RelayMinerConfigName, appConfig.RelayMinerConfigNamespace))
+	}
+
+	merged := yaml.MapSlice{}
+	if err := yaml.Unmarshal(documents[0].Data, &merged); err != nil {
+		return nil, withExitCode(ExitValidationError, fmt.Errorf("unable to unmarshall RelayMiner config in namespace '%s': %w", documents[0].Namespace, err))
+	}
+
+	suppliers := make([]interface{}, 0)
+	for _, document := range documents {
+		config := struct {
+			Suppliers []interface{} `yaml:"suppliers"`
+		}{}
+		if err := yaml.Unmarshal(document.Data, &config); err != nil {
+			return nil, withExitCode(ExitValidationError, fmt.Errorf("unable to unmarshall RelayMiner config in namespace '%s': %w", document.Namespace, err))
+		}
+		suppliers = append(suppliers, config.Suppliers...)
+	}
+
+	found := false
+	for i := range merged {
+		if merged[i].Key == "suppliers" {
+			merged[i].Value = suppliers
+			found = true
+		}
+	}
+	if !found {
+		merged = append(merged, yaml.MapItem{Key: "suppliers", Value: suppliers})
+	}
+
+	content, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal merged RelayMiner config: %w", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestMatchesNamespace(t *testing.T) {
+	tests := []struct {
+		namespaces string
+		namespace  string
+		want       bool
+	}{
+		{namespaces: "relayminers", namespace: "relayminers", want: true},
+		{namespaces: "relayminers", namespace: "relayminers-eu", want: false},
+		{namespaces: "team-a, team-b", namespace: "team-b", want: true},
+		{namespaces: "team-a,team-b", namespace: "team-c", want: false},
+		{namespaces: "team-*", namespace: "team-a", want: true},
+		{namespaces: "team-*", namespace: "default", want: false},
+		{namespaces: "*", namespace: "kube-system", want: true},
+		{namespaces: "relayminer-?", namespace: "relayminer-1", want: true},
+		{namespaces: " , ", namespace: "default", want: false},
+		{namespaces: "", namespace: "default", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespaces+"/"+tt.namespace, func(t *testing.T) {
+			if got := matchesNamespace(tt.namespaces, tt.namespace); got != tt.want {
+				t.Errorf("matchesNamespace(%q, %q) = %t, want %t", tt.namespaces, tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Payload formats of the notification webhook
+const (
+	// NotifyFormatJSON posts the RunNotification as is (default).
+	NotifyFormatJSON string = "json"
+	// NotifyFormatSlack posts a Slack incoming webhook message.
+	NotifyFormatSlack string = "slack"
+)
+
+// Passes the notification webhook is fired for
+const (
+	// NotifyOnAlways notifies every pass, successful or not (default).
+	NotifyOnAlways string = "always"
+	// NotifyOnFailure only notifies failed passes.
+	NotifyOnFailure string = "failure"
+)
+
+// notifyTimeout bounds a notification request.
+const notifyTimeout = 10 * time.Second
+
+// RunNotification summarizes a pass for the notification webhook.
+type RunNotification struct {
+	Status    string    `json:"status"`
+	Pod       string    `json:"pod"`
+	Namespace string    `json:"namespace,omitempty"`
+	RunMode   string    `json:"run_mode"`
+	At        time.Time `json:"at"`
+	// Keys are the keys in the keyring after a successful pass.
+	Keys []ImportedKey `json:"keys,omitempty"`
+	// Error and ExitCode describe a failed pass.
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+// newRunNotification describes the outcome of a pass: importedKeys on success, err on failure.
+func newRunNotification(appConfig *AppConfig, importedKeys []ImportedKey, err error) RunNotification {
+	notification := RunNotification{
+		Status:    "success",
+		Pod:       podName(),
+		Namespace: podNamespace(),
+		RunMode:   appConfig.RunMode,
+		At:        time.Now().UTC(),
+		Keys:      importedKeys,
+	}
+	if err != nil {
+		notification.Status = "failure"
+		notification.Error = err.Error()
+		notification.ExitCode = exitCode(err)
+	}
+	return notification
+}
+
+// slackMessage renders notification as the text of a Slack message.
+func slackMessage(notification RunNotification) string {
+	source := notification.Pod
+	if notification.Namespace != "" {
+		source = notification.Namespace + "/" + notification.Pod
+	}
+	if notification.Status == "failure" {
+		return fmt.Sprintf(":rotating_light: shannon-keyring-loader on `%s` failed (exit code %d): %s", source, notification.ExitCode, notification.Error)
+	}
+	return fmt.Sprintf(":white_check_mark: shannon-keyring-loader on `%s` provisioned %d keys", source, len(notification.Keys))
+}
+
+// notify posts notification to NotifyWebhookURL, unless disabled or filtered out by NotifyOn. The pass is already
+// over, so a failed notification is only logged.
+func notify(appConfig *AppConfig, notification RunNotification) {
+	if appConfig.NotifyWebhookURL == "" || (appConfig.NotifyOn == NotifyOnFailure && notification.Status != "failure") {
+		return
+	}
+
+	var payload interface{} = notification
+	if appConfig.NotifyFormat == NotifyFormatSlack {
+		payload = map[string]string{"text": slackMessage(notification)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to marshal notification")
+		return
+	}
+
+	// webhook urls embed their credentials in the path, only log the host
+	host := ""
+	if parsed, err := url.Parse(appConfig.NotifyWebhookURL); err == nil {
+		host = parsed.Host
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, appConfig.NotifyWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(urlErrorCause(err)).Str("host", host).Msg("Unable to build notification request")
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		log.Warn().Err(urlErrorCause(err)).Str("host", host).Msg("Unable to send notification")
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		log.Warn().Int("status", response.StatusCode).Str("host", host).Msg("Notification rejected")
+		return
+	}
+	log.Info().Str("status", notification.Status).Str("host", host).Msg("Notification sent")
+}
+
+// urlErrorCause strips the url quoted by url and http client errors, which would log the credentials of a webhook.
+func urlErrorCause(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
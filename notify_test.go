@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUrlErrorCause(t *testing.T) {
+	_, err := http.NewRequest(http.MethodPost, "https://hooks.slack.com/services/T000/B000/secret\x7f", nil)
+	if err == nil {
+		t.Fatal("expected an invalid url error")
+	}
+	if got := urlErrorCause(err).Error(); strings.Contains(got, "secret") {
+		t.Errorf("urlErrorCause(%v) = %q, still quotes the url", err, got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the on-chain suppliers sync
+const (
+	OnchainSuppliersOff  string = "off"
+	OnchainSuppliersAdd  string = "add"
+	OnchainSuppliersSync string = "sync"
+)
+
+// defaultSupplierListenUrl is the listen_url of the suppliers added from the chain when neither
+// ONCHAIN_SUPPLIERS_LISTEN_URL nor the base config sets one.
+const defaultSupplierListenUrl = "http://0.0.0.0:8545"
+
+// onchainSupplierListenUrl returns the listen_url of the suppliers added from the chain: OnchainSuppliersListenUrl,
+// or the one of the first supplier of the base config, since the relayminer serves several suppliers on one listener.
+func onchainSupplierListenUrl(appConfig *AppConfig, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) string {
+	if appConfig.OnchainSuppliersListenUrl != "" {
+		return appConfig.OnchainSuppliersListenUrl
+	}
+	for _, supplierConfig := range relayMinerConfig.Suppliers {
+		if supplierConfig.ListenUrl != "" {
+			return supplierConfig.ListenUrl
+		}
+	}
+	return defaultSupplierListenUrl
+}
+
+// syncOnchainSuppliers makes suppliers[] of the relay miner config match the services the imported operator keys are
+// staked for on chain. A supplier is added for every staked service missing from the config, listening on
+// onchainSupplierListenUrl with the backend_url of OnchainSuppliersBackendUrl ({service_id} replaced, or left to
+// BACKEND_DISCOVERY), and operator keys not registered as default signing keys are registered to the services they
+// are staked for. OnchainSuppliersSync also removes the suppliers no imported operator key is staked for. The added
+// and removed service IDs are returned.
+func syncOnchainSuppliers(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]string, []string, error) {
+	if appConfig.OnchainSuppliers == OnchainSuppliersOff || !appConfig.GenerateRelayMinerConfig {
+		return nil, nil, nil
+	}
+
+	staked := make([]string, 0)
+	added := make([]string, 0)
+	listenUrl := onchainSupplierListenUrl(appConfig, relayMinerConfig)
+	for i := range importedKeys {
+		importedKey := &importedKeys[i]
+		if importedKey.Role != OperatorRole {
+			continue
+		}
+		supplier, err := chain.supplier(importedKey.Address)
+		if err != nil {
+			return added, nil, err
+		}
+		if supplier == nil {
+			keyLog.Debug().Str("address", importedKey.Address).Msg("Operator key not staked, no supplier to sync")
+			continue
+		}
+
+		for _, service := range supplier.Services {
+			serviceId := service.ServiceId
+			if !slices.Contains(staked, serviceId) {
+				staked = append(staked, serviceId)
+			}
+			if !slices.ContainsFunc(relayMinerConfig.Suppliers, func(supplierConfig poktrollconfig.YAMLRelayMinerSupplierConfig) bool {
+				return supplierConfig.ServiceId == serviceId
+			}) {
+				relayMinerConfig.Suppliers = append(relayMinerConfig.Suppliers, poktrollconfig.YAMLRelayMinerSupplierConfig{
+					ServiceId: serviceId,
+					ListenUrl: listenUrl,
+					ServiceConfig: poktrollconfig.YAMLRelayMinerSupplierServiceConfig{
+						BackendUrl: strings.ReplaceAll(appConfig.OnchainSuppliersBackendUrl, "{service_id}", serviceId),
+					},
+					SigningKeyNames: []string{},
+				})
+				added = append(added, serviceId)
+				log.Info().Str("service_id", serviceId).Str("operator_address", importedKey.Address).Msg("Supplier added from chain")
+			}
+
+			// default signing keys already sign for every supplier
+			if len(importedKey.ServiceIds) == 0 || slices.Contains(importedKey.ServiceIds, serviceId) {
+				continue
+			}
+			if _, err := registerRelayMinerConfig(appConfig, importedKey.Name, serviceId, relayMinerConfig); err != nil {
+				return added, nil, err
+			}
+			importedKey.ServiceIds = append(importedKey.ServiceIds, serviceId)
+		}
+	}
+
+	removed := make([]string, 0)
+	if appConfig.OnchainSuppliers == OnchainSuppliersSync {
+		relayMinerConfig.Suppliers = slices.DeleteFunc(relayMinerConfig.Suppliers, func(supplierConfig poktrollconfig.YAMLRelayMinerSupplierConfig) bool {
+			if slices.Contains(staked, supplierConfig.ServiceId) {
+				return false
+			}
+			log.Warn().Str("service_id", supplierConfig.ServiceId).Msg("Supplier removed, no imported operator key is staked for it")
+			removed = append(removed, supplierConfig.ServiceId)
+			return true
+		})
+	}
+
+	log.Info().
+		Strs("staked_services", staked).
+		Int("added", len(added)).
+		Int("removed", len(removed)).
+		Msg("Suppliers synced from chain")
+	return added, removed, nil
+}
+
+// validateOnchainSuppliers checks the settings of the on-chain suppliers sync.
+func validateOnchainSuppliers(appConfig *AppConfig) error {
+	if appConfig.OnchainSuppliers != OnchainSuppliersOff && appConfig.OnchainSuppliers != OnchainSuppliersAdd && appConfig.OnchainSuppliers != OnchainSuppliersSync {
+		return fmt.Errorf("invalid on-chain suppliers mode: %s", appConfig.OnchainSuppliers)
+	}
+	if appConfig.OnchainSuppliers == OnchainSuppliersOff {
+		return nil
+	}
+	switch {
+	case appConfig.ChainGRPCUrl == "":
+		return fmt.Errorf("ONCHAIN_SUPPLIERS=%s requires CHAIN_GRPC_URL", appConfig.OnchainSuppliers)
+	case !appConfig.GenerateRelayMinerConfig:
+		return fmt.Errorf("ONCHAIN_SUPPLIERS=%s requires GENERATE_RELAYMINER_CONFIG", appConfig.OnchainSuppliers)
+	case appConfig.OnchainSuppliersBackendUrl == "" && !appConfig.BackendDiscovery:
+		return fmt.Errorf("ONCHAIN_SUPPLIERS=%s requires ONCHAIN_SUPPLIERS_BACKEND_URL or BACKEND_DISCOVERY", appConfig.OnchainSuppliers)
+	}
+	return nil
+}
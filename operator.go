@@ -0,0 +1,551 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// OperatorRunMode runs the loader as a Kubernetes operator reconciling WalletKeyImport resources.
+const OperatorRunMode string = "operator"
+
+// walletKeyImportResource is the WalletKeyImport custom resource (see deploy/crds).
+var walletKeyImportResource = schema.GroupVersionResource{
+	Group:    "keyring.pokt.network",
+	Version:  "v1alpha1",
+	Resource: "walletkeyimports",
+}
+
+// Status condition type and reasons of the resources reconciled by the operator
+const (
+	ReadyCondition        string = "Ready"
+	ReconciledReason      string = "Reconciled"
+	ReconcileFailedReason string = "ReconcileFailed"
+	InvalidSpecReason     string = "InvalidSpec"
+)
+
+const (
+	// operatorDefaultResync is how often every resource is reconciled when RESYNC_INTERVAL is unset.
+	operatorDefaultResync = 10 * time.Minute
+	// operatorMaxRetryDelay caps the backoff between retries of a failing resource.
+	operatorMaxRetryDelay = 5 * time.Minute
+)
+
+// ResourceKeyRef points at a key of a ConfigMap or Secret. The namespace is the WalletKeyImport's: the operator
+// acts with its own permissions, so a resource may only reference its own namespace.
+type ResourceKeyRef struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+}
+
+// WalletKeyImportKeyring are the keyring settings of a WalletKeyImport, empty fields keep the loader defaults.
+// Dir is relative to the operator's KEYRING_DIR.
+type WalletKeyImportKeyring struct {
+	Dir     string `json:"dir,omitempty"`
+	Backend string `json:"backend,omitempty"`
+	AppName string `json:"appName,omitempty"`
+}
+
+// WalletKeyImportRelayMinerConfig declares the base relay miner config and where the generated one is published.
+type WalletKeyImportRelayMinerConfig struct {
+	// Base is the ConfigMap holding the base relay miner config.
+	Base ResourceKeyRef `json:"base"`
+	// Target is configmap (default) or secret.
+	Target string         `json:"target,omitempty"`
+	Output ResourceKeyRef `json:"output"`
+}
+
+// WalletKeyImportSpec is the desired state of a WalletKeyImport.
+type WalletKeyImportSpec struct {
+	KeysSecret ResourceKeyRef         `json:"keysSecret"`
+	Keyring    WalletKeyImportKeyring `json:"keyring,omitempty"`
+	// RelayMinerConfig is optional, leaving it empty only imports the keys.
+	RelayMinerConfig *WalletKeyImportRelayMinerConfig `json:"relayMinerConfig,omitempty"`
+}
+
+// ReconcileStatus is the observed state of a resource reconciled by the operator.
+type ReconcileStatus struct {
+	ObservedGeneration int64          `json:"observedGeneration,omitempty"`
+	LastReconcileTime  *v1.Time       `json:"lastReconcileTime,omitempty"`
+	Conditions         []v1.Condition `json:"conditions,omitempty"`
+}
+
+// WalletKeyImport declares a keys Secret to import into a keyring and the relay miner config generated from it.
+type WalletKeyImport struct {
+	v1.TypeMeta   `json:",inline"`
+	v1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WalletKeyImportSpec `json:"spec"`
+	Status ReconcileStatus     `json:"status,omitempty"`
+}
+
+// orDefault returns value, or fallback when value is empty.
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// resourceNamespace returns the namespace of a reference of a resource in namespace, rejecting references to another
+// namespace: the operator reads and writes with its own permissions, which must not be lent to whoever can create
+// the resource (confused deputy).
+func resourceNamespace(field, reference, namespace string) (string, error) {
+	if reference != "" && reference != namespace {
+		return "", fmt.Errorf("%s must be empty or the namespace of the resource (%s), not %s", field, namespace, reference)
+	}
+	return namespace, nil
+}
+
+// resourceKeyringDir returns the keyring dir of a resource in namespace: dir relative to the namespace's subdirectory
+// of the operator's KeyringDir, which it can't escape, so resources of different namespaces never share a keyring.
+// An empty dir is the resource's own, named after it.
+func resourceKeyringDir(appConfig *AppConfig, field, namespace, name, dir string) (string, error) {
+	root := filepath.Join(appConfig.KeyringDir, namespace)
+	if dir == "" {
+		return filepath.Join(root, name), nil
+	}
+	if filepath.IsAbs(dir) || slices.Contains(strings.Split(filepath.ToSlash(dir), "/"), "..") {
+		return "", fmt.Errorf("%s must be a path relative to the operator keyring dir without '..', not %s", field, dir)
+	}
+	return filepath.Join(root, dir), nil
+}
+
+// resourceScope names the outputs of the passes of a custom resource, e.g. walletkeyimport.pocket.relayminer-1.
+// Namespaces can't hold dots, so two resources never share a scope.
+func resourceScope(kind, namespace, name string) string {
+	return strings.ToLower(kind) + "." + namespace + "." + name
+}
+
+// scopeOutputs makes the outputs a pass inherits from the operator's AppConfig (state, report and completion files,
+// config diff, stake and application configs, unsigned transactions) its own, from scope: the passes of different
+// resources would otherwise overwrite each other's, and plan/apply read another resource's state.
+func scopeOutputs(config, appConfig *AppConfig, scope string) {
+	config.StateFilePath = tenantPath(appConfig.StateFilePath, scope)
+	config.ReportFilePath = tenantPath(appConfig.ReportFilePath, scope)
+	config.CompletionFilePath = tenantPath(appConfig.CompletionFilePath, scope)
+	config.ReportConfigMapKey = strings.TrimSuffix(reportConfigMapKey(appConfig), ".json") + "." + scope + ".json"
+	config.RelayMinerConfigDiffOutputPath = tenantPath(appConfig.RelayMinerConfigDiffOutputPath, scope)
+	config.SupplierStakeConfigOutputDir = tenantDir(appConfig.SupplierStakeConfigOutputDir, scope)
+	config.ApplicationConfigOutputDir = tenantDir(appConfig.ApplicationConfigOutputDir, scope)
+	config.UnsignedTxOutputDir = tenantDir(appConfig.UnsignedTxOutputDir, scope)
+}
+
+// walletKeyImportConfig derives the AppConfig of a single pass from the operator's AppConfig and a WalletKeyImport spec.
+// Settings the resource doesn't declare (address prefix, diff, rollouts, ...) are inherited from the operator, its
+// outputs scoped to the resource. References are confined to the namespace of the resource and its keyring dir under
+// the operator's.
+func walletKeyImportConfig(appConfig *AppConfig, resource *WalletKeyImport) (*AppConfig, error) {
+	spec := resource.Spec
+	if spec.KeysSecret.Name == "" {
+		return nil, fmt.Errorf("spec.keysSecret.name is required")
+	}
+
+	config := *appConfig
+	config.ConfigSource = KubernetesSource
+
+	var err error
+	config.KeysNamespace, err = resourceNamespace("spec.keysSecret.namespace", spec.KeysSecret.Namespace, resource.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	config.KeysSecretName = spec.KeysSecret.Name
+	config.KeysSecretKey = orDefault(spec.KeysSecret.Key, appConfig.KeysSecretKey)
+
+	config.KeyringDir, err = resourceKeyringDir(appConfig, "spec.keyring.dir", resource.Namespace, resource.Name, spec.Keyring.Dir)
+	if err != nil {
+		return nil, err
+	}
+	config.KeyringBackend = orDefault(spec.Keyring.Backend, appConfig.KeyringBackend)
+	config.KeyringAppName = orDefault(spec.Keyring.AppName, appConfig.KeyringAppName)
+	scopeOutputs(&config, appConfig, resourceScope("WalletKeyImport", resource.Namespace, resource.Name))
+
+	config.GenerateRelayMinerConfig = spec.RelayMinerConfig != nil
+	if spec.RelayMinerConfig != nil {
+		relayMinerConfig := spec.RelayMinerConfig
+		if relayMinerConfig.Base.Name == "" || relayMinerConfig.Output.Name == "" {
+			return nil, fmt.Errorf("spec.relayMinerConfig.base.name and spec.relayMinerConfig.output.name are required")
+		}
+
+		config.RelayMinerConfigNamespace, err = resourceNamespace("spec.relayMinerConfig.base.namespace", relayMinerConfig.Base.Namespace, resource.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		config.RelayMinerConfigName = relayMinerConfig.Base.Name
+		config.RelayMinerConfigKey = orDefault(relayMinerConfig.Base.Key, appConfig.RelayMinerConfigKey)
+
+		config.RelayMinerConfigOutputTarget = orDefault(relayMinerConfig.Target, ConfigMapSource)
+		config.RelayMinerConfigOutputNamespace, err = resourceNamespace("spec.relayMinerConfig.output.namespace", relayMinerConfig.Output.Namespace, resource.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		config.RelayMinerConfigOutputName = relayMinerConfig.Output.Name
+		config.RelayMinerConfigOutputKey = orDefault(relayMinerConfig.Output.Key, appConfig.RelayMinerConfigOutputKey)
+		if config.RelayMinerConfigOutputTarget == FileSource {
+			return nil, fmt.Errorf("spec.relayMinerConfig.target must be configmap or secret")
+		}
+	}
+
+	if err := validateConfig(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// walletKeyImportReferences reports whether a WalletKeyImport reads the given ConfigMap or Secret.
+func walletKeyImportReferences(resource *WalletKeyImport, source, namespace, name string) bool {
+	spec := resource.Spec
+	if resource.Namespace != namespace {
+		return false
+	}
+	if source == SecretSource {
+		return spec.KeysSecret.Name == name
+	}
+	return spec.RelayMinerConfig != nil && spec.RelayMinerConfig.Base.Name == name
+}
+
+// decodeResource converts an informer object into the typed resource pointed to by into.
+func decodeResource(obj interface{}, into interface{}) error {
+	object, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object type: %T", obj)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, into); err != nil {
+		return fmt.Errorf("unable to decode %s: %w", object.GetKind(), err)
+	}
+	return nil
+}
+
+// operatorResource describes a custom resource kind reconciled by the operator.
+type operatorResource struct {
+	kind     string
+	resource schema.GroupVersionResource
+	// config derives the AppConfig of a pass from the operator's AppConfig and an object of this kind.
+	config func(appConfig *AppConfig, obj interface{}) (*AppConfig, error)
+	// references reports whether an object of this kind reads the given ConfigMap or Secret.
+	references func(obj interface{}, source, namespace, name string) bool
+	informer   cache.SharedIndexInformer
+}
+
+// operatorResources returns the custom resource kinds reconciled by the operator.
+func operatorResources() []*operatorResource {
+	return []*operatorResource{
+		{
+			kind:     "WalletKeyImport",
+			resource: walletKeyImportResource,
+			config: func(appConfig *AppConfig, obj interface{}) (*AppConfig, error) {
+				resource := &WalletKeyImport{}
+				if err := decodeResource(obj, resource); err != nil {
+					return nil, err
+				}
+				return walletKeyImportConfig(appConfig, resource)
+			},
+			references: func(obj interface{}, source, namespace, name string) bool {
+				resource := &WalletKeyImport{}
+				return decodeResource(obj, resource) == nil && walletKeyImportReferences(resource, source, namespace, name)
+			},
+		},
+		{
+			kind:     "RelayMinerConfigTemplate",
+			resource: relayMinerConfigTemplateResource,
+			config: func(appConfig *AppConfig, obj interface{}) (*AppConfig, error) {
+				resource := &RelayMinerConfigTemplate{}
+				if err := decodeResource(obj, resource); err != nil {
+					return nil, err
+				}
+				return relayMinerConfigTemplateConfig(appConfig, resource)
+			},
+			references: func(obj interface{}, source, namespace, name string) bool {
+				resource := &RelayMinerConfigTemplate{}
+				return decodeResource(obj, resource) == nil && relayMinerConfigTemplateReferences(resource, source, namespace, name)
+			},
+		},
+	}
+}
+
+// reconcileRequest identifies a resource queued for reconciliation.
+type reconcileRequest struct {
+	kind string
+	key  string
+}
+
+// operator reconciles the custom resources of operatorResources: every resource gets its keys imported into its
+// keyring and its configs generated, and reports the outcome in its status conditions.
+type operator struct {
+	appConfig *AppConfig
+	client    dynamic.Interface
+	resources map[string]*operatorResource
+	queue     workqueue.RateLimitingInterface
+}
+
+// enqueue schedules the reconciliation of a resource.
+func (o *operator) enqueue(kind string, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Error().Err(err).Str("kind", kind).Msg("Unable to compute resource key")
+		return
+	}
+	o.queue.Add(reconcileRequest{kind: kind, key: key})
+}
+
+// enqueueReferencing schedules the reconciliation of every resource reading the given ConfigMap or Secret.
+func (o *operator) enqueueReferencing(source string, obj interface{}) {
+	object, ok := obj.(v1.Object)
+	if !ok {
+		return
+	}
+	for kind, resource := range o.resources {
+		for _, item := range resource.informer.GetStore().List() {
+			if !resource.references(item, source, object.GetNamespace(), object.GetName()) {
+				continue
+			}
+			log.Debug().
+				Str("source", source).
+				Str("namespace", object.GetNamespace()).
+				Str("name", object.GetName()).
+				Str("kind", kind).
+				Msg("Referenced resource changed")
+			o.enqueue(kind, item)
+		}
+	}
+}
+
+// reconcile runs a pass for the resource behind request and records the outcome in its status.
+// Returns the error of the pass so the resource is retried with backoff.
+func (o *operator) reconcile(ctx context.Context, request reconcileRequest) error {
+	resource := o.resources[request.kind]
+	obj, exists, err := resource.informer.GetIndexer().GetByKey(request.key)
+	if err != nil {
+		return fmt.Errorf("error fetching %s %s: %w", request.kind, request.key, err)
+	}
+	if !exists {
+		log.Info().
+			Str("kind", request.kind).
+			Str("resource", request.key).
+			Msg("Resource deleted, keeping the last generated state")
+		return nil
+	}
+
+	object := obj.(*unstructured.Unstructured)
+	status := ReconcileStatus{}
+	if content, ok := object.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &status); err != nil {
+			log.Warn().Err(err).Str("resource", request.key).Msg("Unable to decode resource status, resetting it")
+		}
+	}
+
+	condition := v1.Condition{
+		Type:               ReadyCondition,
+		Status:             v1.ConditionTrue,
+		ObservedGeneration: object.GetGeneration(),
+		Reason:             ReconciledReason,
+		Message:            "keys imported and configs generated",
+	}
+
+	log.Info().Str("kind", request.kind).Str("resource", request.key).Msg("Reconciling resource")
+	config, runErr := resource.config(o.appConfig, obj)
+	if runErr != nil {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = InvalidSpecReason
+		condition.Message = runErr.Error()
+	} else if runErr = run(config); runErr != nil {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = ReconcileFailedReason
+		condition.Message = runErr.Error()
+	}
+
+	now := v1.Now()
+	status.ObservedGeneration = object.GetGeneration()
+	status.LastReconcileTime = &now
+	meta.SetStatusCondition(&status.Conditions, condition)
+	if err := o.updateStatus(ctx, resource.resource, object, status); err != nil {
+		log.Error().Err(err).Str("kind", request.kind).Str("resource", request.key).Msg("Failed to update resource status")
+		if runErr == nil {
+			return err
+		}
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("error reconciling %s %s: %w", request.kind, request.key, runErr)
+	}
+	log.Info().Str("kind", request.kind).Str("resource", request.key).Msg("Resource reconciled")
+	return nil
+}
+
+// updateStatus writes the status subresource of a resource.
+func (o *operator) updateStatus(ctx context.Context, gvr schema.GroupVersionResource, object *unstructured.Unstructured, status ReconcileStatus) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return fmt.Errorf("unable to encode %s status: %w", object.GetKind(), err)
+	}
+
+	updated := object.DeepCopy()
+	updated.Object["status"] = content
+	_, err = o.client.Resource(gvr).
+		Namespace(updated.GetNamespace()).
+		UpdateStatus(ctx, updated, v1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error updating %s %s/%s status: %w", object.GetKind(), updated.GetNamespace(), updated.GetName(), err)
+	}
+	return nil
+}
+
+// processNextItem reconciles the next queued resource. Returns false once the queue is shut down.
+func (o *operator) processNextItem(ctx context.Context) bool {
+	item, shutdown := o.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer o.queue.Done(item)
+
+	request := item.(reconcileRequest)
+	if err := o.reconcile(ctx, request); err != nil {
+		log.Error().Err(err).Str("kind", request.kind).Str("resource", request.key).Msg("Reconcile failed, retrying")
+		o.queue.AddRateLimited(request)
+		return true
+	}
+	o.queue.Forget(request)
+	return true
+}
+
+// operate runs the loader as a Kubernetes operator: it watches its custom resources (in OperatorNamespace, or
+// cluster-wide when empty) and the Secrets/ConfigMaps they reference, reconciling them one at a time.
+// Every resource is also reconciled each ResyncInterval (10m by default), healing drift in the keyrings.
+func operate(appConfig *AppConfig) error {
+	ctx, stop := shutdownContext()
+	defer stop()
+	dumpStateOnSignal(ctx, appConfig)
+	defer servePprof(appConfig)()
+
+	client, err := newDynamicClient()
+	if err != nil {
+		return err
+	}
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	resync := appConfig.ResyncInterval
+	if resync == 0 {
+		resync = operatorDefaultResync
+	}
+
+	o := &operator{
+		appConfig: appConfig,
+		client:    client,
+		resources: make(map[string]*operatorResource),
+		queue:     workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Second, operatorMaxRetryDelay)),
+	}
+	defer o.queue.ShutDown()
+
+	dynamicFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resync, appConfig.OperatorNamespace, nil)
+	synced := make([]cache.InformerSynced, 0)
+	for _, resource := range operatorResources() {
+		kind := resource.kind
+		resource.informer = dynamicFactory.ForResource(resource.resource).Informer()
+		o.resources[kind] = resource
+
+		_, err = resource.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				o.enqueue(kind, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldMeta, oldOk := oldObj.(v1.Object)
+				newMeta, newOk := newObj.(v1.Object)
+				// status updates don't bump the generation, skip them to avoid reconciling our own writes
+				if oldOk && newOk &&
+					oldMeta.GetResourceVersion() != newMeta.GetResourceVersion() &&
+					oldMeta.GetGeneration() == newMeta.GetGeneration() {
+					return
+				}
+				o.enqueue(kind, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				o.enqueue(kind, obj)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error watching %ss: %w", kind, err)
+		}
+		synced = append(synced, resource.informer.HasSynced)
+
+		log.Info().
+			Str("namespace", appConfig.OperatorNamespace).
+			Str("resource", resource.resource.String()).
+			Msg("Watching custom resources")
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(appConfig.OperatorNamespace))
+	references := map[string]cache.SharedIndexInformer{
+		SecretSource:    factory.Core().V1().Secrets().Informer(),
+		ConfigMapSource: factory.Core().V1().ConfigMaps().Informer(),
+	}
+	for source, informer := range references {
+		source := source
+		_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldMeta, oldOk := oldObj.(v1.Object)
+				newMeta, newOk := newObj.(v1.Object)
+				if oldOk && newOk && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+					return
+				}
+				o.enqueueReferencing(source, newObj)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error watching %ss: %w", source, err)
+		}
+		synced = append(synced, informer.HasSynced)
+	}
+
+	log.Info().Dur("resync_interval", resync).Msg("Starting operator")
+
+	dynamicFactory.Start(ctx.Done())
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		if ctx.Err() != nil {
+			log.Info().Msg("Shutdown requested, exiting")
+			return nil
+		}
+		return fmt.Errorf("error waiting for operator caches to sync")
+	}
+
+	// reconcile one resource at a time, passes share the process-wide SDK configuration; the in-flight reconcile
+	// isn't canceled on shutdown, so its status update still lands
+	done := make(chan error, 1)
+	go func() {
+		for o.processNextItem(context.WithoutCancel(ctx)) {
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// the worker finishes its current item, then the shut down queue stops it
+		o.queue.ShutDown()
+		if err := waitInFlight(appConfig, done); err != nil {
+			return err
+		}
+		log.Info().Msg("Shutdown completed")
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResourceKeyringDir(t *testing.T) {
+	appConfig := &AppConfig{KeyringDir: "/keyrings"}
+	tests := []struct {
+		dir     string
+		want    string
+		wantErr bool
+	}{
+		{dir: "", want: "/keyrings/pocket/relayminer-1"},
+		{dir: "shared", want: "/keyrings/pocket/shared"},
+		{dir: "team/relayminer-2", want: "/keyrings/pocket/team/relayminer-2"},
+		{dir: "/home/pocket/.pocket", wantErr: true},
+		{dir: "../other", wantErr: true},
+		{dir: "relayminer-1/../../etc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got, err := resourceKeyringDir(appConfig, "spec.keyring.dir", "pocket", "relayminer-1", tt.dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("resourceKeyringDir(%q) = %q, want an error", tt.dir, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resourceKeyringDir(%q): %v", tt.dir, err)
+			}
+			if got != tt.want {
+				t.Errorf("resourceKeyringDir(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeOutputs(t *testing.T) {
+	appConfig := &AppConfig{
+		StateFilePath:                  "/data/state.json",
+		ReportFilePath:                 "/data/report.json",
+		ReportConfigMapKey:             "operator.json",
+		SupplierStakeConfigOutputDir:   "/data/stakes",
+		RelayMinerConfigDiffOutputPath: "",
+	}
+	config := *appConfig
+	scopeOutputs(&config, appConfig, resourceScope("WalletKeyImport", "pocket", "relayminer-1"))
+
+	want := AppConfig{
+		StateFilePath:                "/data/state.walletkeyimport.pocket.relayminer-1.json",
+		ReportFilePath:               "/data/report.walletkeyimport.pocket.relayminer-1.json",
+		ReportConfigMapKey:           "operator.walletkeyimport.pocket.relayminer-1.json",
+		SupplierStakeConfigOutputDir: "/data/stakes/walletkeyimport.pocket.relayminer-1",
+	}
+	if config.StateFilePath != want.StateFilePath ||
+		config.ReportFilePath != want.ReportFilePath ||
+		config.ReportConfigMapKey != want.ReportConfigMapKey ||
+		config.SupplierStakeConfigOutputDir != want.SupplierStakeConfigOutputDir ||
+		config.RelayMinerConfigDiffOutputPath != "" || config.CompletionFilePath != "" {
+		t.Errorf("scopeOutputs() = %+v, want %+v", config, want)
+	}
+}
+
+func TestWalletKeyImportConfig(t *testing.T) {
+	t.Setenv("RUN_MODE", OperatorRunMode)
+	t.Setenv("KEYRING_DIR", "/keyrings")
+	appConfig, err := loadAppConfig()
+	if err != nil {
+		t.Fatalf("loadAppConfig: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    WalletKeyImportSpec
+		wantErr bool
+	}{
+		{
+			name: "own namespace",
+			spec: WalletKeyImportSpec{
+				KeysSecret: ResourceKeyRef{Name: "keys"},
+				Keyring:    WalletKeyImportKeyring{Dir: "relayminer-1"},
+				RelayMinerConfig: &WalletKeyImportRelayMinerConfig{
+					Base:   ResourceKeyRef{Namespace: "pocket", Name: "base"},
+					Output: ResourceKeyRef{Name: "generated"},
+				},
+			},
+		},
+		{
+			name:    "keys secret of another namespace",
+			spec:    WalletKeyImportSpec{KeysSecret: ResourceKeyRef{Namespace: "kube-system", Name: "keys"}},
+			wantErr: true,
+		},
+		{
+			name: "base config of another namespace",
+			spec: WalletKeyImportSpec{
+				KeysSecret: ResourceKeyRef{Name: "keys"},
+				RelayMinerConfig: &WalletKeyImportRelayMinerConfig{
+					Base:   ResourceKeyRef{Namespace: "other", Name: "base"},
+					Output: ResourceKeyRef{Name: "generated"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "output of another namespace",
+			spec: WalletKeyImportSpec{
+				KeysSecret: ResourceKeyRef{Name: "keys"},
+				RelayMinerConfig: &WalletKeyImportRelayMinerConfig{
+					Base:   ResourceKeyRef{Name: "base"},
+					Output: ResourceKeyRef{Namespace: "other", Name: "generated"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "keyring dir outside of the operator's",
+			spec: WalletKeyImportSpec{
+				KeysSecret: ResourceKeyRef{Name: "keys"},
+				Keyring:    WalletKeyImportKeyring{Dir: "/root/.pocket"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &WalletKeyImport{ObjectMeta: v1.ObjectMeta{Name: "relayminer-1", Namespace: "pocket"}, Spec: tt.spec}
+			config, err := walletKeyImportConfig(appConfig, resource)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("walletKeyImportConfig succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("walletKeyImportConfig: %v", err)
+			}
+			if config.KeysNamespace != "pocket" || config.RelayMinerConfigNamespace != "pocket" || config.RelayMinerConfigOutputNamespace != "pocket" {
+				t.Errorf("namespaces = %s, %s, %s, want pocket", config.KeysNamespace, config.RelayMinerConfigNamespace, config.RelayMinerConfigOutputNamespace)
+			}
+			if config.KeyringDir != "/keyrings/pocket/relayminer-1" {
+				t.Errorf("KeyringDir = %s, want /keyrings/pocket/relayminer-1", config.KeyringDir)
+			}
+		})
+	}
+}
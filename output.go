@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats for the generated relay miner config
+const (
+	YAMLOutputFormat string = "yaml"
+	JSONOutputFormat string = "json"
+)
+
+// backupTimeFormat is used in backup file names; it sorts lexically in chronological order.
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// marshalRelayMinerConfig serializes the relay miner config in the given format.
+// JSON output keeps the YAML field names, since those are the ones the relay miner reads.
+func marshalRelayMinerConfig(format string, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]byte, error) {
+	content, err := yaml.Marshal(relayMinerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case YAMLOutputFormat:
+		return content, nil
+	case JSONOutputFormat:
+		var document interface{}
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(jsonCompatible(document), "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// jsonCompatible converts the map[interface{}]interface{} values produced by yaml.v2 into
+// map[string]interface{} values that encoding/json is able to marshal.
+func jsonCompatible(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range value {
+			value[i] = jsonCompatible(item)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// backupRelayMinerConfig copies the config at outputPath to `<output>.<timestamp>.bak` before it is overwritten
+// with updatedContent and removes the oldest backups beyond RelayMinerConfigBackups. previousPath is where the last
+// pass published the config (see previousOutputPath). Nothing is done when backups are disabled, no previous output
+// exists yet, it already holds updatedContent (so unchanged passes don't rotate the meaningful backups out), or it
+// lives at another path than outputPath, as with templated paths, and isn't overwritten at all.
+func backupRelayMinerConfig(appConfig *AppConfig, previousPath, outputPath string, updatedContent []byte) error {
+	if appConfig.RelayMinerConfigBackups <= 0 {
+		return nil
+	}
+	if previousPath != outputPath {
+		log.Debug().Str("previous_path", previousPath).Str("path", outputPath).Msg("Config written to a new path, previous one kept as is")
+		return nil
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debug().Str("path", outputPath).Msg("No previous config to back up")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read config for backup: %w", err)
+	}
+	if bytes.Equal(content, updatedContent) {
+		log.Debug().Str("path", outputPath).Msg("Config unchanged, not backed up")
+		return nil
+	}
+
+	fileInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to get config file info for backup: %w", err)
+	}
+
+	content, err = encryptExport(appConfig, content)
+	if err != nil {
+		return err
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", outputPath, time.Now().UTC().Format(backupTimeFormat))
+	if err := os.WriteFile(backupPath, content, fileInfo.Mode()); err != nil {
+		return fmt.Errorf("unable to write config backup: %w", err)
+	}
+	log.Info().Str("path", backupPath).Msg("Relay miner configuration backed up")
+
+	backups, err := filepath.Glob(globQuoteMeta(outputPath) + ".*.bak")
+	if err != nil {
+		return fmt.Errorf("unable to list config backups: %w", err)
+	}
+	sort.Strings(backups)
+
+	for len(backups) > appConfig.RelayMinerConfigBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("unable to remove old config backup: %w", err)
+		}
+		log.Debug().Str("path", backups[0]).Msg("Removed old relay miner configuration backup")
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// globQuoteMeta escapes the glob metacharacters of path, so it only matches itself in a filepath.Glob pattern.
+func globQuoteMeta(path string) string {
+	var quoted strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			quoted.WriteRune('\\')
+		}
+		quoted.WriteRune(r)
+	}
+	return quoted.String()
+}
+
+// mkdirAllMode creates dir and its missing parents with mode whatever the umask, leaving existing directories alone.
+func mkdirAllMode(dir string, mode os.FileMode) error {
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		if err := mkdirAllMode(parent, mode); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(dir, mode); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return os.Chmod(dir, mode)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, fsyncs it and renames it into place,
+// so readers never observe a partially written file even if the process crashes mid-write.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// clean up the temporary file unless it was renamed into place
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// persist the rename itself
+	return syncDir(dir)
+}
+
+// chownPath changes the owner of path (and everything below it when recursive) to uid/gid.
+// A value of -1 leaves the corresponding id unchanged; when both are -1 nothing is done.
+func chownPath(path string, uid, gid int, recursive bool) error {
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	if !ownershipSupported {
+		log.Warn().Str("path", path).Msg("OUTPUT_UID/OUTPUT_GID are not supported on this platform, owner left unchanged")
+		return nil
+	}
+
+	log.Debug().
+		Str("path", path).
+		Int("uid", uid).
+		Int("gid", gid).
+		Bool("recursive", recursive).
+		Msg("Changing owner")
+
+	if !recursive {
+		return os.Chown(path, uid, gid)
+	}
+
+	return filepath.WalkDir(path, func(p string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, uid, gid)
+	})
+}
+
+// OutputPathVars are the variables available to RELAYMINER_CONFIG_FILE_OUTPUT_PATH templates,
+// e.g. `/shared/{{.Namespace}}/{{.PodName}}/config.yaml`.
+type OutputPathVars struct {
+	// PodName is POD_NAME (as set through the Downward API) or the hostname.
+	PodName string
+	// Namespace is POD_NAMESPACE or the namespace of the pod service account.
+	Namespace string
+	// Timestamp is the UTC generation time formatted as 20060102T150405Z.
+	Timestamp string
+	// ConfigHash is the SHA-256 hex digest of the generated config.
+	ConfigHash string
+	// ShortConfigHash is the first 12 characters of ConfigHash.
+	ShortConfigHash string
+}
+
+// serviceAccountNamespacePath is where Kubernetes mounts the namespace of the pod service account.
+const serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// contentHash returns the SHA-256 hex digest of data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// podNamespace returns the namespace the process runs in, from POD_NAMESPACE or the service account.
+// Returns an empty string when it cannot be determined (e.g. outside Kubernetes).
+func podNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile(serviceAccountNamespacePath); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// podName returns the name of the pod the process runs in, from POD_NAME (Downward API) or the hostname.
+func podName() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// renderOutputPath resolves the template variables of the output path for the given generated content.
+// Paths without template actions are returned unchanged.
+func renderOutputPath(pathTemplate string, content []byte) (string, error) {
+	if !strings.Contains(pathTemplate, "{{") {
+		return pathTemplate, nil
+	}
+
+	tmpl, err := template.New("output_path").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path template: %w", err)
+	}
+
+	hash := contentHash(content)
+	vars := OutputPathVars{
+		PodName:         podName(),
+		Namespace:       podNamespace(),
+		Timestamp:       time.Now().UTC().Format("20060102T150405Z"),
+		ConfigHash:      hash,
+		ShortConfigHash: hash[:12],
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, vars); err != nil {
+		return "", fmt.Errorf("unable to render output path template: %w", err)
+	}
+
+	log.Debug().
+		Str("template", pathTemplate).
+		Str("path", rendered.String()).
+		Msg("Rendered output path")
+	return rendered.String(), nil
+}
+
+// outputPathVaries reports whether the output path template renders to a new path on every generation, through
+// {{.Timestamp}} or the config hash.
+func outputPathVaries(pathTemplate string) bool {
+	return strings.Contains(pathTemplate, ".Timestamp") || strings.Contains(pathTemplate, "ConfigHash")
+}
+
+// previousOutputPath returns the file holding the relay miner config published by the last pass: the path recorded
+// in the state file, or the rendered output path when it doesn't vary per generation. Empty when it is unknown, e.g.
+// for a templated path without a state file.
+func previousOutputPath(appConfig *AppConfig) (string, error) {
+	if appConfig.StateFilePath != "" {
+		state, err := loadState(appConfig.StateFilePath)
+		if err != nil {
+			return "", err
+		}
+		if state != nil && state.ConfigOutputPath != "" {
+			return state.ConfigOutputPath, nil
+		}
+	}
+	if outputPathVaries(appConfig.RelayMinerConfigFileOutputPath) {
+		return "", nil
+	}
+	return renderOutputPath(appConfig.RelayMinerConfigFileOutputPath, nil)
+}
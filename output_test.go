@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobQuoteMeta(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/config/relayminer.yaml", want: "/config/relayminer.yaml"},
+		{path: "/config/[eu]/relayminer.yaml", want: `/config/\[eu]/relayminer.yaml`},
+		{path: "/config/*/relayminer?.yaml", want: `/config/\*/relayminer\?.yaml`},
+		{path: `/config/a\b.yaml`, want: `/config/a\\b.yaml`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := globQuoteMeta(tt.path); got != tt.want {
+				t.Errorf("globQuoteMeta(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupRelayMinerConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "[eu]")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(dir, "relayminer.yaml")
+	if err := os.WriteFile(outputPath, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appConfig := &AppConfig{RelayMinerConfigBackups: 2}
+
+	steps := []struct {
+		name    string
+		content string
+		backups int
+	}{
+		{name: "unchanged", content: "v1", backups: 0},
+		{name: "changed", content: "v2", backups: 1},
+		{name: "unchanged again", content: "v2", backups: 1},
+		{name: "changed again", content: "v3", backups: 2},
+		{name: "rotated", content: "v4", backups: 2},
+	}
+	for _, step := range steps {
+		if err := backupRelayMinerConfig(appConfig, outputPath, outputPath, []byte(step.content)); err != nil {
+			t.Fatalf("%s: backupRelayMinerConfig: %v", step.name, err)
+		}
+		if err := os.WriteFile(outputPath, []byte(step.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		backups, err := filepath.Glob(globQuoteMeta(outputPath) + ".*.bak")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backups) != step.backups {
+			t.Errorf("%s: %d backups, want %d", step.name, len(backups), step.backups)
+		}
+	}
+
+	// a templated path writes the next generation elsewhere, leaving the previous one as is
+	if err := backupRelayMinerConfig(appConfig, outputPath, filepath.Join(dir, "relayminer-v5.yaml"), []byte("v5")); err != nil {
+		t.Fatalf("new path: backupRelayMinerConfig: %v", err)
+	}
+	if backups, _ := filepath.Glob(globQuoteMeta(outputPath) + ".*.bak"); len(backups) != 2 {
+		t.Errorf("new path: %d backups, want 2", len(backups))
+	}
+}
+
+func TestRenderOutputPath(t *testing.T) {
+	t.Setenv("POD_NAME", "relayminer-0")
+	t.Setenv("POD_NAMESPACE", "relayminers")
+
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{template: "/config/relayminer.yaml", want: "/config/relayminer.yaml"},
+		{template: "/shared/{{.Namespace}}/{{.PodName}}/config.yaml", want: "/shared/relayminers/relayminer-0/config.yaml"},
+		{template: "/shared/config-{{.ShortConfigHash}}.yaml", want: "/shared/config-" + contentHash([]byte("v1"))[:12] + ".yaml"},
+		{template: "/shared/{{.ConfigHash}}.yaml", want: "/shared/" + contentHash([]byte("v1")) + ".yaml"},
+		{template: "/shared/{{.Unknown}}.yaml", wantErr: true},
+		{template: "/shared/{{.PodName", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := renderOutputPath(tt.template, []byte("v1"))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("renderOutputPath(%q) = %q, want an error", tt.template, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderOutputPath(%q): %v", tt.template, err)
+			}
+			if got != tt.want {
+				t.Errorf("renderOutputPath(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousOutputPath(t *testing.T) {
+	t.Setenv("POD_NAME", "relayminer-0")
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+
+	tests := []struct {
+		name     string
+		template string
+		state    string
+		want     string
+	}{
+		{name: "stable path", template: "/shared/{{.PodName}}.yaml", want: "/shared/relayminer-0.yaml"},
+		{name: "templated path without state", template: "/shared/{{.Timestamp}}.yaml", want: ""},
+		{name: "templated path without recorded path", template: "/shared/{{.ShortConfigHash}}.yaml", state: `{}`, want: ""},
+		{
+			name:     "recorded path",
+			template: "/shared/{{.Timestamp}}.yaml",
+			state:    `{"config_output_path":"/shared/20260101T000000Z.yaml"}`,
+			want:     "/shared/20260101T000000Z.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Remove(statePath)
+			if tt.state != "" {
+				if err := os.WriteFile(statePath, []byte(tt.state), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			appConfig := &AppConfig{RelayMinerConfigFileOutputPath: tt.template, StateFilePath: statePath}
+			got, err := previousOutputPath(appConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("previousOutputPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
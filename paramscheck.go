@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the on-chain params check
+const (
+	ParamsCheckOff  string = "off"
+	ParamsCheckWarn string = "warn"
+	ParamsCheckFail string = "fail"
+)
+
+// ParamViolation is a value of the generated stake configs or transactions the chain would reject under its current
+// module params, Name and Address being the key it applies to (absent for settings shared by every key).
+type ParamViolation struct {
+	// Param is the module param, e.g. supplier.min_stake.
+	Param   string `json:"param"`
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+	Value   string `json:"value"`
+	Limit   string `json:"limit"`
+}
+
+// checkChainParams fetches the params of the supplier and application modules and returns the values the chain would
+// reject: a SUPPLIER_STAKE_AMOUNT below supplier.min_stake (or in another denom) for the stake configs and
+// SUPPLIER_AUTO_STAKE, and application keys with more gateway_addresses than application.max_delegated_gateways.
+// The params are only fetched when keys depend on them. Reported with a warning, or failing the pass in
+// ParamsCheckFail.
+func checkChainParams(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey) ([]ParamViolation, error) {
+	if appConfig.ParamsCheck == ParamsCheckOff {
+		return nil, nil
+	}
+
+	staking := false
+	delegating := false
+	for _, importedKey := range importedKeys {
+		switch {
+		case importedKey.Role == OperatorRole && len(importedKey.ServiceIds) > 0:
+			staking = staking || appConfig.SupplierStakeAmount != ""
+		case importedKey.Role == ApplicationRole && len(importedKey.GatewayAddresses) > 0:
+			delegating = true
+		}
+	}
+
+	violations := make([]ParamViolation, 0)
+	if staking {
+		supplierParams, err := chain.supplierParams()
+		if err != nil {
+			return nil, err
+		}
+		stakeAmount, err := sdk.ParseCoinNormalized(appConfig.SupplierStakeAmount)
+		if err != nil {
+			return nil, withExitCode(ExitConfigError, fmt.Errorf("invalid supplier stake amount %s: %w", appConfig.SupplierStakeAmount, err))
+		}
+		minStake := supplierParams.MinStake
+		if minStake != nil && (stakeAmount.Denom != minStake.Denom || stakeAmount.IsLT(*minStake)) {
+			log.Warn().
+				Str("stake_amount", stakeAmount.String()).
+				Str("min_stake", minStake.String()).
+				Msg("Supplier stake amount below the minimum stake of the chain")
+			violations = append(violations, ParamViolation{Param: "supplier.min_stake", Value: stakeAmount.String(), Limit: minStake.String()})
+		}
+	}
+
+	if delegating {
+		applicationParams, err := chain.applicationParams()
+		if err != nil {
+			return nil, err
+		}
+		maxGateways := applicationParams.MaxDelegatedGateways
+		for _, importedKey := range importedKeys {
+			if importedKey.Role != ApplicationRole || uint64(len(importedKey.GatewayAddresses)) <= maxGateways {
+				continue
+			}
+			keyLog.Warn().
+				Str("name", importedKey.Name).
+				Str("address", importedKey.Address).
+				Int("gateways", len(importedKey.GatewayAddresses)).
+				Uint64("max_delegated_gateways", maxGateways).
+				Msg("Application key delegated to more gateways than the chain allows")
+			violations = append(violations, ParamViolation{
+				Param:   "application.max_delegated_gateways",
+				Name:    importedKey.Name,
+				Address: importedKey.Address,
+				Value:   strconv.Itoa(len(importedKey.GatewayAddresses)),
+				Limit:   strconv.FormatUint(maxGateways, 10),
+			})
+		}
+	}
+
+	if len(violations) == 0 {
+		if staking || delegating {
+			log.Info().Msg("Generated configs within the params of the chain")
+		}
+		return violations, nil
+	}
+	if appConfig.ParamsCheck == ParamsCheckFail {
+		return violations, withExitCode(ExitValidationError, fmt.Errorf("%w: %d values", ErrParamViolation, len(violations)))
+	}
+	log.Warn().Int("violations", len(violations)).Msg("Generated configs outside the params of the chain")
+	return violations, nil
+}
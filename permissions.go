@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Modes for key material with too open permissions
+const (
+	// PermissionsFail refuses to run the pass.
+	PermissionsFail string = "fail"
+	// PermissionsFix tightens the permissions, warning about it.
+	PermissionsFix string = "fix"
+	// PermissionsOff doesn't check them.
+	PermissionsOff string = "off"
+)
+
+// stateFileMode is the most open mode the state file may have.
+const stateFileMode os.FileMode = 0600
+
+// openPermission is a path whose mode is more open than its limit.
+type openPermission struct {
+	Path  string
+	Mode  os.FileMode
+	Limit os.FileMode
+}
+
+// String implements fmt.Stringer, e.g. `/keyring/keyring-test (0755, at most 0700)`.
+func (p openPermission) String() string {
+	return fmt.Sprintf("%s (%#o, at most %#o)", p.Path, p.Mode.Perm(), p.Limit)
+}
+
+// openPermissions lists the keyring directories and files, and the state file, more open than KEYRING_DIR_MODE
+// (without the execute bits for files) and 0600. Missing paths are fine, the pass creates them.
+func openPermissions(appConfig *AppConfig) ([]openPermission, error) {
+	open := make([]openPermission, 0)
+	check := func(path string, mode, limit os.FileMode) {
+		if mode.Perm()&^limit != 0 {
+			open = append(open, openPermission{Path: path, Mode: mode, Limit: limit})
+		}
+	}
+
+	if keyringDirBackends[appConfig.KeyringBackend] {
+		fileLimit := appConfig.KeyringDirMode &^ 0111
+		err := filepath.WalkDir(appConfig.KeyringDir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				check(path, info.Mode(), appConfig.KeyringDirMode)
+			} else if info.Mode().IsRegular() {
+				check(path, info.Mode(), fileLimit)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("unable to review keyring dir permissions: %w", err)
+		}
+	}
+
+	if appConfig.StateFilePath != "" {
+		info, err := os.Stat(appConfig.StateFilePath)
+		if err == nil {
+			check(appConfig.StateFilePath, info.Mode(), stateFileMode)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("unable to review state file permissions: %w", err)
+		}
+	}
+	return open, nil
+}
+
+// checkPermissions refuses to run a pass over key material other users can read, or tightens its permissions in
+// PERMISSIONS_CHECK=fix. Windows only has a read-only attribute, nothing is checked there.
+func checkPermissions(appConfig *AppConfig) error {
+	if appConfig.PermissionsCheck == PermissionsOff || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	open, err := openPermissions(appConfig)
+	if err != nil || len(open) == 0 {
+		return err
+	}
+
+	if appConfig.PermissionsCheck == PermissionsFail {
+		paths := make([]string, 0, len(open))
+		for _, permission := range open {
+			paths = append(paths, permission.String())
+		}
+		return fmt.Errorf("permissions too open: %s", strings.Join(paths, ", "))
+	}
+
+	for _, permission := range open {
+		mode := permission.Mode.Perm() & permission.Limit
+		if err := os.Chmod(permission.Path, mode); err != nil {
+			return fmt.Errorf("unable to tighten permissions of '%s': %w", permission.Path, err)
+		}
+		log.Warn().
+			Str("path", permission.Path).
+			Str("mode", fmt.Sprintf("%#o", permission.Mode.Perm())).
+			Str("fixed_mode", fmt.Sprintf("%#o", mode)).
+			Msg("Permissions too open, tightened")
+	}
+	return nil
+}
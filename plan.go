@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Subcommands of the loader
+const (
+	// PlanCommand computes the changes a pass would make and writes them as a machine-readable plan.
+	PlanCommand string = "plan"
+	// ApplyCommand executes a plan previously written by PlanCommand.
+	ApplyCommand string = "apply"
+)
+
+// PlannedKey is a key added to or removed from the keyring by a plan.
+type PlannedKey struct {
+	Name       string   `json:"name"`
+	Address    string   `json:"address"`
+	Role       string   `json:"role,omitempty"`
+	ServiceIds []string `json:"service_ids,omitempty"`
+}
+
+// Plan is the changeset of a pass: the keys to add to and remove from the keyring and the relay miner config mutations.
+// Plans carry no key material, so they can be reviewed and stored as automation artifacts.
+type Plan struct {
+	CreatedAt     time.Time      `json:"created_at"`
+	KeysToAdd     []PlannedKey   `json:"keys_to_add"`
+	KeysToRemove  []PlannedKey   `json:"keys_to_remove"`
+	ConfigChanges []ConfigChange `json:"config_changes"`
+	// ConfigHash is the SHA-256 of the relay miner config the plan generates (empty when generation is disabled).
+	ConfigHash string `json:"config_hash,omitempty"`
+	// Fingerprint identifies the changeset, apply refuses plans whose fingerprint no longer matches the sources.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// fingerprint returns the hash of the changeset, ignoring when the plan was created.
+func (p Plan) fingerprint() (string, error) {
+	p.CreatedAt = time.Time{}
+	p.Fingerprint = ""
+	content, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal plan: %w", err)
+	}
+	return contentHash(content), nil
+}
+
+// readRelayMinerConfigOutput returns the currently published relay miner config, or nil when nothing was published
+// yet (or its path is unknown, see previousOutputPath).
+func readRelayMinerConfigOutput(appConfig *AppConfig) ([]byte, error) {
+	if appConfig.RelayMinerConfigOutputTarget != FileSource {
+		return readRelayMinerConfigResource(appConfig)
+	}
+
+	outputPath, err := previousOutputPath(appConfig)
+	if err != nil || outputPath == "" {
+		return nil, err
+	}
+	content, err := os.ReadFile(outputPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read relay miner config output '%s': %w", outputPath, err)
+	}
+	return content, nil
+}
+
+// recordedAddresses returns the addresses of the keys the last pass recorded as imported in the state file, none
+// without a state file.
+func recordedAddresses(appConfig *AppConfig) (map[string]bool, error) {
+	addresses := make(map[string]bool)
+	if appConfig.StateFilePath == "" {
+		return addresses, nil
+	}
+	state, err := loadState(appConfig.StateFilePath)
+	if err != nil || state == nil {
+		return addresses, err
+	}
+	for _, key := range state.Keys {
+		addresses[key.Address] = true
+	}
+	return addresses, nil
+}
+
+// disabledAddresses returns the addresses of the keys of the disabled entries, which are kept in the keyring.
+func disabledAddresses(keys []WalletKeySpec) (map[string]bool, error) {
+	addresses := make(map[string]bool)
+	for _, i := range disabledEntries(keys) {
+		privKeys, _, err := entryPrivateKeys(keys[i])
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		for _, privKey := range privKeys {
+			addresses[sdk.AccAddress(privKey.PubKey().Address()).String()] = true
+		}
+	}
+	return addresses, nil
+}
+
+// keysToRemove returns the keyring keys to remove, sorted by address: the keys a previous pass imported (recorded)
+// that are no longer desired. Keys the loader didn't import (added by hand or another tool) and the keys of
+// disabled entries (kept) are never removed.
+func keysToRemove(existing []PlannedKey, desired, recorded, kept map[string]bool) []PlannedKey {
+	removed := make([]PlannedKey, 0)
+	for _, key := range existing {
+		if recorded[key.Address] && !desired[key.Address] && !kept[key.Address] {
+			removed = append(removed, key)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Address < removed[j].Address
+	})
+	return removed
+}
+
+// buildPlan computes the changes a pass would make without writing anything: keys are imported into an
+// in-memory keyring, compared with the keys of the real keyring, and the generated config with the published one.
+func buildPlan(appConfig *AppConfig) (*Plan, error) {
+	keys, err := loadDesiredKeys(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	relayMinerConfig, err := loadRelayMinerConfig(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading relay miner config: %w", err)
+	}
+
+	importedKeys, err := importAndRegisterKeys(appConfig, keys, keyring.NewInMemory(getCodec()), relayMinerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error processing keys: %w", err)
+	}
+
+	walletKeyring, err := newKeyring(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing keyring: %w", err)
+	}
+	records, err := walletKeyring.List()
+	if err != nil {
+		return nil, fmt.Errorf("error listing keyring keys: %w", err)
+	}
+
+	plan := &Plan{
+		CreatedAt:     time.Now().UTC(),
+		KeysToAdd:     make([]PlannedKey, 0),
+		KeysToRemove:  make([]PlannedKey, 0),
+		ConfigChanges: make([]ConfigChange, 0),
+	}
+
+	existing := make([]PlannedKey, 0, len(records))
+	existingAddresses := make(map[string]bool)
+	for _, record := range records {
+		address, err := record.GetAddress()
+		if err != nil {
+			return nil, fmt.Errorf("error reading address of key %s: %w", record.Name, err)
+		}
+		existing = append(existing, PlannedKey{Name: record.Name, Address: address.String()})
+		existingAddresses[address.String()] = true
+	}
+
+	desired := make(map[string]bool)
+	for _, key := range importedKeys {
+		if desired[key.Address] {
+			continue
+		}
+		desired[key.Address] = true
+		if !existingAddresses[key.Address] {
+			plan.KeysToAdd = append(plan.KeysToAdd, PlannedKey{
+				Name:       key.Name,
+				Address:    key.Address,
+				Role:       key.Role,
+				ServiceIds: key.ServiceIds,
+			})
+		}
+	}
+
+	recorded, err := recordedAddresses(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	kept, err := disabledAddresses(keys)
+	if err != nil {
+		return nil, err
+	}
+	plan.KeysToRemove = keysToRemove(existing, desired, recorded, kept)
+
+	if relayMinerConfig != nil {
+		if err := discoverSupplierBackends(appConfig, relayMinerConfig); err != nil {
+			return nil, fmt.Errorf("error discovering supplier backends: %w", err)
+		}
+		if err := checkEmptySuppliers(appConfig, relayMinerConfig); err != nil {
+			return nil, fmt.Errorf("error checking suppliers signing keys: %w", err)
+		}
+
+		updatedContent, err := marshalRelayMinerConfig(appConfig.RelayMinerOutputFormat, relayMinerConfig)
+		if err != nil {
+			return nil, err
+		}
+		previousContent, err := readRelayMinerConfigOutput(appConfig)
+		if err != nil {
+			return nil, err
+		}
+		plan.ConfigChanges, err = configChanges(previousContent, updatedContent)
+		if err != nil {
+			return nil, err
+		}
+		plan.ConfigChanges = redactConfigChanges(plan.ConfigChanges)
+		plan.ConfigHash = contentHash(updatedContent)
+	}
+
+	plan.Fingerprint, err = plan.fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Int("keys_to_add", len(plan.KeysToAdd)).
+		Int("keys_to_remove", len(plan.KeysToRemove)).
+		Int("config_changes", len(plan.ConfigChanges)).
+		Str("fingerprint", plan.Fingerprint).
+		Msg("Plan computed")
+	return plan, nil
+}
+
+// applyPlan executes a plan: the sources are planned again and must still produce the same changeset, then a pass
+// imports the keys and writes the configs, and the keys the plan removes are deleted from the keyring.
+func applyPlan(appConfig *AppConfig, plan *Plan) error {
+	current, err := buildPlan(appConfig)
+	if err != nil {
+		return err
+	}
+	if current.Fingerprint != plan.Fingerprint {
+		log.Error().
+			Str("plan_fingerprint", plan.Fingerprint).
+			Str("current_fingerprint", current.Fingerprint).
+			Msg("Sources changed since the plan was created")
+		return withExitCode(ExitValidationError, fmt.Errorf("stale plan: the sources changed since it was created, run %s again", PlanCommand))
+	}
+
+	if err := run(appConfig); err != nil {
+		return err
+	}
+
+	if len(plan.KeysToRemove) == 0 {
+		return nil
+	}
+
+	walletKeyring, err := newKeyring(appConfig)
+	if err != nil {
+		return fmt.Errorf("error initializing keyring: %w", err)
+	}
+	walletKeyring = newAuditedKeyring(appConfig, walletKeyring, "plan")
+	for _, key := range plan.KeysToRemove {
+		address, err := sdk.AccAddressFromBech32(key.Address)
+		if err != nil {
+			return fmt.Errorf("invalid address %s in plan: %w", key.Address, err)
+		}
+		if err := walletKeyring.DeleteByAddress(address); err != nil {
+			return fmt.Errorf("error removing key %s: %w", key.Name, err)
+		}
+		log.Info().Str("name", key.Name).Str("address", key.Address).Msg("Removed key from keyring")
+	}
+
+	log.Info().Int("keys_removed", len(plan.KeysToRemove)).Msg("Plan applied successfully")
+	return nil
+}
+
+// planCommand runs the plan subcommand: it prints the plan, or writes it to out.
+func planCommand(appConfig *AppConfig, out string) error {
+	plan, err := buildPlan(appConfig)
+	if err != nil {
+		return fmt.Errorf("error computing plan: %w", err)
+	}
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal plan: %w", err)
+	}
+	if out == "" {
+		_, err = fmt.Println(string(content))
+		return err
+	}
+	if err := writeFileAtomic(out, content, 0600); err != nil {
+		return fmt.Errorf("unable to write plan: %w", err)
+	}
+	log.Info().Str("path", out).Msg("Plan written")
+	return nil
+}
+
+// applyCommand runs the apply subcommand with the plan file at path.
+func applyCommand(appConfig *AppConfig, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read plan: %w", err)
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(content, plan); err != nil {
+		return withExitCode(ExitValidationError, fmt.Errorf("unable to parse plan: %w", err))
+	}
+	return applyPlan(appConfig, plan)
+}
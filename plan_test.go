@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeysToRemove(t *testing.T) {
+	existing := []PlannedKey{
+		{Name: "supplier3", Address: "pokt1c"},
+		{Name: "supplier1", Address: "pokt1a"},
+		{Name: "manual", Address: "pokt1m"},
+		{Name: "disabled", Address: "pokt1d"},
+		{Name: "supplier2", Address: "pokt1b"},
+	}
+	tests := []struct {
+		name     string
+		desired  map[string]bool
+		recorded map[string]bool
+		kept     map[string]bool
+		want     []PlannedKey
+	}{
+		{
+			name:    "no state",
+			desired: map[string]bool{"pokt1a": true},
+			want:    []PlannedKey{},
+		},
+		{
+			name:     "every recorded key still desired",
+			desired:  map[string]bool{"pokt1a": true, "pokt1b": true, "pokt1c": true},
+			recorded: map[string]bool{"pokt1a": true, "pokt1b": true, "pokt1c": true},
+			want:     []PlannedKey{},
+		},
+		{
+			name:     "recorded keys no longer desired, sorted by address",
+			desired:  map[string]bool{"pokt1b": true},
+			recorded: map[string]bool{"pokt1a": true, "pokt1b": true, "pokt1c": true},
+			want:     []PlannedKey{{Name: "supplier1", Address: "pokt1a"}, {Name: "supplier3", Address: "pokt1c"}},
+		},
+		{
+			name:     "keys not imported by the loader",
+			desired:  map[string]bool{},
+			recorded: map[string]bool{"pokt1a": true},
+			want:     []PlannedKey{{Name: "supplier1", Address: "pokt1a"}},
+		},
+		{
+			name:     "keys of disabled entries",
+			desired:  map[string]bool{"pokt1a": true, "pokt1b": true, "pokt1c": true},
+			recorded: map[string]bool{"pokt1a": true, "pokt1b": true, "pokt1c": true, "pokt1d": true},
+			kept:     map[string]bool{"pokt1d": true},
+			want:     []PlannedKey{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keysToRemove(existing, tt.desired, tt.recorded, tt.kept); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keysToRemove = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisabledAddresses(t *testing.T) {
+	keys := []WalletKeySpec{
+		{Hex: "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"},
+		{Hex: "0x8f2a55949038a9610f50fb23b5883af3b4ecb3c3bb792cbcefbd1542c692be63", Disabled: true},
+		{Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", StartIndex: 0, EndIndex: 1, Disabled: true},
+	}
+	addresses, err := disabledAddresses(keys)
+	if err != nil {
+		t.Fatalf("disabledAddresses: %v", err)
+	}
+	if len(addresses) != 3 {
+		t.Errorf("disabledAddresses returned %d addresses, want the 3 keys of the disabled entries", len(addresses))
+	}
+
+	enabled, err := disabledAddresses(keys[:1])
+	if err != nil {
+		t.Fatalf("disabledAddresses: %v", err)
+	}
+	if len(enabled) != 0 {
+		t.Errorf("disabledAddresses returned %v for enabled entries", enabled)
+	}
+}
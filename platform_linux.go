@@ -0,0 +1,9 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// setNonDumpable clears the dumpable flag of the process: no core dump, whatever core_pattern pipes it to, and no
+// ptrace attach by other processes of the same user.
+func setNonDumpable() error {
+	return unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0)
+}
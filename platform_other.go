@@ -0,0 +1,8 @@
+//go:build !windows && !linux
+
+package main
+
+// setNonDumpable does nothing: the core file size limit is all there is to disable core dumps.
+func setNonDumpable() error {
+	return nil
+}
@@ -0,0 +1,72 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ownershipSupported tells whether files can be handed over to OUTPUT_UID/OUTPUT_GID.
+const ownershipSupported = true
+
+// dumpStateSignals trigger a state dump, see dumpStateOnSignal.
+var dumpStateSignals = []os.Signal{syscall.SIGUSR1}
+
+// hookShell runs the import hooks.
+var hookShell = []string{"sh", "-c"}
+
+// tryLockFile takes an exclusive flock on file without waiting, busy tells the lock is held by another process.
+func tryLockFile(file *os.File) (busy bool, err error) {
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return true, nil
+	}
+	return false, err
+}
+
+// unlockFile releases the lock taken by tryLockFile.
+func unlockFile(file *os.File) {
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// syncDir persists the entries of dir, such as a rename into it.
+func syncDir(dir string) error {
+	dirHandle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer dirHandle.Close()
+	return dirHandle.Sync()
+}
+
+// freeDiskSpace returns the bytes available to unprivileged users on the filesystem of dir.
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// disableCoreDumps drops the core file size limit to zero, and makes the process non-dumpable where supported so
+// neither core dumps piped to a handler nor ptrace can read its memory.
+func disableCoreDumps() error {
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{Cur: 0, Max: 0}); err != nil {
+		return err
+	}
+	return setNonDumpable()
+}
+
+// lockMemory locks the current and future pages of the process into RAM, keeping key material out of swap.
+func lockMemory() error {
+	return unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE)
+}
+
+// setUmask replaces the umask of the process.
+func setUmask(mask os.FileMode) {
+	syscall.Umask(int(mask.Perm()))
+}
@@ -0,0 +1,64 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// ownershipSupported tells whether files can be handed over to OUTPUT_UID/OUTPUT_GID: Windows has no uid/gid.
+const ownershipSupported = false
+
+// dumpStateSignals trigger a state dump, see dumpStateOnSignal: Windows has no SIGUSR1, use the status socket.
+var dumpStateSignals []os.Signal
+
+// hookShell runs the import hooks.
+var hookShell = []string{"cmd", "/C"}
+
+// tryLockFile takes an exclusive lock on file without waiting, busy tells the lock is held by another process.
+func tryLockFile(file *os.File) (busy bool, err error) {
+	err = windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return true, nil
+	}
+	return false, err
+}
+
+// unlockFile releases the lock taken by tryLockFile.
+func unlockFile(file *os.File) {
+	_ = windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
+
+// syncDir persists the entries of dir: NTFS journals renames and directories can't be flushed, so it does nothing.
+func syncDir(dir string) error {
+	return nil
+}
+
+// freeDiskSpace returns the bytes available to the current user on the volume of dir.
+func freeDiskSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, &total, &free); err != nil {
+		return 0, err
+	}
+	return available, nil
+}
+
+// disableCoreDumps does nothing: Windows Error Reporting only collects dumps when configured to.
+func disableCoreDumps() error {
+	return nil
+}
+
+// lockMemory isn't supported: Windows can only lock given ranges, not the whole process.
+func lockMemory() error {
+	return errors.New("locking the process memory is not supported on Windows")
+}
+
+// setUmask does nothing: Windows has no umask.
+func setUmask(mask os.FileMode) {}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ProbeCommand checks that the loader provisioned the keyring, for exec-based liveness and readiness probes.
+const ProbeCommand string = "probe"
+
+// probeCompletion checks the completion file, and that it was written within maxAge when set. Returns when the
+// last successful pass completed.
+func probeCompletion(path string, maxAge time.Duration) (time.Time, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, fmt.Errorf("no pass completed yet: %s doesn't exist", path)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to read completion file '%s': %w", path, err)
+	}
+
+	marker := CompletionMarker{}
+	if err := json.Unmarshal(content, &marker); err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse completion file '%s': %w", path, err)
+	}
+	if age := time.Since(marker.CompletedAt); maxAge > 0 && age > maxAge {
+		return marker.CompletedAt, fmt.Errorf("last pass completed %s ago, more than %s", age.Round(time.Second), maxAge)
+	}
+	return marker.CompletedAt, nil
+}
+
+// probe checks the completion file and the state file of the loader, whichever are configured. Passes with
+// unchanged inputs rewrite the completion file but not the state file, so maxAge only applies to the former.
+func probe(appConfig *AppConfig, maxAge time.Duration) error {
+	if appConfig.CompletionFilePath == "" && appConfig.StateFilePath == "" {
+		return withExitCode(ExitConfigError, errors.New("probe needs COMPLETION_FILE_PATH or STATE_FILE_PATH"))
+	}
+	if maxAge > 0 && appConfig.CompletionFilePath == "" {
+		return withExitCode(ExitConfigError, errors.New("--max-age needs COMPLETION_FILE_PATH"))
+	}
+
+	status := "ok"
+	if appConfig.CompletionFilePath != "" {
+		completedAt, err := probeCompletion(appConfig.CompletionFilePath, maxAge)
+		if err != nil {
+			return err
+		}
+		status += fmt.Sprintf(", last pass completed at %s", completedAt.Format(time.RFC3339))
+	}
+	if appConfig.StateFilePath != "" {
+		state, err := loadState(appConfig.StateFilePath)
+		if err != nil {
+			return err
+		}
+		if state == nil {
+			return fmt.Errorf("no pass applied yet: %s doesn't exist", appConfig.StateFilePath)
+		}
+		status += fmt.Sprintf(", %d keys applied at %s", len(state.Keys), state.AppliedAt.Format(time.RFC3339))
+	}
+
+	_, err := fmt.Println(status)
+	return err
+}
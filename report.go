@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Statuses of a RunReport
+const (
+	ReportSuccess   string = "success"
+	ReportUnchanged string = "unchanged"
+	ReportFailure   string = "failure"
+)
+
+// ReportError is an error of a RunReport, Entry being the index of the failed keys entry (absent for errors of the
+// pass as a whole). Code, Component and Retryable classify it, see PipelineError.
+type ReportError struct {
+	Entry     *int   `json:"entry,omitempty"`
+	Code      string `json:"code"`
+	Component string `json:"component,omitempty"`
+	Retryable bool   `json:"retryable"`
+	Message   string `json:"message"`
+}
+
+// newReportError reports err, classified as a failure of component unless it already is.
+func newReportError(entry *int, component string, err error) ReportError {
+	classified := classifyError(withComponent(component, err))
+	return ReportError{
+		Entry:     entry,
+		Code:      classified.Code,
+		Component: classified.Component,
+		Retryable: classified.Retryable,
+		Message:   err.Error(),
+	}
+}
+
+// ReportInputs identifies the inputs of a pass by their SHA-256, so observers can tell which loaders run the same
+// keys and config without seeing them.
+type ReportInputs struct {
+	// Hash covers the keys, the base relay miner config and the settings, like the state file.
+	Hash             string `json:"hash,omitempty"`
+	Keys             string `json:"keys,omitempty"`
+	RelayMinerConfig string `json:"relayminer_config,omitempty"`
+}
+
+// RunReport is the machine-readable summary of a pass, written to ReportFilePath and/or published to the report
+// ConfigMap for downstream automation.
+type RunReport struct {
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Pod        string    `json:"pod"`
+	RunMode    string    `json:"run_mode"`
+	// Inputs are unset when the pass failed before its inputs were loaded.
+	Inputs ReportInputs `json:"inputs"`
+	// Drift compares the inputs and the keyring with the last pass recorded in STATE_FILE_PATH, absent without one.
+	Drift *InputsDrift `json:"drift,omitempty"`
+	// Keys are the keys in the keyring with the services they were registered to, the keys recorded by the last
+	// pass when the inputs were unchanged.
+	Keys []ImportedKey `json:"keys"`
+	// Services are the signing keys of every supplier of the generated relay miner config and SuppliersWithoutKeys
+	// the service IDs of the suppliers left without any, both absent when the pass didn't generate it.
+	Services             []ServiceCoverage `json:"services,omitempty"`
+	SuppliersWithoutKeys []string          `json:"suppliers_without_keys,omitempty"`
+	// SuppliersAdded and SuppliersRemoved are the service IDs of the suppliers synced from the chain, see
+	// ONCHAIN_SUPPLIERS.
+	SuppliersAdded   []string `json:"suppliers_added,omitempty"`
+	SuppliersRemoved []string `json:"suppliers_removed,omitempty"`
+	// UnknownServices are the service IDs keys are registered to that don't exist on chain, see SERVICE_CHECK.
+	UnknownServices []string `json:"unknown_services,omitempty"`
+	// FundedAddresses are the addresses of the imported keys funded by the faucet, see FAUCET_URL.
+	FundedAddresses []string `json:"funded_addresses,omitempty"`
+	// Accounts are the account numbers and sequences of the imported keys with an account on chain, absent without
+	// CHAIN_GRPC_URL.
+	Accounts []AccountRecord `json:"accounts,omitempty"`
+	// MissingAccounts are the addresses of the imported keys without an account on chain, see ACCOUNT_CHECK.
+	MissingAccounts []string `json:"missing_accounts,omitempty"`
+	// LowBalances are the imported supplier keys holding less than MIN_BALANCE, see BALANCE_CHECK.
+	LowBalances []LowBalance `json:"low_balances,omitempty"`
+	// StakeMismatches are the imported operator keys whose supplier stake doesn't match their services, see
+	// STAKE_CHECK.
+	StakeMismatches []StakeMismatch `json:"stake_mismatches,omitempty"`
+	// MissingDelegations are the imported application keys not delegated to all their gateways, see
+	// DELEGATION_CHECK.
+	MissingDelegations []MissingDelegation `json:"missing_delegations,omitempty"`
+	// ParamViolations are the values of the generated configs the chain params would reject, see PARAMS_CHECK.
+	ParamViolations []ParamViolation `json:"param_violations,omitempty"`
+	// Transactions are the transactions broadcast (or written unsigned, see UNSIGNED_TX_OUTPUT_DIR) by the pass, see
+	// SUPPLIER_AUTO_STAKE and APPLICATION_AUTO_DELEGATE.
+	Transactions []ChainTx `json:"transactions,omitempty"`
+	// SkippedEntries are the indexes of the disabled keys entries.
+	SkippedEntries []int         `json:"skipped_entries"`
+	Errors         []ReportError `json:"errors"`
+	ExitCode       int           `json:"exit_code"`
+}
+
+// newRunReport starts the report of a pass.
+func newRunReport(appConfig *AppConfig) *RunReport {
+	return &RunReport{
+		StartedAt:      time.Now().UTC(),
+		Pod:            podName(),
+		RunMode:        appConfig.RunMode,
+		Keys:           []ImportedKey{},
+		SkippedEntries: []int{},
+		Errors:         []ReportError{},
+	}
+}
+
+// recordInputs hashes the inputs of the pass.
+func (r *RunReport) recordInputs(appConfig *AppConfig, keys []WalletKeySpec, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	hash, err := inputsHash(appConfig, keys, relayMinerConfig)
+	if err != nil {
+		return err
+	}
+	keysContent, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("unable to marshal keys: %w", err)
+	}
+	relayMinerConfigContent, err := yaml.Marshal(relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("unable to marshal RelayMiner config: %w", err)
+	}
+	r.Inputs = ReportInputs{
+		Hash:             hash,
+		Keys:             contentHash(keysContent),
+		RelayMinerConfig: contentHash(relayMinerConfigContent),
+	}
+	return nil
+}
+
+// finish records the outcome of the pass, err being its error. Failed entries of FailModeContinue are reported
+// one by one.
+func (r *RunReport) finish(err error) {
+	r.FinishedAt = time.Now().UTC()
+	if r.Status == "" {
+		r.Status = ReportSuccess
+	}
+	if err == nil {
+		return
+	}
+
+	r.Status = ReportFailure
+	r.ExitCode = exitCode(err)
+	var entryErrors EntryErrors
+	if errors.As(err, &entryErrors) {
+		for _, entryError := range entryErrors {
+			index := entryError.Index
+			r.Errors = append(r.Errors, newReportError(&index, ComponentKeys, entryError.Err))
+		}
+		return
+	}
+	r.Errors = append(r.Errors, newReportError(nil, "", err))
+}
+
+// reportConfigMapKey returns the key of the report ConfigMap the loader publishes its report under.
+func reportConfigMapKey(appConfig *AppConfig) string {
+	return orDefault(appConfig.ReportConfigMapKey, podName()+".json")
+}
+
+// publishRunReport finishes report with the outcome of the pass, writes it to ReportFilePath and publishes it to the
+// report ConfigMap, whichever are enabled.
+func publishRunReport(appConfig *AppConfig, report *RunReport, err error) error {
+	if appConfig.ReportFilePath == "" && appConfig.ReportConfigMapName == "" {
+		return nil
+	}
+	report.finish(err)
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal run report: %w", err)
+	}
+	content = append(content, '\n')
+	signature, err := signRunReport(appConfig, content)
+	if err != nil {
+		return err
+	}
+	// the signature covers the report itself, recipients verify it once decrypted
+	content, err = encryptExport(appConfig, content)
+	if err != nil {
+		return err
+	}
+
+	if appConfig.ReportFilePath != "" {
+		if err := writeFileAtomic(appConfig.ReportFilePath, content, 0644); err != nil {
+			return fmt.Errorf("unable to write run report: %w", err)
+		}
+		if err := chownPath(appConfig.ReportFilePath, appConfig.OutputUid, appConfig.OutputGid, false); err != nil {
+			return err
+		}
+		if signature != nil {
+			signaturePath := appConfig.ReportFilePath + reportSignatureSuffix
+			if err := writeFileAtomic(signaturePath, signature, 0644); err != nil {
+				return fmt.Errorf("unable to write run report signature: %w", err)
+			}
+			if err := chownPath(signaturePath, appConfig.OutputUid, appConfig.OutputGid, false); err != nil {
+				return err
+			}
+		}
+		log.Info().Str("path", appConfig.ReportFilePath).Str("status", report.Status).Bool("signed", signature != nil).Msg("Run report written")
+	}
+
+	if appConfig.ReportConfigMapName != "" {
+		key := reportConfigMapKey(appConfig)
+		data := map[string]string{key: string(content)}
+		if signature != nil {
+			data[key+reportSignatureSuffix] = string(signature)
+		}
+		err := retryKubernetes(appConfig, "publish run report", func() error {
+			return putRunReport(appConfig, data)
+		})
+		if err != nil {
+			return withExitCode(ExitSourceError, err)
+		}
+		log.Info().
+			Str("namespace", appConfig.ReportConfigMapNamespace).
+			Str("name", appConfig.ReportConfigMapName).
+			Str("key", key).
+			Str("status", report.Status).
+			Bool("signed", signature != nil).
+			Msg("Run report published")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+)
+
+// reportSignatureSuffix is appended to the report file path and ConfigMap key to name the detached signature.
+const reportSignatureSuffix = ".sig"
+
+// reportSignatureAlgorithm is ECDSA over secp256k1 of the SHA-256 of the report, the signatures of the Cosmos SDK.
+const reportSignatureAlgorithm = "secp256k1-sha256"
+
+// ReportSignature is the detached signature of the exact bytes of a run report, so downstream systems can verify the
+// report wasn't altered and who signed it.
+type ReportSignature struct {
+	Algorithm string `json:"algorithm"`
+	Address   string `json:"address"`
+	// PubKey is the compressed public key and Signature the 64-byte r||s signature, both base64 encoded.
+	PubKey    string `json:"pub_key"`
+	Signature string `json:"signature"`
+}
+
+// loadReportSigningKey reads the hex secp256k1 private key of REPORT_SIGNING_KEY_FILE.
+func loadReportSigningKey(path string) (*secp256k1.PrivKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read report signing key '%s': %w", path, err)
+	}
+	keyBytes, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(content)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: report signing key '%s': %w", ErrInvalidHexKey, path, err)
+	}
+	if len(keyBytes) != secp256k1.PrivKeySize {
+		return nil, fmt.Errorf("%w: report signing key '%s' is %d bytes, expected %d", ErrInvalidHexKey, path, len(keyBytes), secp256k1.PrivKeySize)
+	}
+	return &secp256k1.PrivKey{Key: keyBytes}, nil
+}
+
+// signRunReport signs the content of a run report with the dedicated key of REPORT_SIGNING_KEY_FILE or the keyring
+// key REPORT_SIGNING_KEY_NAME, and returns the marshaled ReportSignature, nil when signing is disabled.
+func signRunReport(appConfig *AppConfig, content []byte) ([]byte, error) {
+	var signature []byte
+	var pubKey cryptotypes.PubKey
+	switch {
+	case appConfig.ReportSigningKeyFile != "":
+		privKey, err := loadReportSigningKey(appConfig.ReportSigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signature, err = privKey.Sign(content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign run report: %w", err)
+		}
+		pubKey = privKey.PubKey()
+	case appConfig.ReportSigningKeyName != "":
+		walletKeyring, err := newKeyring(appConfig)
+		if err != nil {
+			return nil, err
+		}
+		signature, pubKey, err = walletKeyring.Sign(appConfig.ReportSigningKeyName, content, signing.SignMode_SIGN_MODE_DIRECT)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign run report with key %s: %w", appConfig.ReportSigningKeyName, err)
+		}
+	default:
+		return nil, nil
+	}
+
+	marshaled, err := json.MarshalIndent(ReportSignature{
+		Algorithm: reportSignatureAlgorithm,
+		Address:   sdk.AccAddress(pubKey.Address()).String(),
+		PubKey:    base64.StdEncoding.EncodeToString(pubKey.Bytes()),
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal run report signature: %w", err)
+	}
+	return append(marshaled, '\n'), nil
+}
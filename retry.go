@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// retriableKubernetesError reports whether a failed Kubernetes request may succeed when sent again: throttling,
+// conflicts, timeouts, unavailable or failing API servers and dropped connections.
+func retriableKubernetesError(err error) bool {
+	return k8serrors.IsTooManyRequests(err) ||
+		k8serrors.IsConflict(err) ||
+		k8serrors.IsServerTimeout(err) ||
+		k8serrors.IsTimeout(err) ||
+		k8serrors.IsServiceUnavailable(err) ||
+		k8serrors.IsInternalError(err) ||
+		k8serrors.IsUnexpectedServerError(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
+// retryKubernetes calls fn until it succeeds, fails with a non-retriable error or KubernetesRetryAttempts are used up,
+// sleeping with exponential backoff and jitter in between. A Retry-After sent with a 429 is honored when longer.
+// fn must redo the whole read-modify-write, so a conflict is retried against the latest resource version.
+func retryKubernetes(appConfig *AppConfig, operation string, fn func() error) error {
+	backoff := wait.Backoff{
+		Duration: appConfig.KubernetesRetryInitialDelay,
+		Factor:   2,
+		Jitter:   0.2,
+		Steps:    appConfig.KubernetesRetryAttempts,
+		Cap:      appConfig.KubernetesRetryMaxDelay,
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retriableKubernetesError(err) || attempt >= appConfig.KubernetesRetryAttempts {
+			return err
+		}
+
+		delay := backoff.Step()
+		if seconds, ok := k8serrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+		log.Warn().
+			Err(err).
+			Str("operation", operation).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Kubernetes request failed, retrying")
+		select {
+		case <-appConfig.runContext().Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+	"github.com/rs/zerolog/log"
+)
+
+// InitCommand writes a working skeleton of the loader inputs.
+const InitCommand string = "init"
+
+// Formats of the scaffolded keys document
+const (
+	// ScaffoldYAML writes a commented keys.yaml (default).
+	ScaffoldYAML string = "yaml"
+	// ScaffoldJSON writes a keys.json, which can't carry comments.
+	ScaffoldJSON string = "json"
+)
+
+// scaffoldKeysYAML is the keys document written by init, %s being the generated mnemonic.
+const scaffoldKeysYAML = `# Keys imported into the keyring (KEYS_FILE_PATH). JSON works too, YAML only adds comments.
+#
+# Every entry holds either a BIP-39 mnemonic, whose keys are derived for every HD index from start_index to
+# end_index, or a raw secp256k1 private key as hex. The mnemonic below was generated by init: replace it with your
+# own before staking anything, and keep this file as secret as the keys themselves.
+- mnemonic: "%s"
+  start_index: 0
+  end_index: 1
+  # Suppliers of config.yaml (suppliers[].service_id) the keys sign for. Plain IDs match exactly, anything else is a
+  # regular expression. An empty list registers the keys as default_signing_key_names instead.
+  service_id:
+    - anvil
+  # operator (default) keys sign relays, owner keys are imported only, application keys get an AppGate config.
+  role: operator
+
+# A hex key, quoted so digits-only keys aren't read as numbers:
+# - hex: "<hex private key>"
+#   service_id: ["anvil"]
+`
+
+// scaffoldRelayMinerConfig is the base relay miner config written by init.
+const scaffoldRelayMinerConfig = `# Base relay miner config (RELAYMINER_CONFIG_FILE_PATH). The loader fills default_signing_key_names and the
+# signing_key_names of the suppliers with the imported keys and writes the result to
+# RELAYMINER_CONFIG_FILE_OUTPUT_PATH, leaving this file untouched.
+default_signing_key_names: []
+smt_store_path: /home/pocket/.pocket/smt
+pocket_node:
+  query_node_rpc_url: https://shannon-testnet-grove-rpc.beta.poktroll.com:443
+  query_node_grpc_url: tcp://shannon-testnet-grove-grpc.beta.poktroll.com:443
+  tx_node_rpc_url: https://shannon-testnet-grove-rpc.beta.poktroll.com:443
+suppliers:
+  # service_id is what keys.yaml entries reference
+  - service_id: anvil
+    signing_key_names: []
+    service_config:
+      backend_url: http://anvil:8545
+    listen_url: http://0.0.0.0:8545
+metrics:
+  enabled: true
+  addr: :9090
+`
+
+// scaffoldLoaderConfig is the loader config file written by init, wiring the other files together.
+const scaffoldLoaderConfig = `# Loader settings (LOADER_CONFIG_FILE), keyed by the lower-cased environment variable names. Environment
+# variables and flags override them. Paths are relative to the working directory.
+config_source: file
+keys_file_path: %s
+relayminer_config_file_path: %s
+relayminer_config_file_output_path: %s
+keyring_backend: test
+keyring_dir: %s
+`
+
+// checkScaffoldTargets refuses to overwrite existing files unless forced, before anything is written.
+func checkScaffoldTargets(force bool, paths ...string) error {
+	if force {
+		return nil
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeScaffoldFile writes a scaffolded file.
+func writeScaffoldFile(path, content string, mode os.FileMode) error {
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	log.Info().Str("path", path).Msg("Scaffolded file written")
+	return nil
+}
+
+// scaffold writes a keys document with a freshly generated mnemonic, a minimal base relay miner config whose
+// supplier the keys sign for, and a loader config file pointing at both, into dir.
+func scaffold(dir, format string, force bool) error {
+	if format != ScaffoldYAML && format != ScaffoldJSON {
+		return withExitCode(ExitConfigError, fmt.Errorf("unsupported scaffold format: %s", format))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return fmt.Errorf("error generating entropy: %w", err)
+	}
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return fmt.Errorf("error generating mnemonic: %w", err)
+	}
+
+	keysPath := filepath.Join(dir, "keys."+format)
+	keys := fmt.Sprintf(scaffoldKeysYAML, mnemonic)
+	if format == ScaffoldJSON {
+		content, err := json.MarshalIndent([]WalletKeySpec{{
+			Mnemonic:   mnemonic,
+			StartIndex: 0,
+			EndIndex:   1,
+			ServiceID:  []string{"anvil"},
+			Role:       OperatorRole,
+		}}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal keys: %w", err)
+		}
+		keys = string(content) + "\n"
+	}
+
+	configPath := filepath.Join(dir, "config.yaml")
+	loaderConfigPath := filepath.Join(dir, "loader.yaml")
+	loaderConfig := fmt.Sprintf(scaffoldLoaderConfig,
+		keysPath,
+		configPath,
+		filepath.Join(dir, "generated.config.yaml"),
+		filepath.Join(dir, "keyring"),
+	)
+
+	if err := checkScaffoldTargets(force, keysPath, configPath, loaderConfigPath); err != nil {
+		return err
+	}
+	if err := writeScaffoldFile(keysPath, keys, 0600); err != nil {
+		return err
+	}
+	if err := writeScaffoldFile(configPath, scaffoldRelayMinerConfig, 0644); err != nil {
+		return err
+	}
+	if err := writeScaffoldFile(loaderConfigPath, loaderConfig, 0644); err != nil {
+		return err
+	}
+
+	fmt.Println(strings.Join([]string{
+		"Scaffolded " + keysPath + ", " + configPath + " and " + loaderConfigPath + ". Next steps:",
+		"  LOADER_CONFIG_FILE=" + loaderConfigPath + " shannon-keyring-loader verify",
+		"  LOADER_CONFIG_FILE=" + loaderConfigPath + " shannon-keyring-loader",
+	}, "\n"))
+	return nil
+}
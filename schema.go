@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// Relay miner config schema versions
+const (
+	// RelayMinerSchemaLegacy is the schema with `signing_key_name`, `proxies`/`proxy_names`
+	// and `service_config.url`, used by early poktroll releases.
+	RelayMinerSchemaLegacy string = "legacy"
+	// RelayMinerSchemaCurrent is the schema understood by poktrollconfig.YAMLRelayMinerConfig.
+	RelayMinerSchemaCurrent string = "current"
+)
+
+// detectRelayMinerSchema returns the schema version of a relay miner config document.
+// The relay miner config has no version field, so the version is inferred from fields that were renamed or removed.
+func detectRelayMinerSchema(document map[interface{}]interface{}) string {
+	if _, ok := document["signing_key_name"]; ok {
+		return RelayMinerSchemaLegacy
+	}
+	if _, ok := document["proxies"]; ok {
+		return RelayMinerSchemaLegacy
+	}
+
+	for _, supplier := range yamlSlice(document["suppliers"]) {
+		supplierConfig := yamlMap(supplier)
+		if _, ok := supplierConfig["proxy_names"]; ok {
+			return RelayMinerSchemaLegacy
+		}
+		if _, ok := yamlMap(supplierConfig["service_config"])["url"]; ok {
+			return RelayMinerSchemaLegacy
+		}
+	}
+
+	return RelayMinerSchemaCurrent
+}
+
+// upgradeRelayMinerConfig detects the schema version of a relay miner config and converts older documents
+// to the current schema, so renamed fields are carried over instead of being dropped on unmarshal.
+// Documents already using the current schema are returned untouched.
+func upgradeRelayMinerConfig(content []byte) ([]byte, error) {
+	document := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("unable to unmarshall RelayMiner config file: %w", err)
+	}
+
+	version := detectRelayMinerSchema(document)
+	log.Debug().Str("schema", version).Msg("Detected relay miner config schema")
+
+	if version == RelayMinerSchemaCurrent {
+		return content, nil
+	}
+
+	log.Warn().
+		Str("schema", version).
+		Msg("Relay miner config uses an older schema, converting it to the current one")
+
+	if err := upgradeLegacyRelayMinerConfig(document); err != nil {
+		return nil, fmt.Errorf("unable to convert %s RelayMiner config: %w", version, err)
+	}
+
+	return yaml.Marshal(document)
+}
+
+// upgradeLegacyRelayMinerConfig converts a legacy relay miner config document in place:
+//   - signing_key_name becomes default_signing_key_names
+//   - suppliers[].service_config.url becomes suppliers[].service_config.backend_url
+//   - suppliers[].proxy_names are resolved through proxies[] into suppliers[].listen_url
+func upgradeLegacyRelayMinerConfig(document map[interface{}]interface{}) error {
+	if name, ok := document["signing_key_name"]; ok {
+		if _, exists := document["default_signing_key_names"]; !exists && name != nil && name != "" {
+			document["default_signing_key_names"] = []interface{}{name}
+		}
+		delete(document, "signing_key_name")
+		log.Info().Msg("Converted signing_key_name to default_signing_key_names")
+	}
+
+	// index proxies by name so suppliers can be pointed at the address they used to listen on
+	listenUrls := make(map[interface{}]string)
+	for _, proxy := range yamlSlice(document["proxies"]) {
+		proxyConfig := yamlMap(proxy)
+		proxyType, _ := proxyConfig["type"].(string)
+		if proxyType == "" {
+			proxyType = "http"
+		}
+		host, _ := proxyConfig["host"].(string)
+		if host != "" {
+			listenUrls[proxyConfig["proxy_name"]] = fmt.Sprintf("%s://%s", proxyType, host)
+		}
+	}
+	delete(document, "proxies")
+
+	for _, supplier := range yamlSlice(document["suppliers"]) {
+		supplierConfig := yamlMap(supplier)
+		serviceId := supplierConfig["service_id"]
+
+		serviceConfig := yamlMap(supplierConfig["service_config"])
+		if backendUrl, ok := serviceConfig["url"]; ok {
+			if _, exists := serviceConfig["backend_url"]; !exists {
+				serviceConfig["backend_url"] = backendUrl
+			}
+			delete(serviceConfig, "url")
+			log.Info().Interface("service_id", serviceId).Msg("Converted service_config.url to service_config.backend_url")
+		}
+
+		if proxyNames, ok := supplierConfig["proxy_names"]; ok {
+			names := yamlSlice(proxyNames)
+			if len(names) > 1 {
+				return fmt.Errorf("supplier %v uses %d proxies, only one listen_url is supported", serviceId, len(names))
+			}
+			if len(names) == 1 {
+				listenUrl, found := listenUrls[names[0]]
+				if !found {
+					return fmt.Errorf("supplier %v references unknown proxy: %v", serviceId, names[0])
+				}
+				if _, exists := supplierConfig["listen_url"]; !exists {
+					supplierConfig["listen_url"] = listenUrl
+				}
+			}
+			delete(supplierConfig, "proxy_names")
+			log.Info().Interface("service_id", serviceId).Msg("Converted proxy_names to listen_url")
+		}
+	}
+
+	return nil
+}
+
+// yamlMap returns v as a YAML mapping, or an empty mapping if v is not one.
+func yamlMap(v interface{}) map[interface{}]interface{} {
+	if m, ok := v.(map[interface{}]interface{}); ok {
+		return m
+	}
+	return map[interface{}]interface{}{}
+}
+
+// yamlSlice returns v as a YAML sequence, or nil if v is not one.
+func yamlSlice(v interface{}) []interface{} {
+	if s, ok := v.([]interface{}); ok {
+		return s
+	}
+	return nil
+}
+
+// droppedFields returns the paths of the fields present in the original document but missing from the
+// typed one, i.e. the fields the relay miner config schema does not know about. Empty fields are ignored, since
+// the typed document omits its own empty fields (omitempty).
+func droppedFields(path string, original, typed interface{}) []string {
+	fields := make([]string, 0)
+
+	originalMap, originalIsMap := original.(map[string]interface{})
+	typedMap, typedIsMap := typed.(map[string]interface{})
+	if originalIsMap && typedIsMap {
+		for name, value := range originalMap {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			typedValue, ok := typedMap[name]
+			if !ok {
+				if isEmptyField(value) {
+					continue
+				}
+				fields = append(fields, childPath)
+				continue
+			}
+			fields = append(fields, droppedFields(childPath, value, typedValue)...)
+		}
+		sort.Strings(fields)
+		return fields
+	}
+
+	originalSlice, originalIsSlice := original.([]interface{})
+	typedSlice, typedIsSlice := typed.([]interface{})
+	if originalIsSlice && typedIsSlice {
+		for i := 0; i < len(originalSlice) && i < len(typedSlice); i++ {
+			fields = append(fields, droppedFields(fmt.Sprintf("%s[%d]", path, i), originalSlice[i], typedSlice[i])...)
+		}
+	}
+
+	return fields
+}
+
+// isEmptyField reports whether a document field holds no value: null, an empty string, mapping or sequence.
+func isEmptyField(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return typed == ""
+	case map[string]interface{}:
+		return len(typed) == 0
+	case []interface{}:
+		return len(typed) == 0
+	default:
+		return false
+	}
+}
+
+// warnDroppedFields logs a warning for every field of the base config that the relay miner config schema
+// does not know about, since those fields are silently dropped from the regenerated output.
+func warnDroppedFields(content []byte, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	var original, typed interface{}
+
+	if err := yaml.Unmarshal(content, &original); err != nil {
+		return fmt.Errorf("unable to unmarshall RelayMiner config file: %w", err)
+	}
+
+	typedContent, err := yaml.Marshal(relayMinerConfig)
+	if err != nil {
+		return fmt.Errorf("unable to marshal RelayMiner config: %w", err)
+	}
+	if err := yaml.Unmarshal(typedContent, &typed); err != nil {
+		return fmt.Errorf("unable to unmarshall RelayMiner config: %w", err)
+	}
+
+	fields := droppedFields("", jsonCompatible(original), jsonCompatible(typed))
+	for _, field := range fields {
+		log.Warn().
+			Str("field", field).
+			Msg("Unknown relay miner config field will be dropped from the generated config")
+	}
+
+	if len(fields) > 0 {
+		log.Warn().
+			Int("fields", len(fields)).
+			Msg("Relay miner config contains fields unknown to this loader, it may need to be updated to a newer poktroll release")
+	}
+	return nil
+}
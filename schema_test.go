@@ -0,0 +1,182 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpgradeLegacyRelayMinerConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		document map[interface{}]interface{}
+		want     map[interface{}]interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "signing_key_name",
+			document: map[interface{}]interface{}{"signing_key_name": "supplier1"},
+			want:     map[interface{}]interface{}{"default_signing_key_names": []interface{}{"supplier1"}},
+		},
+		{
+			name: "signing_key_name doesn't override default_signing_key_names",
+			document: map[interface{}]interface{}{
+				"signing_key_name":          "supplier1",
+				"default_signing_key_names": []interface{}{"supplier2"},
+			},
+			want: map[interface{}]interface{}{"default_signing_key_names": []interface{}{"supplier2"}},
+		},
+		{
+			name:     "empty signing_key_name",
+			document: map[interface{}]interface{}{"signing_key_name": ""},
+			want:     map[interface{}]interface{}{},
+		},
+		{
+			name: "service_config.url and proxy_names",
+			document: map[interface{}]interface{}{
+				"proxies": []interface{}{
+					map[interface{}]interface{}{"proxy_name": "http-proxy", "type": "http", "host": "0.0.0.0:8545"},
+					map[interface{}]interface{}{"proxy_name": "default-proxy", "host": "0.0.0.0:8546"},
+				},
+				"suppliers": []interface{}{
+					map[interface{}]interface{}{
+						"service_id":     "anvil",
+						"service_config": map[interface{}]interface{}{"url": "http://anvil:8547"},
+						"proxy_names":    []interface{}{"http-proxy"},
+					},
+					map[interface{}]interface{}{
+						"service_id":  "ollama",
+						"proxy_names": []interface{}{"default-proxy"},
+					},
+				},
+			},
+			want: map[interface{}]interface{}{
+				"suppliers": []interface{}{
+					map[interface{}]interface{}{
+						"service_id":     "anvil",
+						"service_config": map[interface{}]interface{}{"backend_url": "http://anvil:8547"},
+						"listen_url":     "http://0.0.0.0:8545",
+					},
+					map[interface{}]interface{}{
+						"service_id": "ollama",
+						"listen_url": "http://0.0.0.0:8546",
+					},
+				},
+			},
+		},
+		{
+			name: "backend_url and listen_url are kept",
+			document: map[interface{}]interface{}{
+				"proxies": []interface{}{
+					map[interface{}]interface{}{"proxy_name": "http-proxy", "host": "0.0.0.0:8545"},
+				},
+				"suppliers": []interface{}{
+					map[interface{}]interface{}{
+						"service_id":     "anvil",
+						"service_config": map[interface{}]interface{}{"url": "http://old:8547", "backend_url": "http://anvil:8547"},
+						"proxy_names":    []interface{}{"http-proxy"},
+						"listen_url":     "http://0.0.0.0:9000",
+					},
+				},
+			},
+			want: map[interface{}]interface{}{
+				"suppliers": []interface{}{
+					map[interface{}]interface{}{
+						"service_id":     "anvil",
+						"service_config": map[interface{}]interface{}{"backend_url": "http://anvil:8547"},
+						"listen_url":     "http://0.0.0.0:9000",
+					},
+				},
+			},
+		},
+		{
+			name: "several proxies",
+			document: map[interface{}]interface{}{
+				"suppliers": []interface{}{
+					map[interface{}]interface{}{"service_id": "anvil", "proxy_names": []interface{}{"a", "b"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown proxy",
+			document: map[interface{}]interface{}{
+				"suppliers": []interface{}{
+					map[interface{}]interface{}{"service_id": "anvil", "proxy_names": []interface{}{"missing"}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := upgradeLegacyRelayMinerConfig(tt.document)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("upgradeLegacyRelayMinerConfig succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("upgradeLegacyRelayMinerConfig: %v", err)
+			}
+			if !reflect.DeepEqual(tt.document, tt.want) {
+				t.Errorf("upgradeLegacyRelayMinerConfig = %v, want %v", tt.document, tt.want)
+			}
+		})
+	}
+}
+
+func TestDroppedFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		original interface{}
+		typed    interface{}
+		want     []string
+	}{
+		{
+			name:     "known fields",
+			original: map[string]interface{}{"default_signing_key_names": []interface{}{"a"}},
+			typed:    map[string]interface{}{"default_signing_key_names": []interface{}{"a"}},
+			want:     []string{},
+		},
+		{
+			name: "unknown fields",
+			original: map[string]interface{}{
+				"smt_store_path": "/data",
+				"suppliers": []interface{}{
+					map[string]interface{}{"service_id": "anvil", "rpc_type": "json_rpc"},
+				},
+			},
+			typed: map[string]interface{}{
+				"suppliers": []interface{}{
+					map[string]interface{}{"service_id": "anvil"},
+				},
+			},
+			want: []string{"smt_store_path", "suppliers[0].rpc_type"},
+		},
+		{
+			name: "empty fields omitted from the typed document",
+			original: map[string]interface{}{
+				"default_signing_key_names": []interface{}{},
+				"metrics":                   map[string]interface{}{},
+				"pprof":                     nil,
+				"suppliers": []interface{}{
+					map[string]interface{}{"service_id": "anvil", "signing_key_names": []interface{}{}, "listen_url": ""},
+				},
+			},
+			typed: map[string]interface{}{
+				"suppliers": []interface{}{
+					map[string]interface{}{"service_id": "anvil"},
+				},
+			},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := droppedFields("", tt.original, tt.typed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("droppedFields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+)
+
+// redactedSecret replaces the secrets scrubbed from the logs.
+const redactedSecret = "[REDACTED]"
+
+// mnemonicMinWords is the length of the shortest BIP-39 mnemonic.
+const mnemonicMinWords = 12
+
+var (
+	// armoredKeyPattern matches an ASCII-armored private key, up to the end of the line when it is cut short.
+	armoredKeyPattern = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?(?:-----END [A-Z0-9 ]*PRIVATE KEY-----|$)`)
+	// hexKeyPattern matches a 32-byte hex string, optionally 0x-prefixed, along with the field it is the value of.
+	hexKeyPattern = regexp.MustCompile(`(?i)([\w./-]*(?:hash|sha256|digest)[\w./-]*"?\s*[:=]\s*"?)?\b(?:0x)?[0-9a-f]{64}\b`)
+	// wordRunPattern matches runs of lowercase words long enough to be a mnemonic.
+	wordRunPattern = regexp.MustCompile(`\b[a-z]+(?:\s+[a-z]+){11,}\b`)
+	// credentialFieldPattern matches the value of a password, passphrase, token, authorization, api key or cookie
+	// field (like the authentication.password or an X-Api-Key header of a backend), quoted or not, along with the field.
+	credentialFieldPattern = regexp.MustCompile(`(?i)([\w.-]*(?:password|passphrase|token|authorization|api[_-]?key|cookie)\\?"?\s*[:=]\s*)(\\?"[^"\\]*\\?"|(?:(?:bearer|basic)\s+)?[^\s,;}\]"\\]+)`)
+	// headersPattern matches the headers of a backend, as a JSON object or a Go map, along with the field.
+	headersPattern = regexp.MustCompile(`(?i)(headers\\?"?\s*[:=]\s*)(\{[^{}]*\}|map\[[^\[\]]*\])`)
+	// urlUserInfoPattern matches the user info of a url.
+	urlUserInfoPattern = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^/\s"'@\\]+@`)
+	// webhookUrlPattern matches a webhook url, which embeds its token in the path (hooks.slack.com/services/...).
+	webhookUrlPattern = regexp.MustCompile(`(?i)\b(https?://[^/\s"'\\]+)(/[^\s"'\\]*)`)
+)
+
+// scrubSecrets redacts anything resembling a mnemonic, a hex private key or an armored private key from a log line,
+// along with the credentials of backends and webhooks: password, token and authorization fields, header values, the
+// user info of urls and the path of webhook urls. 32-byte hex values of fields named after hashes (config_hash,
+// sha256, ...) look like keys but are kept.
+func scrubSecrets(line string) string {
+	line = armoredKeyPattern.ReplaceAllString(line, redactedSecret)
+	line = scrubCredentials(line)
+
+	line = hexKeyPattern.ReplaceAllStringFunc(line, func(match string) string {
+		if field := hexKeyPattern.FindStringSubmatch(match)[1]; field != "" {
+			return match
+		}
+		return redactedSecret
+	})
+
+	return wordRunPattern.ReplaceAllStringFunc(line, func(run string) string {
+		consecutive := 0
+		for _, word := range strings.Fields(run) {
+			if _, ok := bip39.ReverseWordMap[word]; !ok {
+				consecutive = 0
+				continue
+			}
+			if consecutive++; consecutive >= mnemonicMinWords {
+				return redactedSecret
+			}
+		}
+		return run
+	})
+}
+
+// scrubCredentials redacts the credentials of backends and webhooks from a log line, keeping the quotes around the
+// redacted values so JSON lines stay valid.
+func scrubCredentials(line string) string {
+	line = headersPattern.ReplaceAllStringFunc(line, func(match string) string {
+		groups := headersPattern.FindStringSubmatch(match)
+		if strings.HasPrefix(groups[2], "{") {
+			return groups[1] + quoteLike(groups[1], redactedSecret)
+		}
+		return groups[1] + redactedSecret
+	})
+	line = credentialFieldPattern.ReplaceAllStringFunc(line, func(match string) string {
+		groups := credentialFieldPattern.FindStringSubmatch(match)
+		return groups[1] + redactedValue(groups[2])
+	})
+	line = urlUserInfoPattern.ReplaceAllString(line, "${1}"+redactedSecret+"@")
+	return webhookUrlPattern.ReplaceAllStringFunc(line, func(match string) string {
+		groups := webhookUrlPattern.FindStringSubmatch(match)
+		if !strings.Contains(strings.ToLower(match), "hook") {
+			return match
+		}
+		return groups[1] + "/" + redactedSecret
+	})
+}
+
+// redactedValue replaces value with redactedSecret, keeping its quotes.
+func redactedValue(value string) string {
+	switch {
+	case strings.HasPrefix(value, `\"`):
+		return `\"` + redactedSecret + `\"`
+	case strings.HasPrefix(value, `"`):
+		return `"` + redactedSecret + `"`
+	default:
+		return redactedSecret
+	}
+}
+
+// quoteLike quotes value the way field is quoted: escaped within a JSON string, plain, or not at all.
+func quoteLike(field, value string) string {
+	switch {
+	case strings.Contains(field, `\"`):
+		return `\"` + value + `\"`
+	case strings.Contains(field, `"`):
+		return `"` + value + `"`
+	default:
+		return value
+	}
+}
+
+// scrubbingWriter scrubs the secrets of every log line before writing it to out. zerolog hooks can add fields but
+// not rewrite the ones already set, so the scrubbing happens on the output, whatever the level and format: zerolog
+// and its ConsoleWriter write every event with a single Write.
+type scrubbingWriter struct {
+	out io.Writer
+}
+
+// Write implements io.Writer, reporting the length of p as written so zerolog doesn't see a short write.
+func (w scrubbingWriter) Write(p []byte) (int, error) {
+	line := string(p)
+	// keep the trailing newline away from the armored key pattern, which may run to the end of the line
+	trimmed := strings.TrimSuffix(line, "\n")
+	if _, err := io.WriteString(w.out, scrubSecrets(trimmed)+line[len(trimmed):]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/rs/zerolog/log"
+)
+
+// selfTestPayloadSize is the size of the random payload signed by every imported key.
+const selfTestPayloadSize = 32
+
+// selfTestKey signs a random payload with the key through the keyring and verifies the signature with the public key
+// stored for it, which must also derive the expected address.
+func selfTestKey(walletKeyring keyring.Keyring, key ImportedKey) error {
+	payload := make([]byte, selfTestPayloadSize)
+	if _, err := rand.Read(payload); err != nil {
+		return fmt.Errorf("unable to generate self-test payload: %w", err)
+	}
+
+	signature, _, err := walletKeyring.Sign(key.Name, payload, signing.SignMode_SIGN_MODE_DIRECT)
+	if err != nil {
+		return fmt.Errorf("%w: key %s can't sign: %w", ErrKeySelfTest, key.Name, err)
+	}
+
+	record, err := walletKeyring.Key(key.Name)
+	if err != nil {
+		return fmt.Errorf("%w: key %s can't be read back: %w", ErrKeySelfTest, key.Name, err)
+	}
+	pubKey, err := record.GetPubKey()
+	if err != nil {
+		return fmt.Errorf("%w: public key of key %s can't be read back: %w", ErrKeySelfTest, key.Name, err)
+	}
+	if address := sdk.AccAddress(pubKey.Address()).String(); address != key.Address {
+		return fmt.Errorf("%w: stored public key of key %s derives %s, expected %s", ErrKeySelfTest, key.Name, address, key.Address)
+	}
+	if !pubKey.VerifySignature(payload, signature) {
+		return fmt.Errorf("%w: signature of key %s doesn't verify with its stored public key", ErrKeySelfTest, key.Name)
+	}
+	return nil
+}
+
+// selfTestKeys runs selfTestKey on every imported key, catching corrupted imports and backend quirks before the
+// relayminer depends on the keys.
+func selfTestKeys(appConfig *AppConfig, walletKeyring keyring.Keyring, importedKeys []ImportedKey) error {
+	if !appConfig.KeySelfTest {
+		return nil
+	}
+	for _, key := range importedKeys {
+		if err := selfTestKey(walletKeyring, key); err != nil {
+			return err
+		}
+		keyLog.Debug().Str("name", key.Name).Msg("Key self-test passed")
+	}
+	log.Info().Int("keys", len(importedKeys)).Msg("Keys self-tested")
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the on-chain service check
+const (
+	ServiceCheckOff  string = "off"
+	ServiceCheckWarn string = "warn"
+	ServiceCheckFail string = "fail"
+)
+
+// registrationServiceIds returns the service IDs keys may be registered to: the suppliers of the relay miner config
+// and the literal (non-pattern) service IDs of the enabled entries, sorted.
+func registrationServiceIds(keys []WalletKeySpec, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) []string {
+	serviceIds := make([]string, 0)
+	if relayMinerConfig != nil {
+		for _, supplierConfig := range relayMinerConfig.Suppliers {
+			serviceIds = append(serviceIds, supplierConfig.ServiceId)
+		}
+	}
+	for _, entry := range keys {
+		if entry.Disabled {
+			continue
+		}
+		for _, serviceId := range entry.ServiceID {
+			if serviceIdPattern.MatchString(serviceId) {
+				serviceIds = append(serviceIds, serviceId)
+			}
+		}
+	}
+	slices.Sort(serviceIds)
+	return slices.Compact(serviceIds)
+}
+
+// checkServiceIds queries the service module of the chain node for every service ID keys may be registered to and
+// returns the ones that don't exist on chain. A typo like `ethh` otherwise only shows as a supplier serving zero
+// relays: reported with a warning, or failing the pass in ServiceCheckFail.
+func checkServiceIds(appConfig *AppConfig, chain *ChainClient, keys []WalletKeySpec, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]string, error) {
+	if appConfig.ServiceCheck == ServiceCheckOff {
+		return nil, nil
+	}
+	serviceIds := registrationServiceIds(keys, relayMinerConfig)
+	if len(serviceIds) == 0 {
+		return nil, nil
+	}
+
+	unknown := make([]string, 0)
+	for _, serviceId := range serviceIds {
+		found, err := chain.service(serviceId)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			log.Warn().Str("service_id", serviceId).Msg("Service not found on chain")
+			unknown = append(unknown, serviceId)
+		}
+	}
+
+	if len(unknown) == 0 {
+		log.Info().Int("services", len(serviceIds)).Msg("All services found on chain")
+		return unknown, nil
+	}
+	if appConfig.ServiceCheck == ServiceCheckFail {
+		return unknown, withExitCode(ExitValidationError, fmt.Errorf("%w: %v", ErrUnknownService, unknown))
+	}
+	log.Warn().Strs("service_ids", unknown).Msg("Services not found on chain, their suppliers won't serve relays")
+	return unknown, nil
+}
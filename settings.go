@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"text/tabwriter"
+)
+
+// Origins of a setting, from the highest precedence to the lowest
+const (
+	FlagOrigin             string = "flag"
+	EnvOrigin              string = "env"
+	DotEnvOrigin           string = ".env"
+	LoaderConfigFileOrigin string = "loader config file"
+	DefaultOrigin          string = "default"
+)
+
+// settingOrigins maps the settings given by the user to where they came from, settings missing from it are defaults.
+var settingOrigins = make(map[string]string)
+
+// resolvedSettings records the value every setting resolved to when the AppConfig was loaded, defaults included.
+var resolvedSettings = make(map[string]string)
+
+// EffectiveSetting is a setting as printed by `config show`.
+type EffectiveSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+// recordSetting records the value a setting resolved to and returns it.
+func recordSetting(key, value string) string {
+	resolvedSettings[key] = value
+	return value
+}
+
+// markSettingOrigins attributes the settings set since the last call (and not yet attributed) to origin. Empty
+// values count as unset, like in getenv.
+func markSettingOrigins(origin string) {
+	for _, setting := range settingFlags {
+		if _, ok := settingOrigins[setting.Env]; ok {
+			continue
+		}
+		if os.Getenv(setting.Env) != "" {
+			settingOrigins[setting.Env] = origin
+		}
+	}
+}
+
+// credentialSettings are the settings whose whole value is a credential: webhook urls carry their token in the path.
+var credentialSettings = map[string]bool{
+	"NOTIFY_WEBHOOK_URL": true,
+}
+
+// credentialSettingPattern matches the names of settings holding passwords, tokens or backend credentials.
+var credentialSettingPattern = regexp.MustCompile(`PASSWORD|PASSPHRASE|TOKEN|AUTH|CREDENTIAL|HEADER`)
+
+// redactSetting hides the credentials the value of setting name may embed. Credential settings are redacted whole;
+// urls keep their scheme, host and path, their user info and query values being redacted.
+func redactSetting(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if credentialSettings[name] || credentialSettingPattern.MatchString(name) {
+		return redactedSecret
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || (parsed.User == nil && parsed.RawQuery == "") {
+		return value
+	}
+	if parsed.User != nil {
+		parsed.User = url.User("xxxxx")
+	}
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for key := range query {
+			query[key] = []string{"xxxxx"}
+		}
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String()
+}
+
+// effectiveSettings lists every setting with its resolved value and origin, in the order of settingFlags.
+func effectiveSettings() []EffectiveSetting {
+	settings := make([]EffectiveSetting, 0, len(settingFlags))
+	for _, setting := range settingFlags {
+		value, ok := resolvedSettings[setting.Env]
+		if !ok {
+			value = os.Getenv(setting.Env)
+		}
+		origin, ok := settingOrigins[setting.Env]
+		if !ok {
+			origin = DefaultOrigin
+		}
+		settings = append(settings, EffectiveSetting{
+			Name:   setting.Env,
+			Value:  redactSetting(setting.Env, value),
+			Origin: origin,
+		})
+	}
+	return settings
+}
+
+// showConfig prints the effective settings and their origin, as a table or as JSON.
+func showConfig(asJSON bool) error {
+	settings := effectiveSettings()
+
+	if asJSON {
+		content, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal settings: %w", err)
+		}
+		_, err = fmt.Println(string(content))
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "SETTING\tVALUE\tORIGIN")
+	for _, setting := range settings {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", setting.Name, setting.Value, setting.Origin)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// shutdownContext returns a context canceled when the process receives SIGTERM or SIGINT.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+}
+
+// waitInFlight waits for the in-flight work reporting to done once a shutdown was requested, giving up after
+// ShutdownTimeout so the process exits within the pod's termination grace period.
+func waitInFlight(appConfig *AppConfig, done <-chan error) error {
+	log.Info().Dur("timeout", appConfig.ShutdownTimeout).Msg("Shutdown requested, waiting for the in-flight reconcile")
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(appConfig.ShutdownTimeout):
+		return fmt.Errorf("in-flight reconcile did not finish within %s", appConfig.ShutdownTimeout)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	supplierconfig "github.com/pokt-network/poktroll/x/supplier/config"
+	suppliertypes "github.com/pokt-network/poktroll/x/supplier/types"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// StakeEndpoint is a publicly exposed supplier endpoint written to the supplier stake configs.
+type StakeEndpoint struct {
+	PubliclyExposedUrl string `json:"publicly_exposed_url"`
+	// RPCType is the poktroll rpc type of the endpoint (e.g. JSON_RPC, REST, WEBSOCKET).
+	RPCType string `json:"rpc_type"`
+}
+
+// buildSupplierStakeConfig builds the supplier stake config of an operator key, owned by its owner address.
+// The full revenue share goes to the owner, matching the poktroll defaults.
+func buildSupplierStakeConfig(appConfig *AppConfig, entry WalletKeySpec, key ImportedKey) *supplierconfig.YAMLStakeConfig {
+	endpoints := make([]supplierconfig.YAMLServiceEndpoint, 0, len(entry.Endpoints))
+	for _, endpoint := range entry.Endpoints {
+		endpoints = append(endpoints, supplierconfig.YAMLServiceEndpoint{
+			PubliclyExposedUrl: endpoint.PubliclyExposedUrl,
+			RPCType:            endpoint.RPCType,
+		})
+	}
+
+	services := make([]*supplierconfig.YAMLStakeService, 0, len(key.ServiceIds))
+	for _, serviceId := range key.ServiceIds {
+		services = append(services, &supplierconfig.YAMLStakeService{
+			ServiceId: serviceId,
+			Endpoints: endpoints,
+		})
+	}
+
+	return &supplierconfig.YAMLStakeConfig{
+		OwnerAddress:    key.OwnerAddress,
+		OperatorAddress: key.Address,
+		StakeAmount:     appConfig.SupplierStakeAmount,
+		Services:        services,
+		DefaultRevSharePercent: map[string]uint64{
+			key.OwnerAddress: 100,
+		},
+	}
+}
+
+// writeSupplierStakeConfigs writes a `<operator_address>.yaml` supplier stake config for every operator key
+// registered to at least one service, validating each one with the poktroll stake config parser.
+func writeSupplierStakeConfigs(appConfig *AppConfig, keys []WalletKeySpec, importedKeys []ImportedKey) error {
+	if appConfig.SupplierStakeConfigOutputDir == "" {
+		return nil
+	}
+	if err := checkRunContext(appConfig); err != nil {
+		return err
+	}
+
+	if err := mkdirAllMode(appConfig.SupplierStakeConfigOutputDir, 0755); err != nil {
+		return fmt.Errorf("unable to create stake config output dir: %w", err)
+	}
+
+	written := 0
+	for _, key := range importedKeys {
+		if key.Role != OperatorRole {
+			continue
+		}
+		if len(key.ServiceIds) == 0 {
+			log.Warn().
+				Str("address", key.Address).
+				Msg("Skipping supplier stake config of operator key without service ids")
+			continue
+		}
+
+		stakeConfig := buildSupplierStakeConfig(appConfig, keys[key.EntryIndex], key)
+		content, err := yaml.Marshal(stakeConfig)
+		if err != nil {
+			return fmt.Errorf("unable to marshal stake config: %w", err)
+		}
+
+		if _, err := supplierconfig.ParseSupplierConfigs(context.Background(), content); err != nil {
+			log.Error().Err(err).Str("address", key.Address).Msg("Invalid supplier stake config")
+			return fmt.Errorf("invalid supplier stake config for %s: %w", key.Address, err)
+		}
+
+		path := filepath.Join(appConfig.SupplierStakeConfigOutputDir, key.Address+".yaml")
+		if err := writeFileAtomic(path, content, 0644); err != nil {
+			return fmt.Errorf("unable to write stake config: %w", err)
+		}
+		if err := auditConfigWritten(appConfig, path, content); err != nil {
+			return err
+		}
+
+		log.Debug().
+			Str("path", path).
+			Str("operator_address", key.Address).
+			Str("owner_address", key.OwnerAddress).
+			Msg("Supplier stake config written")
+		written++
+	}
+
+	log.Info().
+		Int("stake_configs", written).
+		Str("dir", appConfig.SupplierStakeConfigOutputDir).
+		Msg("Supplier stake configs written successfully")
+	return nil
+}
+
+// stakeSuppliers stakes a supplier for every operator key registered to at least one service that isn't staked yet,
+// turning the loader into a supplier bootstrapper: the stake-supplier transaction is built from the same stake config
+// as writeSupplierStakeConfigs, signed by the freshly imported operator key (which pays the stake) and broadcast to
+// the chain node, or written unsigned to UnsignedTxOutputDir. Suppliers already staked are left alone, their changes
+// are up to the operator.
+func stakeSuppliers(appConfig *AppConfig, chain *ChainClient, walletKeyring keyring.Keyring, keys []WalletKeySpec, importedKeys []ImportedKey) ([]ChainTx, error) {
+	if !appConfig.SupplierAutoStake {
+		return nil, nil
+	}
+
+	transactions := make([]ChainTx, 0)
+	for _, key := range importedKeys {
+		if key.Role != OperatorRole {
+			continue
+		}
+		if len(key.ServiceIds) == 0 {
+			log.Warn().
+				Str("address", key.Address).
+				Msg("Skipping supplier stake of operator key without service ids")
+			continue
+		}
+
+		supplier, err := chain.supplier(key.Address)
+		if err != nil {
+			return transactions, err
+		}
+		if supplier != nil {
+			keyLog.Debug().Str("address", key.Address).Msg("Supplier already staked")
+			continue
+		}
+
+		content, err := yaml.Marshal(buildSupplierStakeConfig(appConfig, keys[key.EntryIndex], key))
+		if err != nil {
+			return transactions, fmt.Errorf("unable to marshal stake config: %w", err)
+		}
+		stakeConfig, err := supplierconfig.ParseSupplierConfigs(appConfig.runContext(), content)
+		if err != nil {
+			log.Error().Err(err).Str("address", key.Address).Msg("Invalid supplier stake config")
+			return transactions, withExitCode(ExitValidationError, fmt.Errorf("invalid supplier stake config for %s: %w", key.Address, err))
+		}
+
+		msg := suppliertypes.NewMsgStakeSupplier(key.Address, stakeConfig.OwnerAddress, stakeConfig.OperatorAddress, stakeConfig.StakeAmount, stakeConfig.Services)
+		chainTx, err := chain.broadcastTx(walletKeyring, key.Name, TxStakeSupplier, msg)
+		if chainTx.Hash != "" || chainTx.File != "" {
+			transactions = append(transactions, chainTx)
+		}
+		if err != nil {
+			return transactions, fmt.Errorf("error staking supplier %s: %w", key.Address, err)
+		}
+		if chainTx.File != "" {
+			continue
+		}
+		log.Info().
+			Str("operator_address", key.Address).
+			Str("owner_address", stakeConfig.OwnerAddress).
+			Str("stake", stakeConfig.StakeAmount.String()).
+			Strs("service_ids", key.ServiceIds).
+			Str("hash", chainTx.Hash).
+			Msg("Supplier staked")
+	}
+	return transactions, nil
+}
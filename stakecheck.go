@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes of the supplier stake check
+const (
+	StakeCheckOff  string = "off"
+	StakeCheckWarn string = "warn"
+	StakeCheckFail string = "fail"
+)
+
+// StakeMismatch is an operator key whose supplier stake on chain differs from what keys.json registers it for.
+type StakeMismatch struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// Staked is unset when the operator has no supplier on chain, Unstaking when its supplier is unbonding.
+	Staked    bool `json:"staked"`
+	Unstaking bool `json:"unstaking,omitempty"`
+	// MissingServices are registered to the key but not staked for, UnregisteredServices staked for but not
+	// registered to the key.
+	MissingServices      []string `json:"missing_services,omitempty"`
+	UnregisteredServices []string `json:"unregistered_services,omitempty"`
+	// OwnerAddress is the owner of the supplier on chain, set when it isn't the owner_address of the entry.
+	OwnerAddress string `json:"owner_address,omitempty"`
+}
+
+// registeredServices returns the service IDs an operator key signs for: its own, or every supplier of the generated
+// relay miner config for default signing keys.
+func registeredServices(importedKey ImportedKey, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) []string {
+	if len(importedKey.ServiceIds) > 0 || relayMinerConfig == nil {
+		return importedKey.ServiceIds
+	}
+	serviceIds := make([]string, 0, len(relayMinerConfig.Suppliers))
+	for _, supplierConfig := range relayMinerConfig.Suppliers {
+		serviceIds = append(serviceIds, supplierConfig.ServiceId)
+	}
+	return serviceIds
+}
+
+// checkSupplierStakes queries the supplier module of the chain node for the supplier of every imported operator key
+// and returns the keys whose stake doesn't match their registration: not staked, unstaking, staked for other
+// services or owned by another owner. Reported with a warning, or failing the pass in StakeCheckFail.
+func checkSupplierStakes(appConfig *AppConfig, chain *ChainClient, importedKeys []ImportedKey, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) ([]StakeMismatch, error) {
+	if appConfig.StakeCheck == StakeCheckOff {
+		return nil, nil
+	}
+
+	operatorKeys := make([]ImportedKey, 0, len(importedKeys))
+	for _, importedKey := range importedKeys {
+		if importedKey.Role == OperatorRole {
+			operatorKeys = append(operatorKeys, importedKey)
+		}
+	}
+	if len(operatorKeys) == 0 {
+		return nil, nil
+	}
+
+	mismatches := make([]StakeMismatch, 0)
+	for _, importedKey := range operatorKeys {
+		mismatch := StakeMismatch{Name: importedKey.Name, Address: importedKey.Address}
+		services := registeredServices(importedKey, relayMinerConfig)
+
+		supplier, err := chain.supplier(importedKey.Address)
+		switch {
+		case err != nil:
+			return nil, err
+		case supplier == nil:
+			mismatch.MissingServices = services
+			keyLog.Warn().
+				Str("name", importedKey.Name).
+				Str("address", importedKey.Address).
+				Strs("service_ids", services).
+				Msg("Operator key not staked as a supplier")
+			mismatches = append(mismatches, mismatch)
+			continue
+		}
+
+		mismatch.Staked = true
+		mismatch.Unstaking = supplier.UnstakeSessionEndHeight > 0
+		stakedServices := make([]string, 0, len(supplier.Services))
+		for _, service := range supplier.Services {
+			stakedServices = append(stakedServices, service.ServiceId)
+		}
+		for _, serviceId := range services {
+			if !slices.Contains(stakedServices, serviceId) {
+				mismatch.MissingServices = append(mismatch.MissingServices, serviceId)
+			}
+		}
+		for _, serviceId := range stakedServices {
+			if !slices.Contains(services, serviceId) {
+				mismatch.UnregisteredServices = append(mismatch.UnregisteredServices, serviceId)
+			}
+		}
+		if importedKey.OwnerAddress != "" && supplier.OwnerAddress != importedKey.OwnerAddress {
+			mismatch.OwnerAddress = supplier.OwnerAddress
+		}
+
+		if !mismatch.Unstaking && len(mismatch.MissingServices) == 0 && len(mismatch.UnregisteredServices) == 0 && mismatch.OwnerAddress == "" {
+			keyLog.Debug().Str("name", importedKey.Name).Strs("service_ids", stakedServices).Msg("Supplier stake matches the key")
+			continue
+		}
+		keyLog.Warn().
+			Str("name", importedKey.Name).
+			Str("address", importedKey.Address).
+			Bool("unstaking", mismatch.Unstaking).
+			Strs("missing_services", mismatch.MissingServices).
+			Strs("unregistered_services", mismatch.UnregisteredServices).
+			Str("owner_address", mismatch.OwnerAddress).
+			Msg("Supplier stake doesn't match the key")
+		mismatches = append(mismatches, mismatch)
+	}
+
+	if len(mismatches) == 0 {
+		log.Info().Int("keys", len(operatorKeys)).Msg("All operator keys staked for their services")
+		return mismatches, nil
+	}
+	if appConfig.StakeCheck == StakeCheckFail {
+		return mismatches, withExitCode(ExitValidationError, fmt.Errorf("%w: %d operator keys", ErrStakeMismatch, len(mismatches)))
+	}
+	log.Warn().Int("keys", len(mismatches)).Msg("Supplier stakes don't match the keys")
+	return mismatches, nil
+}
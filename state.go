@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+// State records the last successful pass, so later passes with the same inputs can be skipped.
+// It carries hashes and addresses only, never key material.
+type State struct {
+	AppliedAt time.Time `json:"applied_at"`
+	// InputsHash is the SHA-256 of the settings, the selected keys.json entries and the base relay miner config.
+	InputsHash string `json:"inputs_hash"`
+	// KeysHash and RelayMinerConfigHash are the SHA-256 of the keys.json entries and the base relay miner config
+	// alone, telling which input changed.
+	KeysHash             string        `json:"keys_hash,omitempty"`
+	RelayMinerConfigHash string        `json:"relayminer_config_hash,omitempty"`
+	Keys                 []ImportedKey `json:"keys"`
+	// ConfigHash is the SHA-256 of the generated relay miner config (empty when generation is disabled).
+	ConfigHash string `json:"config_hash,omitempty"`
+	// ConfigOutputPath is the rendered output file of the generated config, for the file target.
+	ConfigOutputPath string `json:"config_output_path,omitempty"`
+}
+
+// Inputs compared by InputsDrift
+const (
+	InputKeys             string = "keys"
+	InputRelayMinerConfig string = "relayminer_config"
+	InputSettings         string = "settings"
+)
+
+// InputsDrift compares a pass with the last pass recorded in the state file.
+type InputsDrift struct {
+	LastAppliedAt time.Time `json:"last_applied_at"`
+	// InputsChanged lists the inputs that changed since the last pass, settings meaning the keys and the base relay
+	// miner config are the same.
+	InputsChanged []string `json:"inputs_changed"`
+	// KeyringInSync tells whether the keyring and the published config still match the last-applied inputs, Drift
+	// telling why not.
+	KeyringInSync bool   `json:"keyring_in_sync"`
+	Drift         string `json:"drift,omitempty"`
+}
+
+// changedInputs lists the inputs that differ from the ones recorded in state. The per-input hashes are missing from
+// the states of older loaders, the inputs are then only known to have changed as a whole.
+func changedInputs(state *State, inputs ReportInputs) []string {
+	changed := make([]string, 0)
+	if state.InputsHash == inputs.Hash {
+		return changed
+	}
+	if state.KeysHash != "" && state.KeysHash != inputs.Keys {
+		changed = append(changed, InputKeys)
+	}
+	if state.RelayMinerConfigHash != "" && state.RelayMinerConfigHash != inputs.RelayMinerConfig {
+		changed = append(changed, InputRelayMinerConfig)
+	}
+	if len(changed) == 0 {
+		changed = append(changed, InputSettings)
+	}
+	return changed
+}
+
+// inputsHash returns the hash of everything a pass derives its outputs from.
+func inputsHash(appConfig *AppConfig, keys []WalletKeySpec, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) (string, error) {
+	relayMinerConfigContent, err := yaml.Marshal(relayMinerConfig)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal RelayMiner config: %w", err)
+	}
+
+	content, err := json.Marshal(struct {
+		Settings         *AppConfig
+		Keys             []WalletKeySpec
+		RelayMinerConfig []byte
+	}{appConfig, keys, relayMinerConfigContent})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal pass inputs: %w", err)
+	}
+	return contentHash(content), nil
+}
+
+// loadState reads the state file, returning nil when there is none yet.
+func loadState(path string) (*State, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state file '%s': %w", path, err)
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("unable to parse state file '%s': %w", path, err)
+	}
+	return state, nil
+}
+
+// stateDrift returns why the keyring or the published config no longer match the recorded state, or an empty
+// string when they still do.
+func stateDrift(appConfig *AppConfig, state *State, walletKeyring keyring.Keyring) (string, error) {
+	records, err := walletKeyring.List()
+	if err != nil {
+		return "", fmt.Errorf("error listing keyring keys: %w", err)
+	}
+	addresses := make([]string, 0, len(records))
+	for _, record := range records {
+		address, err := record.GetAddress()
+		if err != nil {
+			return "", fmt.Errorf("error reading address of key %s: %w", record.Name, err)
+		}
+		addresses = append(addresses, address.String())
+	}
+	for _, key := range state.Keys {
+		if !slices.Contains(addresses, key.Address) {
+			return fmt.Sprintf("key %s is missing from the keyring", key.Address), nil
+		}
+	}
+
+	if !appConfig.GenerateRelayMinerConfig {
+		return "", nil
+	}
+	var content []byte
+	if appConfig.RelayMinerConfigOutputTarget == FileSource {
+		content, err = os.ReadFile(state.ConfigOutputPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("unable to read relay miner config output '%s': %w", state.ConfigOutputPath, err)
+		}
+	} else {
+		content, err = readRelayMinerConfigResource(appConfig)
+		if err != nil {
+			return "", err
+		}
+	}
+	if content == nil || contentHash(content) != state.ConfigHash {
+		return "relay miner config output changed", nil
+	}
+	return "", nil
+}
+
+// checkState compares the inputs of the pass with the state file, and the keyring and published config with the
+// last-applied inputs. It returns how they compare, nil without a state file or recorded pass, and the recorded state
+// when the inputs are unchanged and nothing drifted so the pass can be skipped.
+func checkState(appConfig *AppConfig, inputs ReportInputs, walletKeyring keyring.Keyring) (*State, *InputsDrift, error) {
+	if appConfig.StateFilePath == "" {
+		return nil, nil, nil
+	}
+
+	state, err := loadState(appConfig.StateFilePath)
+	if err != nil || state == nil {
+		return nil, nil, err
+	}
+
+	drift := &InputsDrift{LastAppliedAt: state.AppliedAt, InputsChanged: changedInputs(state, inputs)}
+	drift.Drift, err = stateDrift(appConfig, state, walletKeyring)
+	if err != nil {
+		return nil, nil, err
+	}
+	drift.KeyringInSync = drift.Drift == ""
+
+	if len(drift.InputsChanged) > 0 {
+		log.Info().Strs("inputs_changed", drift.InputsChanged).Time("applied_at", state.AppliedAt).Msg("Inputs changed since the last pass")
+	}
+	if !drift.KeyringInSync {
+		log.Warn().Str("drift", drift.Drift).Msg("Outputs drifted from the recorded state, reconciling")
+	}
+	if len(drift.InputsChanged) > 0 || !drift.KeyringInSync {
+		return nil, drift, nil
+	}
+	return state, drift, nil
+}
+
+// saveState records a successful pass in the state file.
+func saveState(appConfig *AppConfig, inputs ReportInputs, importedKeys []ImportedKey, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	if appConfig.StateFilePath == "" {
+		return nil
+	}
+	if err := checkRunContext(appConfig); err != nil {
+		return err
+	}
+
+	state := State{
+		AppliedAt:            time.Now().UTC(),
+		InputsHash:           inputs.Hash,
+		KeysHash:             inputs.Keys,
+		RelayMinerConfigHash: inputs.RelayMinerConfig,
+		Keys:                 importedKeys,
+	}
+	if appConfig.GenerateRelayMinerConfig && relayMinerConfig != nil {
+		content, err := marshalRelayMinerConfig(appConfig.RelayMinerOutputFormat, relayMinerConfig)
+		if err != nil {
+			return err
+		}
+		state.ConfigHash = contentHash(content)
+		if appConfig.RelayMinerConfigOutputTarget == FileSource {
+			// the path the pass wrote to, templated paths render to a new one on every generation
+			state.ConfigOutputPath = appConfig.configOutputPath
+			if state.ConfigOutputPath == "" {
+				state.ConfigOutputPath, err = renderOutputPath(appConfig.RelayMinerConfigFileOutputPath, content)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal state: %w", err)
+	}
+	if err := writeFileAtomic(appConfig.StateFilePath, content, 0600); err != nil {
+		return fmt.Errorf("unable to write state file: %w", err)
+	}
+	log.Debug().Str("path", appConfig.StateFilePath).Msg("State file written")
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StatusCommand prints the status of a running loader, read from its status socket.
+const StatusCommand string = "status"
+
+// statusClientTimeout bounds a status request.
+const statusClientTimeout = 5 * time.Second
+
+// Status is the state of a long-lived loader, as served on the status socket.
+type Status struct {
+	Mode      string    `json:"mode"`
+	StartedAt time.Time `json:"started_at"`
+	// Running is set while a pass is in flight.
+	Running bool `json:"running"`
+	// Pending is set when a change (or resync) was detected and its pass hasn't started yet.
+	Pending       bool   `json:"pending"`
+	PendingReason string `json:"pending_reason,omitempty"`
+
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastDuration  string     `json:"last_duration,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	KeysImported  int        `json:"keys_imported"`
+	LastRunReason string     `json:"last_run_reason,omitempty"`
+}
+
+// statusTracker records the Status of the process. A nil tracker records nothing, so passes outside of the
+// long-lived modes don't need one.
+type statusTracker struct {
+	mu     sync.Mutex
+	status Status
+}
+
+// newStatusTracker creates the tracker of a loader running in mode.
+func newStatusTracker(mode string) *statusTracker {
+	return &statusTracker{status: Status{Mode: mode, StartedAt: time.Now().UTC()}}
+}
+
+// pending records a change waiting for its pass.
+func (t *statusTracker) pending(reason string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Pending = true
+	t.status.PendingReason = reason
+}
+
+// started records the start of a pass, consuming the pending change.
+func (t *statusTracker) started(reason string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now().UTC()
+	t.status.Running = true
+	t.status.Pending = false
+	t.status.PendingReason = ""
+	t.status.LastRunAt = &now
+	t.status.LastRunReason = reason
+}
+
+// completed records the keys of a successful pass, see signalCompletion.
+func (t *statusTracker) completed(keys int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.KeysImported = keys
+}
+
+// finished records the outcome of a pass.
+func (t *statusTracker) finished(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now().UTC()
+	t.status.Running = false
+	t.status.Runs++
+	if t.status.LastRunAt != nil {
+		t.status.LastDuration = now.Sub(*t.status.LastRunAt).Round(time.Millisecond).String()
+	}
+	if err != nil {
+		t.status.Failures++
+		t.status.LastError = err.Error()
+		t.status.LastErrorAt = &now
+		return
+	}
+	t.status.LastSuccessAt = &now
+}
+
+// live fails when a pass has been running for longer than stuckAfter (0 disables the check), the loop being stuck.
+func (t *statusTracker) live(stuckAfter time.Duration) error {
+	status := t.snapshot()
+	if stuckAfter > 0 && status.Running && status.LastRunAt != nil {
+		if running := time.Since(*status.LastRunAt); running > stuckAfter {
+			return fmt.Errorf("pass running for %s, more than %s", running.Round(time.Second), stuckAfter)
+		}
+	}
+	return nil
+}
+
+// ready fails until a pass succeeded, and while the last finished pass failed.
+func (t *statusTracker) ready() error {
+	status := t.snapshot()
+	if status.LastSuccessAt == nil {
+		return errors.New("no pass succeeded yet")
+	}
+	if status.LastErrorAt != nil && status.LastErrorAt.After(*status.LastSuccessAt) {
+		return fmt.Errorf("last pass failed: %s", status.LastError)
+	}
+	return nil
+}
+
+// snapshot returns a copy of the current Status.
+func (t *statusTracker) snapshot() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// serveStatus serves the tracker's Status as JSON on `GET /status` over the unix socket at path, replacing a stale
+// socket left by a previous process. The returned function stops the server and removes the socket.
+func serveStatus(path string, tracker *statusTracker) (func(), error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to remove stale status socket '%s': %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on status socket '%s': %w", path, err)
+	}
+	// the status carries no secrets, but only the loader's user needs it
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("unable to restrict status socket '%s': %w", path, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tracker.snapshot())
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		log.Info().Str("path", path).Msg("Serving status")
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Status server failed")
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), statusClientTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		_ = os.Remove(path)
+	}, nil
+}
+
+// fetchStatus reads the Status of the loader serving the status socket at path.
+func fetchStatus(path string) (*Status, error) {
+	client := &http.Client{
+		Timeout: statusClientTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+
+	response, err := client.Get("http://loader/status")
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach the loader on status socket '%s': %w", path, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read status: %w", err)
+	}
+	status := &Status{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, fmt.Errorf("unable to parse status: %w", err)
+	}
+	return status, nil
+}
+
+// statusCommand runs the status subcommand: it prints the status of the running loader as JSON.
+func statusCommand(appConfig *AppConfig) error {
+	if appConfig.StatusSocketPath == "" {
+		return withExitCode(ExitConfigError, errors.New("status needs STATUS_SOCKET_PATH"))
+	}
+
+	status, err := fetchStatus(appConfig.StatusSocketPath)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal status: %w", err)
+	}
+	_, err = fmt.Println(string(content))
+	return err
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Modes for suppliers left without signing keys
+const (
+	EmptySupplierWarn string = "warn"
+	EmptySupplierFail string = "fail"
+)
+
+// SupplierOverrides are supplier fields a key entry may override for the services its keys serve.
+// Fields left unset keep the value of the base config.
+type SupplierOverrides struct {
+	RequestTimeoutSeconds *uint64 `json:"request_timeout_seconds,omitempty"`
+	MaxBodySize           *string `json:"max_body_size,omitempty"`
+	XForwardedHostLookup  *bool   `json:"x_forwarded_host_lookup,omitempty"`
+}
+
+// applySupplierOverrides applies the supplier overrides of a key entry to every supplier matched by its service IDs.
+// When several entries override the same supplier the last one wins, which is reported with a warning;
+// overridden tracks the `<service_id>.<field>` already overridden during the run.
+func applySupplierOverrides(appConfig *AppConfig, index int, entry WalletKeySpec, overridden map[string]bool, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	overrides := entry.SupplierOverrides
+	if !appConfig.GenerateRelayMinerConfig || overrides == nil {
+		return nil
+	}
+	if len(entry.ServiceID) == 0 {
+		return fmt.Errorf("invalid entry index %d: supplier_overrides requires at least one service id", index)
+	}
+
+	for _, serviceId := range entry.ServiceID {
+		matches, err := newServiceIdMatcher(serviceId)
+		if err != nil {
+			return err
+		}
+
+		for j := range relayMinerConfig.Suppliers {
+			supplierConfig := &relayMinerConfig.Suppliers[j]
+			if !matches(supplierConfig.ServiceId) {
+				continue
+			}
+
+			if overrides.RequestTimeoutSeconds != nil {
+				warnOverriddenField(overridden, supplierConfig.ServiceId, "request_timeout_seconds", supplierConfig.RequestTimeoutSeconds, *overrides.RequestTimeoutSeconds)
+				supplierConfig.RequestTimeoutSeconds = *overrides.RequestTimeoutSeconds
+			}
+			if overrides.MaxBodySize != nil {
+				warnOverriddenField(overridden, supplierConfig.ServiceId, "max_body_size", supplierConfig.MaxBodySize, *overrides.MaxBodySize)
+				supplierConfig.MaxBodySize = *overrides.MaxBodySize
+			}
+			if overrides.XForwardedHostLookup != nil {
+				warnOverriddenField(overridden, supplierConfig.ServiceId, "x_forwarded_host_lookup", supplierConfig.XForwardedHostLookup, *overrides.XForwardedHostLookup)
+				supplierConfig.XForwardedHostLookup = *overrides.XForwardedHostLookup
+			}
+
+			log.Debug().
+				Int("index", index).
+				Str("service_id", supplierConfig.ServiceId).
+				Msg("Applied supplier overrides")
+		}
+	}
+
+	return nil
+}
+
+// warnOverriddenField warns when a supplier field overridden by a previous entry is overridden again with another value.
+func warnOverriddenField[T comparable](overridden map[string]bool, serviceId, field string, current, value T) {
+	key := serviceId + "." + field
+	if overridden[key] && current != value {
+		log.Warn().
+			Str("service_id", serviceId).
+			Str("field", field).
+			Interface("previous", current).
+			Interface("value", value).
+			Msg("Supplier field overridden by more than one key entry, the last one wins")
+	}
+	overridden[key] = true
+}
+
+// emptySuppliers returns the service IDs of the suppliers that have no signing key to use.
+// A supplier without its own signing_key_names falls back to default_signing_key_names on the relayminer,
+// so it is only considered empty when both lists are empty.
+func emptySuppliers(relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) []string {
+	serviceIds := make([]string, 0)
+
+	if len(relayMinerConfig.DefaultSigningKeyNames) > 0 {
+		return serviceIds
+	}
+
+	for _, supplierConfig := range relayMinerConfig.Suppliers {
+		if len(supplierConfig.SigningKeyNames) == 0 {
+			serviceIds = append(serviceIds, supplierConfig.ServiceId)
+		}
+	}
+
+	return serviceIds
+}
+
+// ServiceCoverage is the number of signing keys a supplier of the relay miner config signs with.
+type ServiceCoverage struct {
+	ServiceId   string `json:"service_id"`
+	SigningKeys int    `json:"signing_keys"`
+	// DefaultKeys is set when the supplier has no signing_key_names of its own and signs with the
+	// default_signing_key_names.
+	DefaultKeys bool `json:"default_keys,omitempty"`
+}
+
+// serviceCoverage returns the signing keys of every supplier of relayMinerConfig, the way the relayminer resolves them.
+func serviceCoverage(relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) []ServiceCoverage {
+	coverage := make([]ServiceCoverage, 0, len(relayMinerConfig.Suppliers))
+	for _, supplierConfig := range relayMinerConfig.Suppliers {
+		if len(supplierConfig.SigningKeyNames) > 0 {
+			coverage = append(coverage, ServiceCoverage{ServiceId: supplierConfig.ServiceId, SigningKeys: len(supplierConfig.SigningKeyNames)})
+			continue
+		}
+		coverage = append(coverage, ServiceCoverage{
+			ServiceId:   supplierConfig.ServiceId,
+			SigningKeys: len(relayMinerConfig.DefaultSigningKeyNames),
+			DefaultKeys: true,
+		})
+	}
+	return coverage
+}
+
+// recordServiceCoverage logs and exports the signing keys of every supplier and records them in report, so capacity
+// planning can see the key coverage of every service served.
+func recordServiceCoverage(appConfig *AppConfig, report *RunReport, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) {
+	if !appConfig.GenerateRelayMinerConfig {
+		return
+	}
+
+	report.Services = serviceCoverage(relayMinerConfig)
+	report.SuppliersWithoutKeys = emptySuppliers(relayMinerConfig)
+	recordServiceMetrics(report.Services)
+	for _, coverage := range report.Services {
+		keyLog.Info().
+			Str("service_id", coverage.ServiceId).
+			Int("signing_keys", coverage.SigningKeys).
+			Bool("default_keys", coverage.DefaultKeys).
+			Msg("Service signing keys")
+	}
+}
+
+// checkEmptySuppliers reports suppliers left without signing keys after registration.
+// Depending on EmptySupplierMode it either logs a warning or returns an error.
+func checkEmptySuppliers(appConfig *AppConfig, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	if !appConfig.GenerateRelayMinerConfig {
+		return nil
+	}
+
+	serviceIds := emptySuppliers(relayMinerConfig)
+	if len(serviceIds) == 0 {
+		log.Debug().Msg("All suppliers have signing keys")
+		return nil
+	}
+
+	if appConfig.EmptySupplierMode == EmptySupplierFail {
+		log.Error().Strs("service_ids", serviceIds).Msg("Suppliers left without signing keys")
+		return fmt.Errorf("%w: %v", ErrSuppliersWithoutKeys, serviceIds)
+	}
+
+	log.Warn().
+		Strs("service_ids", serviceIds).
+		Msg("Suppliers left without signing keys, the relayminer will reject these services")
+	return nil
+}
+
+// probeBackend checks whether a supplier backend is reachable within the given timeout.
+// HTTP(S) backends receive a HEAD request, where any HTTP response (even 4xx/5xx) counts as reachable.
+// Any other scheme (ws, tcp, grpc, ...) is probed by opening a TCP connection to its host, on the default port of
+// the scheme when the url has none (see defaultBackendPorts).
+func probeBackend(ctx context.Context, supplierConfig poktrollconfig.YAMLRelayMinerSupplierConfig, timeout time.Duration) error {
+	serviceConfig := supplierConfig.ServiceConfig
+
+	backendUrl, err := url.Parse(serviceConfig.BackendUrl)
+	if err != nil {
+		return fmt.Errorf("invalid backend url: %w", err)
+	}
+	if backendUrl.Host == "" {
+		return fmt.Errorf("backend url has no host: %s", serviceConfig.BackendUrl)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch backendUrl.Scheme {
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, backendUrl.String(), nil)
+		if err != nil {
+			return err
+		}
+		for header, value := range serviceConfig.Headers {
+			req.Header.Set(header, value)
+		}
+		if serviceConfig.Authentication.Username != "" {
+			req.SetBasicAuth(serviceConfig.Authentication.Username, serviceConfig.Authentication.Password)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	default:
+		address, err := backendAddress(backendUrl)
+		if err != nil {
+			return err
+		}
+
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// defaultBackendPorts are the ports dialed for backend urls without one, by scheme. gRPC backends are usually served
+// with TLS behind an ingress, so grpc defaults to 443 like grpcs.
+var defaultBackendPorts = map[string]string{
+	"ws":    "80",
+	"wss":   "443",
+	"grpc":  "443",
+	"grpcs": "443",
+}
+
+// backendAddress returns the host:port a TCP probe of backendUrl dials, defaulting the port from its scheme.
+func backendAddress(backendUrl *url.URL) (string, error) {
+	port := backendUrl.Port()
+	if port == "" {
+		port = defaultBackendPorts[backendUrl.Scheme]
+	}
+	if port == "" {
+		return "", fmt.Errorf("backend url has no port for scheme %s: %s", backendUrl.Scheme, backendUrl.Redacted())
+	}
+	return net.JoinHostPort(backendUrl.Hostname(), port), nil
+}
+
+// preflightSupplierBackends probes the backend_url of every supplier and reports the unreachable ones.
+// Unreachable backends are logged as warnings unless BackendPreflightFail is set, in which case an error is returned.
+func preflightSupplierBackends(appConfig *AppConfig, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) error {
+	if !appConfig.GenerateRelayMinerConfig || !appConfig.BackendPreflight {
+		return nil
+	}
+
+	log.Info().
+		Int("suppliers", len(relayMinerConfig.Suppliers)).
+		Dur("timeout", appConfig.BackendPreflightTimeout).
+		Msg("Probing supplier backends")
+
+	unreachable := make([]string, 0)
+	for _, supplierConfig := range relayMinerConfig.Suppliers {
+		err := probeBackend(appConfig.runContext(), supplierConfig, appConfig.BackendPreflightTimeout)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("service_id", supplierConfig.ServiceId).
+				Str("backend_url", supplierConfig.ServiceConfig.BackendUrl).
+				Msg("Supplier backend is unreachable")
+			unreachable = append(unreachable, supplierConfig.ServiceId)
+			continue
+		}
+
+		log.Debug().
+			Str("service_id", supplierConfig.ServiceId).
+			Str("backend_url", supplierConfig.ServiceConfig.BackendUrl).
+			Msg("Supplier backend is reachable")
+	}
+
+	if len(unreachable) > 0 && appConfig.BackendPreflightFail {
+		return fmt.Errorf("unreachable supplier backends: %v", unreachable)
+	}
+
+	log.Info().Int("unreachable", len(unreachable)).Msg("Supplier backends probed")
+	return nil
+}
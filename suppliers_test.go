@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBackendAddress(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "ws://anvil/", want: "anvil:80"},
+		{url: "wss://anvil.example.com/rpc", want: "anvil.example.com:443"},
+		{url: "grpc://cosmos-node", want: "cosmos-node:443"},
+		{url: "grpcs://cosmos-node", want: "cosmos-node:443"},
+		{url: "grpc://cosmos-node:9090", want: "cosmos-node:9090"},
+		{url: "ws://[::1]:8546", want: "[::1]:8546"},
+		{url: "wss://[::1]/", want: "[::1]:443"},
+		{url: "tcp://backend:26657", want: "backend:26657"},
+		{url: "tcp://backend", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			backendUrl, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := backendAddress(backendUrl)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("backendAddress(%q) = %q, want an error", tt.url, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("backendAddress(%q): %v", tt.url, err)
+			}
+			if got != tt.want {
+				t.Errorf("backendAddress(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
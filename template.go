@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// relayMinerConfigTemplateResource is the RelayMinerConfigTemplate custom resource (see deploy/crds).
+var relayMinerConfigTemplateResource = schema.GroupVersionResource{
+	Group:    "keyring.pokt.network",
+	Version:  "v1alpha1",
+	Resource: "relayminerconfigtemplates",
+}
+
+// KeySelection restricts the keys.json entries used to render a relay miner config. Empty fields select everything.
+type KeySelection struct {
+	// Entries keeps the keys.json entries at these indexes.
+	Entries []int `json:"entries,omitempty"`
+	// Roles keeps the entries with one of these roles.
+	Roles []string `json:"roles,omitempty"`
+	// ServiceIds keeps only these service IDs (or patterns) on every entry, entries left without any are dropped.
+	ServiceIds []string `json:"serviceIds,omitempty"`
+}
+
+// RelayMinerConfigTemplateSpec is the desired state of a RelayMinerConfigTemplate.
+type RelayMinerConfigTemplateSpec struct {
+	// Config is the base relay miner config, in the same schema as config.yaml.
+	Config       map[string]interface{} `json:"config"`
+	KeysSecret   ResourceKeyRef         `json:"keysSecret"`
+	KeySelection *KeySelection          `json:"keySelection,omitempty"`
+	Keyring      WalletKeyImportKeyring `json:"keyring,omitempty"`
+	// Target is configmap (default) or secret.
+	Target string         `json:"target,omitempty"`
+	Output ResourceKeyRef `json:"output"`
+}
+
+// RelayMinerConfigTemplate declares a base relay miner config and the keys registered in it; the operator renders it
+// and publishes the generated config to a ConfigMap or Secret.
+type RelayMinerConfigTemplate struct {
+	v1.TypeMeta   `json:",inline"`
+	v1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RelayMinerConfigTemplateSpec `json:"spec"`
+	Status ReconcileStatus              `json:"status,omitempty"`
+}
+
+// relayMinerConfigTemplateConfig derives the AppConfig of a single pass from the operator's AppConfig and a
+// RelayMinerConfigTemplate spec. Settings the resource doesn't declare are inherited from the operator, its outputs
+// scoped to the resource. Like a WalletKeyImport's, its references are confined to its namespace and its keyring dir
+// under the operator's.
+func relayMinerConfigTemplateConfig(appConfig *AppConfig, resource *RelayMinerConfigTemplate) (*AppConfig, error) {
+	spec := resource.Spec
+	if spec.KeysSecret.Name == "" || spec.Output.Name == "" {
+		return nil, fmt.Errorf("spec.keysSecret.name and spec.output.name are required")
+	}
+	if len(spec.Config) == 0 {
+		return nil, fmt.Errorf("spec.config is required")
+	}
+
+	configData, err := yaml.Marshal(spec.Config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal spec.config: %w", err)
+	}
+
+	config := *appConfig
+	config.ConfigSource = KubernetesSource
+
+	config.KeysNamespace, err = resourceNamespace("spec.keysSecret.namespace", spec.KeysSecret.Namespace, resource.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	config.KeysSecretName = spec.KeysSecret.Name
+	config.KeysSecretKey = orDefault(spec.KeysSecret.Key, appConfig.KeysSecretKey)
+	config.KeySelection = spec.KeySelection
+
+	config.KeyringDir, err = resourceKeyringDir(appConfig, "spec.keyring.dir", resource.Namespace, resource.Name, spec.Keyring.Dir)
+	if err != nil {
+		return nil, err
+	}
+	config.KeyringBackend = orDefault(spec.Keyring.Backend, appConfig.KeyringBackend)
+	config.KeyringAppName = orDefault(spec.Keyring.AppName, appConfig.KeyringAppName)
+	scopeOutputs(&config, appConfig, resourceScope("RelayMinerConfigTemplate", resource.Namespace, resource.Name))
+
+	config.GenerateRelayMinerConfig = true
+	config.RelayMinerConfigData = configData
+	config.RelayMinerConfigOutputTarget = orDefault(spec.Target, ConfigMapSource)
+	config.RelayMinerConfigOutputNamespace, err = resourceNamespace("spec.output.namespace", spec.Output.Namespace, resource.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	config.RelayMinerConfigOutputName = spec.Output.Name
+	config.RelayMinerConfigOutputKey = orDefault(spec.Output.Key, appConfig.RelayMinerConfigOutputKey)
+	if config.RelayMinerConfigOutputTarget == FileSource {
+		return nil, fmt.Errorf("spec.target must be configmap or secret")
+	}
+
+	if err := validateConfig(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// relayMinerConfigTemplateReferences reports whether a RelayMinerConfigTemplate reads the given ConfigMap or Secret.
+func relayMinerConfigTemplateReferences(resource *RelayMinerConfigTemplate, source, namespace, name string) bool {
+	return source == SecretSource && resource.Namespace == namespace && resource.Spec.KeysSecret.Name == name
+}
+
+// selectKeys returns the key entries matching selection, narrowing their service IDs to the selected ones.
+// A nil selection keeps every entry.
+func selectKeys(keys []WalletKeySpec, selection *KeySelection) ([]WalletKeySpec, error) {
+	if selection == nil {
+		return keys, nil
+	}
+
+	matchers := make([]func(string) bool, 0, len(selection.ServiceIds))
+	for _, serviceId := range selection.ServiceIds {
+		matcher, err := newServiceIdMatcher(serviceId)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	selected := make([]WalletKeySpec, 0, len(keys))
+	for i, entry := range keys {
+		if len(selection.Entries) > 0 && !slices.Contains(selection.Entries, i) {
+			continue
+		}
+		if len(selection.Roles) > 0 && !slices.Contains(selection.Roles, entryRole(entry)) {
+			continue
+		}
+
+		if len(matchers) > 0 {
+			serviceIds := make([]string, 0, len(entry.ServiceID))
+			for _, serviceId := range entry.ServiceID {
+				for _, matches := range matchers {
+					if matches(serviceId) {
+						serviceIds = append(serviceIds, serviceId)
+						break
+					}
+				}
+			}
+			if len(serviceIds) == 0 {
+				continue
+			}
+			entry.ServiceID = serviceIds
+		}
+
+		selected = append(selected, entry)
+	}
+
+	log.Info().
+		Int("key_count", len(keys)).
+		Int("selected", len(selected)).
+		Msg("Key entries selected")
+	return selected, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRelayMinerConfigTemplateConfig(t *testing.T) {
+	t.Setenv("RUN_MODE", OperatorRunMode)
+	t.Setenv("KEYRING_DIR", "/keyrings")
+	t.Setenv("STATE_FILE_PATH", "/data/state.json")
+	appConfig, err := loadAppConfig()
+	if err != nil {
+		t.Fatalf("loadAppConfig: %v", err)
+	}
+
+	config := map[string]interface{}{"default_signing_key_names": []interface{}{}}
+	tests := []struct {
+		name    string
+		spec    RelayMinerConfigTemplateSpec
+		wantErr bool
+	}{
+		{
+			name: "own namespace",
+			spec: RelayMinerConfigTemplateSpec{
+				Config:     config,
+				KeysSecret: ResourceKeyRef{Namespace: "pocket", Name: "keys"},
+				Keyring:    WalletKeyImportKeyring{Dir: "evm"},
+				Output:     ResourceKeyRef{Name: "generated"},
+			},
+		},
+		{
+			name: "keys secret of another namespace",
+			spec: RelayMinerConfigTemplateSpec{
+				Config:     config,
+				KeysSecret: ResourceKeyRef{Namespace: "kube-system", Name: "keys"},
+				Output:     ResourceKeyRef{Name: "generated"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "output of another namespace",
+			spec: RelayMinerConfigTemplateSpec{
+				Config:     config,
+				KeysSecret: ResourceKeyRef{Name: "keys"},
+				Output:     ResourceKeyRef{Namespace: "kube-system", Name: "generated"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "keyring dir escaping the operator's",
+			spec: RelayMinerConfigTemplateSpec{
+				Config:     config,
+				KeysSecret: ResourceKeyRef{Name: "keys"},
+				Keyring:    WalletKeyImportKeyring{Dir: "../evm"},
+				Output:     ResourceKeyRef{Name: "generated"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &RelayMinerConfigTemplate{ObjectMeta: v1.ObjectMeta{Name: "evm", Namespace: "pocket"}, Spec: tt.spec}
+			config, err := relayMinerConfigTemplateConfig(appConfig, resource)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("relayMinerConfigTemplateConfig succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("relayMinerConfigTemplateConfig: %v", err)
+			}
+			if config.KeysNamespace != "pocket" || config.RelayMinerConfigOutputNamespace != "pocket" {
+				t.Errorf("namespaces = %s, %s, want pocket", config.KeysNamespace, config.RelayMinerConfigOutputNamespace)
+			}
+			if config.KeyringDir != "/keyrings/pocket/evm" {
+				t.Errorf("KeyringDir = %s, want /keyrings/pocket/evm", config.KeyringDir)
+			}
+			if config.StateFilePath != "/data/state.relayminerconfigtemplate.pocket.evm.json" {
+				t.Errorf("StateFilePath = %s, want /data/state.relayminerconfigtemplate.pocket.evm.json", config.StateFilePath)
+			}
+		})
+	}
+}
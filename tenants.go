@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Tenant is a (keys, keyring, relay miner config) tuple of the tenants manifest, processed as its own pass.
+// Fields are named after the environment variables they override; empty fields inherit the loader's settings.
+type Tenant struct {
+	Name string `yaml:"name"`
+
+	KeysNamespace  string `yaml:"keys_namespace,omitempty"`
+	KeysSecretName string `yaml:"keys_secret_name,omitempty"`
+	KeysSecretKey  string `yaml:"keys_secret_key,omitempty"`
+	KeysFilePath   string `yaml:"keys_file_path,omitempty"`
+
+	KeyringDir     string `yaml:"keyring_dir"`
+	KeyringBackend string `yaml:"keyring_backend,omitempty"`
+
+	RelayMinerConfigNamespace       string `yaml:"relayminer_config_namespace,omitempty"`
+	RelayMinerConfigName            string `yaml:"relayminer_config_name,omitempty"`
+	RelayMinerConfigKey             string `yaml:"relayminer_config_key,omitempty"`
+	RelayMinerConfigFilePath        string `yaml:"relayminer_config_file_path,omitempty"`
+	RelayMinerConfigOutputTarget    string `yaml:"relayminer_config_output_target,omitempty"`
+	RelayMinerConfigOutputNamespace string `yaml:"relayminer_config_output_namespace,omitempty"`
+	RelayMinerConfigOutputName      string `yaml:"relayminer_config_output_name,omitempty"`
+	RelayMinerConfigOutputKey       string `yaml:"relayminer_config_output_key,omitempty"`
+	RelayMinerConfigFileOutputPath  string `yaml:"relayminer_config_file_output_path,omitempty"`
+
+	// StateFilePath, CompletionFilePath and ReportFilePath are per tenant, the loader-wide ones are not inherited.
+	StateFilePath      string `yaml:"state_file_path,omitempty"`
+	CompletionFilePath string `yaml:"completion_file_path,omitempty"`
+	ReportFilePath     string `yaml:"report_file_path,omitempty"`
+}
+
+// TenantsManifest lists the tenants processed by a batch run.
+// Example document:
+//
+//	tenants:
+//	  - name: customer-a
+//	    keys_secret_name: customer-a-keys
+//	    keyring_dir: /keyrings/customer-a
+//	    relayminer_config_name: customer-a-relayminer
+//	    relayminer_config_output_name: customer-a-relayminer-generated
+type TenantsManifest struct {
+	Tenants []Tenant `yaml:"tenants"`
+}
+
+// tenantsEnabled reports whether a tenants manifest is configured for the current config source.
+func tenantsEnabled(appConfig *AppConfig) bool {
+	if appConfig.ConfigSource == KubernetesSource {
+		return appConfig.TenantsName != ""
+	}
+	return appConfig.TenantsFilePath != ""
+}
+
+// loadTenants loads the tenants manifest from a file or Kubernetes ConfigMap.
+func loadTenants(appConfig *AppConfig) ([]Tenant, error) {
+	data, err := loadConfigData(
+		appConfig,
+		ConfigMapSource,
+		appConfig.TenantsNamespace,
+		appConfig.TenantsName,
+		appConfig.TenantsKey,
+		appConfig.TenantsFilePath,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load tenants manifest")
+		return nil, fmt.Errorf("error loading tenants manifest: %w", err)
+	}
+
+	manifest := TenantsManifest{}
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		log.Error().Err(err).Msg("Failed to parse tenants manifest")
+		return nil, withExitCode(ExitValidationError, fmt.Errorf("unable to unmarshall tenants manifest: %w", err))
+	}
+
+	names := make(map[string]bool)
+	for i, tenant := range manifest.Tenants {
+		if tenant.Name == "" || tenant.KeyringDir == "" {
+			return nil, withExitCode(ExitValidationError, fmt.Errorf("tenant %d: name and keyring_dir are required", i))
+		}
+		// the name is part of the tenant's output paths and report key
+		if errs := validation.IsDNS1123Label(tenant.Name); len(errs) > 0 {
+			return nil, withExitCode(ExitValidationError, fmt.Errorf("tenant %d: invalid name %q: %s", i, tenant.Name, strings.Join(errs, ", ")))
+		}
+		if names[tenant.Name] {
+			return nil, withExitCode(ExitValidationError, fmt.Errorf("duplicate tenant: %s", tenant.Name))
+		}
+		names[tenant.Name] = true
+	}
+
+	log.Info().Int("tenant_count", len(manifest.Tenants)).Msg("Tenants manifest loaded successfully")
+	return manifest.Tenants, nil
+}
+
+// tenantPath returns path with the tenant name inserted before its extension, e.g. diff.customer-a.json.
+func tenantPath(path, tenant string) string {
+	if path == "" {
+		return ""
+	}
+	extension := filepath.Ext(path)
+	return strings.TrimSuffix(path, extension) + "." + tenant + extension
+}
+
+// tenantDir returns the tenant's subdirectory of dir.
+func tenantDir(dir, tenant string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, tenant)
+}
+
+// tenantConfig derives the AppConfig of a tenant's pass from the loader's AppConfig. The outputs of the loader no
+// tenant field overrides (config diff, stake and application configs, unsigned transactions) are made per tenant
+// from its name.
+func tenantConfig(appConfig *AppConfig, tenant Tenant) (*AppConfig, error) {
+	config := *appConfig
+
+	config.KeysNamespace = orDefault(tenant.KeysNamespace, appConfig.KeysNamespace)
+	config.KeysSecretName = orDefault(tenant.KeysSecretName, appConfig.KeysSecretName)
+	config.KeysSecretKey = orDefault(tenant.KeysSecretKey, appConfig.KeysSecretKey)
+	config.KeysFilePath = orDefault(tenant.KeysFilePath, appConfig.KeysFilePath)
+
+	config.KeyringDir = tenant.KeyringDir
+	config.KeyringBackend = orDefault(tenant.KeyringBackend, appConfig.KeyringBackend)
+
+	config.RelayMinerConfigNamespace = orDefault(tenant.RelayMinerConfigNamespace, appConfig.RelayMinerConfigNamespace)
+	config.RelayMinerConfigName = orDefault(tenant.RelayMinerConfigName, appConfig.RelayMinerConfigName)
+	config.RelayMinerConfigKey = orDefault(tenant.RelayMinerConfigKey, appConfig.RelayMinerConfigKey)
+	config.RelayMinerConfigFilePath = orDefault(tenant.RelayMinerConfigFilePath, appConfig.RelayMinerConfigFilePath)
+	config.RelayMinerConfigOutputTarget = orDefault(tenant.RelayMinerConfigOutputTarget, appConfig.RelayMinerConfigOutputTarget)
+	config.RelayMinerConfigOutputNamespace = orDefault(tenant.RelayMinerConfigOutputNamespace, appConfig.RelayMinerConfigOutputNamespace)
+	config.RelayMinerConfigOutputName = orDefault(tenant.RelayMinerConfigOutputName, appConfig.RelayMinerConfigOutputName)
+	config.RelayMinerConfigOutputKey = orDefault(tenant.RelayMinerConfigOutputKey, appConfig.RelayMinerConfigOutputKey)
+	config.RelayMinerConfigFileOutputPath = orDefault(tenant.RelayMinerConfigFileOutputPath, appConfig.RelayMinerConfigFileOutputPath)
+
+	config.StateFilePath = tenant.StateFilePath
+	config.CompletionFilePath = tenant.CompletionFilePath
+	config.ReportFilePath = tenant.ReportFilePath
+	// the tenants share the pod, and so its key of the report ConfigMap
+	config.ReportConfigMapKey = strings.TrimSuffix(reportConfigMapKey(appConfig), ".json") + "." + tenant.Name + ".json"
+
+	config.RelayMinerConfigDiffOutputPath = tenantPath(appConfig.RelayMinerConfigDiffOutputPath, tenant.Name)
+	config.SupplierStakeConfigOutputDir = tenantDir(appConfig.SupplierStakeConfigOutputDir, tenant.Name)
+	config.ApplicationConfigOutputDir = tenantDir(appConfig.ApplicationConfigOutputDir, tenant.Name)
+	config.UnsignedTxOutputDir = tenantDir(appConfig.UnsignedTxOutputDir, tenant.Name)
+
+	if err := validateConfig(&config); err != nil {
+		return nil, withExitCode(ExitValidationError, err)
+	}
+	return &config, nil
+}
+
+// tenantOutputs returns the outputs of a tenant's pass no other tenant may write: its keyring, the generated relay
+// miner config (and so its backups), the state, completion and report files.
+func tenantOutputs(config *AppConfig) []string {
+	outputs := make([]string, 0)
+	if config.KeyringDir != "" {
+		outputs = append(outputs, "keyring "+filepath.Clean(config.KeyringDir))
+	}
+	if config.GenerateRelayMinerConfig {
+		if config.RelayMinerConfigOutputTarget == FileSource {
+			// the template, which renders to the same paths for every tenant of the process
+			outputs = append(outputs, "file "+filepath.Clean(config.RelayMinerConfigFileOutputPath))
+		} else {
+			outputs = append(outputs, fmt.Sprintf("%s %s/%s", config.RelayMinerConfigOutputTarget, config.RelayMinerConfigOutputNamespace, config.RelayMinerConfigOutputName))
+		}
+	}
+	for _, path := range []string{config.StateFilePath, config.CompletionFilePath, config.ReportFilePath} {
+		if path != "" {
+			outputs = append(outputs, "file "+filepath.Clean(path))
+		}
+	}
+	return outputs
+}
+
+// claimTenantOutputs records the outputs of a tenant's pass in owners, rejecting the tenant if one of them is already
+// written by another tenant (e.g. both inheriting RELAYMINER_CONFIG_FILE_OUTPUT_PATH).
+func claimTenantOutputs(owners map[string]string, tenant string, config *AppConfig) error {
+	outputs := tenantOutputs(config)
+	for _, output := range outputs {
+		if owner, ok := owners[output]; ok && owner != tenant {
+			return withExitCode(ExitValidationError, fmt.Errorf("%s is already used by tenant %s", output, owner))
+		}
+	}
+	for _, output := range outputs {
+		owners[output] = tenant
+	}
+	return nil
+}
+
+// runTenants runs a pass for every tenant of the manifest. A failing tenant doesn't stop the others, the
+// failures are reported together at the end. A tenant whose outputs are another tenant's fails without running.
+func runTenants(appConfig *AppConfig) error {
+	tenants, err := loadTenants(appConfig)
+	if err != nil {
+		return err
+	}
+
+	failures := make([]error, 0)
+	owners := make(map[string]string)
+	for _, tenant := range tenants {
+		log.Info().Str("tenant", tenant.Name).Msg("Processing tenant")
+
+		config, err := tenantConfig(appConfig, tenant)
+		if err == nil {
+			err = claimTenantOutputs(owners, tenant.Name, config)
+		}
+		if err == nil {
+			err = run(config)
+		}
+		if errors.Is(err, ErrPassAbandoned) {
+			// the abandoned pass keeps running, stop the batch so the process exits
+			return fmt.Errorf("tenant %s: %w", tenant.Name, err)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("tenant", tenant.Name).Msg("Tenant failed, continuing with the next one")
+			failures = append(failures, fmt.Errorf("tenant %s: %w", tenant.Name, err))
+			continue
+		}
+		log.Info().Str("tenant", tenant.Name).Msg("Tenant processed successfully")
+	}
+
+	log.Info().
+		Int("tenants", len(tenants)).
+		Int("failed", len(failures)).
+		Msg("Tenants batch completed")
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d tenants failed: %w", len(failures), len(tenants), errors.Join(failures...))
+	}
+	return nil
+}
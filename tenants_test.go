@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTenantConfig(t *testing.T) {
+	t.Setenv("CONFIG_SOURCE", FileSource)
+	t.Setenv("KEYS_FILE_PATH", "/config/keys.json")
+	t.Setenv("KEYRING_DIR", "/keyrings/default")
+	t.Setenv("RELAYMINER_CONFIG_FILE_PATH", "/config/relayminer.yaml")
+	t.Setenv("RELAYMINER_CONFIG_FILE_OUTPUT_PATH", "/generated/relayminer.yaml")
+	t.Setenv("STATE_FILE_PATH", "/state/state.json")
+	t.Setenv("RELAYMINER_CONFIG_DIFF_OUTPUT_PATH", "/reports/diff.json")
+	t.Setenv("APPLICATION_CONFIG_OUTPUT_DIR", "/applications")
+	appConfig, err := loadAppConfig()
+	if err != nil {
+		t.Fatalf("loadAppConfig: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tenant Tenant
+		check  func(t *testing.T, config *AppConfig)
+	}{
+		{
+			name:   "inherits the loader's settings",
+			tenant: Tenant{Name: "customer-a", KeyringDir: "/keyrings/customer-a"},
+			check: func(t *testing.T, config *AppConfig) {
+				if config.KeyringDir != "/keyrings/customer-a" {
+					t.Errorf("KeyringDir = %s, want /keyrings/customer-a", config.KeyringDir)
+				}
+				if config.KeysFilePath != "/config/keys.json" {
+					t.Errorf("KeysFilePath = %s, want the loader's", config.KeysFilePath)
+				}
+				if config.RelayMinerConfigFilePath != "/config/relayminer.yaml" {
+					t.Errorf("RelayMinerConfigFilePath = %s, want the loader's", config.RelayMinerConfigFilePath)
+				}
+				if config.StateFilePath != "" {
+					t.Errorf("StateFilePath = %s, want the loader's not to be inherited", config.StateFilePath)
+				}
+				if config.RelayMinerConfigDiffOutputPath != "/reports/diff.customer-a.json" {
+					t.Errorf("RelayMinerConfigDiffOutputPath = %s, want /reports/diff.customer-a.json", config.RelayMinerConfigDiffOutputPath)
+				}
+				if config.ApplicationConfigOutputDir != "/applications/customer-a" {
+					t.Errorf("ApplicationConfigOutputDir = %s, want /applications/customer-a", config.ApplicationConfigOutputDir)
+				}
+				if config.UnsignedTxOutputDir != "" {
+					t.Errorf("UnsignedTxOutputDir = %s, want it to stay disabled", config.UnsignedTxOutputDir)
+				}
+			},
+		},
+		{
+			name: "overrides",
+			tenant: Tenant{
+				Name:                           "customer-b",
+				KeysFilePath:                   "/config/customer-b.json",
+				KeyringDir:                     "/keyrings/customer-b",
+				RelayMinerConfigFileOutputPath: "/generated/customer-b.yaml",
+				StateFilePath:                  "/state/customer-b.json",
+			},
+			check: func(t *testing.T, config *AppConfig) {
+				if config.KeysFilePath != "/config/customer-b.json" {
+					t.Errorf("KeysFilePath = %s, want /config/customer-b.json", config.KeysFilePath)
+				}
+				if config.RelayMinerConfigFileOutputPath != "/generated/customer-b.yaml" {
+					t.Errorf("RelayMinerConfigFileOutputPath = %s, want /generated/customer-b.yaml", config.RelayMinerConfigFileOutputPath)
+				}
+				if config.StateFilePath != "/state/customer-b.json" {
+					t.Errorf("StateFilePath = %s, want /state/customer-b.json", config.StateFilePath)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := tenantConfig(appConfig, tt.tenant)
+			if err != nil {
+				t.Fatalf("tenantConfig: %v", err)
+			}
+			if appConfig.KeyringDir != "/keyrings/default" {
+				t.Errorf("tenantConfig modified the loader's config")
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestClaimTenantOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []AppConfig
+		wantErr bool
+	}{
+		{
+			name: "distinct outputs",
+			configs: []AppConfig{
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: FileSource, RelayMinerConfigFileOutputPath: "/generated/a.yaml", StateFilePath: "/state/a.json"},
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: FileSource, RelayMinerConfigFileOutputPath: "/generated/b.yaml", StateFilePath: "/state/b.json"},
+			},
+		},
+		{
+			name: "inherited file output",
+			configs: []AppConfig{
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: FileSource, RelayMinerConfigFileOutputPath: "/generated/config.yaml"},
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: FileSource, RelayMinerConfigFileOutputPath: "/generated/./config.yaml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same ConfigMap",
+			configs: []AppConfig{
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: ConfigMapSource, RelayMinerConfigOutputNamespace: "pocket", RelayMinerConfigOutputName: "generated"},
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: ConfigMapSource, RelayMinerConfigOutputNamespace: "pocket", RelayMinerConfigOutputName: "generated"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same ConfigMap name in other namespaces",
+			configs: []AppConfig{
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: ConfigMapSource, RelayMinerConfigOutputNamespace: "customer-a", RelayMinerConfigOutputName: "generated"},
+				{GenerateRelayMinerConfig: true, RelayMinerConfigOutputTarget: ConfigMapSource, RelayMinerConfigOutputNamespace: "customer-b", RelayMinerConfigOutputName: "generated"},
+			},
+		},
+		{
+			name: "same keyring dir",
+			configs: []AppConfig{
+				{KeyringDir: "/keyrings/customer-a"},
+				{KeyringDir: "/keyrings/customer-b/../customer-a/"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "distinct keyring dirs",
+			configs: []AppConfig{
+				{KeyringDir: "/keyrings/customer-a"},
+				{KeyringDir: "/keyrings/customer-b"},
+			},
+		},
+		{
+			name: "same report file",
+			configs: []AppConfig{
+				{ReportFilePath: "/reports/report.json"},
+				{ReportFilePath: "/reports/report.json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "import only",
+			configs: []AppConfig{
+				{RelayMinerConfigOutputTarget: FileSource, RelayMinerConfigFileOutputPath: "/generated/config.yaml"},
+				{RelayMinerConfigOutputTarget: FileSource, RelayMinerConfigFileOutputPath: "/generated/config.yaml"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owners := make(map[string]string)
+			var err error
+			for i := range tt.configs {
+				if err = claimTenantOutputs(owners, fmt.Sprintf("tenant-%d", i), &tt.configs[i]); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != (err != nil) {
+				t.Errorf("claimTenantOutputs error = %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  bool
+	}{
+		{name: "valid", manifest: "tenants:\n- name: customer-a\n  keyring_dir: /keyrings/a\n- name: customer-b\n  keyring_dir: /keyrings/b\n"},
+		{name: "path traversal", manifest: "tenants:\n- name: ../other\n  keyring_dir: /keyrings/a\n", wantErr: true},
+		{name: "slash", manifest: "tenants:\n- name: customer/a\n  keyring_dir: /keyrings/a\n", wantErr: true},
+		{name: "upper case", manifest: "tenants:\n- name: CustomerA\n  keyring_dir: /keyrings/a\n", wantErr: true},
+		{name: "duplicate", manifest: "tenants:\n- name: customer-a\n  keyring_dir: /keyrings/a\n- name: customer-a\n  keyring_dir: /keyrings/b\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tenants.yaml")
+			if err := os.WriteFile(path, []byte(tt.manifest), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadTenants(&AppConfig{ConfigSource: FileSource, TenantsFilePath: path})
+			if tt.wantErr != (err != nil) {
+				t.Errorf("loadTenants() error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil && exitCode(err) != ExitValidationError {
+				t.Errorf("loadTenants() exit code = %d, want %d", exitCode(err), ExitValidationError)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/go-bip39"
+	poktrollconfig "github.com/pokt-network/poktroll/pkg/relayer/config"
+	"github.com/rs/zerolog/log"
+)
+
+// VerifyCommand validates keys.json and the base relay miner config without writing anything.
+const VerifyCommand string = "verify"
+
+// Severities of verification findings
+const (
+	SeverityError   string = "error"
+	SeverityWarning string = "warning"
+)
+
+// Finding is a single problem found while verifying the sources.
+type Finding struct {
+	Severity string `json:"severity"`
+	// Entry is the keys.json entry index the finding is about, if any.
+	Entry   *int   `json:"entry,omitempty"`
+	Message string `json:"message"`
+}
+
+// VerifyReport lists the findings of a verification.
+type VerifyReport struct {
+	Entries  int       `json:"entries"`
+	Keys     int       `json:"keys"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+	Findings []Finding `json:"findings"`
+}
+
+// add records a finding, entry is -1 for findings not about a keys.json entry.
+func (r *VerifyReport) add(severity string, entry int, format string, args ...interface{}) {
+	finding := Finding{Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if entry >= 0 {
+		finding.Entry = &entry
+	}
+	r.Findings = append(r.Findings, finding)
+
+	if severity == SeverityError {
+		r.Errors++
+		log.Error().Int("entry", entry).Msg(finding.Message)
+	} else {
+		r.Warnings++
+		log.Warn().Int("entry", entry).Msg(finding.Message)
+	}
+}
+
+// verifyServiceIds checks that every service ID (or pattern) of an operator entry matches a supplier of the base config.
+func verifyServiceIds(report *VerifyReport, index int, entry WalletKeySpec, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) {
+	for _, serviceId := range entry.ServiceID {
+		matches, err := newServiceIdMatcher(serviceId)
+		if err != nil {
+			report.add(SeverityError, index, "%s", err)
+			continue
+		}
+		if relayMinerConfig == nil || entryRole(entry) != OperatorRole {
+			continue
+		}
+
+		found := false
+		for _, supplierConfig := range relayMinerConfig.Suppliers {
+			if matches(supplierConfig.ServiceId) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report.add(SeverityError, index, "service id not found under suppliers[].service_id: %s", serviceId)
+		}
+	}
+}
+
+// verifyEntry checks a single keys.json entry: key material, role, distribution and service IDs.
+func verifyEntry(report *VerifyReport, index int, entry WalletKeySpec, relayMinerConfig *poktrollconfig.YAMLRelayMinerConfig) {
+	if err := validateRole(entry); err != nil {
+		report.add(SeverityError, index, "%s", err)
+	}
+	if err := validateDistribution(entry); err != nil {
+		report.add(SeverityError, index, "%s", err)
+	}
+
+	switch {
+	case entry.Mnemonic != "":
+		if entry.Hex != "" {
+			report.add(SeverityWarning, index, "both mnemonic and hex are set, hex is ignored")
+		}
+		if !bip39.IsMnemonicValid(entry.Mnemonic) {
+			report.add(SeverityError, index, "invalid mnemonic")
+		}
+		if entry.StartIndex < 0 || entry.EndIndex < entry.StartIndex {
+			report.add(SeverityError, index, "invalid derivation range %d..%d", entry.StartIndex, entry.EndIndex)
+		} else if bip39.IsMnemonicValid(entry.Mnemonic) {
+			verifyExpectedAddresses(report, index, entry)
+		}
+	case entry.Hex != "":
+		if entry.Distribution != "" && entry.Distribution != DistributionAll {
+			report.add(SeverityError, index, "distribution %s requires a mnemonic range", entry.Distribution)
+		}
+		privKeyBytes, err := hex.DecodeString(strings.TrimPrefix(entry.Hex, "0x"))
+		if err != nil {
+			report.add(SeverityError, index, "invalid hex key: %s", err)
+		} else if len(privKeyBytes) != secp256k1.PrivKeySize {
+			report.add(SeverityError, index, "invalid hex key length: %d bytes, expected %d", len(privKeyBytes), secp256k1.PrivKeySize)
+		} else {
+			verifyExpectedAddresses(report, index, entry)
+		}
+	default:
+		report.add(SeverityError, index, "one of mnemonic or hex is required")
+	}
+
+	verifyServiceIds(report, index, entry, relayMinerConfig)
+}
+
+// verifyExpectedAddresses reports the keys of a valid entry not matching its expected_addresses.
+func verifyExpectedAddresses(report *VerifyReport, index int, entry WalletKeySpec) {
+	if err := checkExpectedAddresses(entry); err != nil {
+		report.add(SeverityError, index, "%s", err)
+	}
+}
+
+// verify validates the keys and the base relay miner config from their sources, importing the keys into an
+// in-memory keyring, and returns every finding instead of stopping at the first one.
+func verify(appConfig *AppConfig) *VerifyReport {
+	report := &VerifyReport{Findings: make([]Finding, 0)}
+
+	keys, err := loadWalletKeys(appConfig)
+	if err != nil {
+		report.add(SeverityError, -1, "%s", err)
+		return report
+	}
+	report.Entries = len(keys)
+
+	serviceGroups, err := loadServiceGroups(appConfig)
+	if err != nil {
+		report.add(SeverityError, -1, "%s", err)
+	}
+
+	relayMinerConfig, err := loadRelayMinerConfig(appConfig)
+	if err != nil {
+		report.add(SeverityError, -1, "%s", err)
+		// keep checking the keys, without the service ID cross-references
+		relayMinerConfig = nil
+	}
+
+	// resolve the groups of the entries whose groups all exist, the others are reported
+	for i := range keys {
+		resolvable := true
+		for _, groupName := range keys[i].ServiceGroup {
+			if _, ok := serviceGroups[groupName]; !ok {
+				report.add(SeverityError, i, "service group not found: %s", groupName)
+				resolvable = false
+			}
+		}
+		if resolvable {
+			_ = resolveServiceGroups(keys[i:i+1], serviceGroups)
+		}
+	}
+
+	keys, err = selectKeys(keys, appConfig.KeySelection)
+	if err != nil {
+		report.add(SeverityError, -1, "%s", err)
+		return report
+	}
+
+	for i, entry := range keys {
+		if entry.Disabled {
+			log.Info().Int("entry", i).Msg("Entry disabled, not verified")
+			continue
+		}
+		verifyEntry(report, i, entry, relayMinerConfig)
+	}
+	if err := checkMaxKeys(appConfig, keys); err != nil {
+		report.add(SeverityError, -1, "%s", err)
+	}
+	if report.Errors > 0 {
+		return report
+	}
+
+	// the entries look fine on their own, run the actual import and registration to catch the rest
+	importedKeys, err := importAndRegisterKeys(appConfig, keys, keyring.NewInMemory(getCodec()), relayMinerConfig)
+	if err != nil {
+		report.add(SeverityError, -1, "%s", err)
+		return report
+	}
+	report.Keys = len(importedKeys)
+
+	seen := make(map[string]int)
+	for _, key := range importedKeys {
+		if previous, ok := seen[key.Address]; ok && previous != key.EntryIndex {
+			report.add(SeverityWarning, key.EntryIndex, "key %s is also derived by entry %d", key.Address, previous)
+			continue
+		}
+		seen[key.Address] = key.EntryIndex
+
+		if key.OwnerAddress != "" {
+			if _, err := sdk.AccAddressFromBech32(key.OwnerAddress); err != nil {
+				report.add(SeverityError, key.EntryIndex, "invalid owner address %s: %s", key.OwnerAddress, err)
+			}
+		}
+	}
+
+	if appConfig.GenerateRelayMinerConfig && relayMinerConfig != nil {
+		severity := SeverityWarning
+		if appConfig.EmptySupplierMode == EmptySupplierFail {
+			severity = SeverityError
+		}
+		for _, serviceId := range emptySuppliers(relayMinerConfig) {
+			report.add(severity, -1, "supplier %s is left without signing keys", serviceId)
+		}
+	}
+
+	return report
+}
+
+// verifyCommand runs the verify subcommand: it prints (or writes) the findings report and fails when the
+// report has errors, or warnings with --strict, so broken bundles fail CI before they are deployed.
+func verifyCommand(appConfig *AppConfig, out string, strict bool) error {
+	report := verify(appConfig)
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal findings report: %w", err)
+	}
+	if out == "" {
+		if _, err := fmt.Println(string(content)); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(out, content, 0644); err != nil {
+		return fmt.Errorf("unable to write findings report: %w", err)
+	}
+
+	log.Info().
+		Int("entries", report.Entries).
+		Int("keys", report.Keys).
+		Int("errors", report.Errors).
+		Int("warnings", report.Warnings).
+		Msg("Verification completed")
+
+	if report.Errors > 0 || (strict && report.Warnings > 0) {
+		return withExitCode(ExitValidationError, fmt.Errorf("verification failed with %d errors and %d warnings", report.Errors, report.Warnings))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/fips140"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+)
+
+// VersionCommand prints the build metadata of the loader.
+const VersionCommand string = "version"
+
+// poktrollModule is the module defining the relay miner config schema the loader reads and writes.
+const poktrollModule = "github.com/pokt-network/poktroll"
+
+// Build metadata, set at build time with
+// `-ldflags "-X main.version=<tag> -X main.commit=<sha> -X main.buildDate=<RFC 3339 date>"`. Commit and date fall back to
+// the revision and commit time stamped by the Go toolchain when empty.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// BuildInfo describes the build of the loader, for support to correlate behavior with releases.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	// ConfigSchema is the poktroll release whose relay miner config schema the loader was built against.
+	ConfigSchema string `json:"config_schema"`
+	GoVersion    string `json:"go_version"`
+	Platform     string `json:"platform"`
+	// FIPS140 reports whether the Go FIPS 140-3 cryptographic module is enabled.
+	FIPS140 bool `json:"fips140"`
+}
+
+// buildInfo returns the build metadata, completed from the module information embedded by the Go toolchain.
+func buildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:      version,
+		Commit:       commit,
+		BuildDate:    buildDate,
+		ConfigSchema: "unknown",
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		FIPS140:      fips140.Enabled(),
+	}
+
+	embedded, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, dependency := range embedded.Deps {
+		if dependency.Path == poktrollModule {
+			info.ConfigSchema = dependency.Version
+			if dependency.Replace != nil {
+				info.ConfigSchema = dependency.Replace.Version
+			}
+		}
+	}
+	for _, setting := range embedded.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = setting.Value
+		case setting.Key == "vcs.time" && info.BuildDate == "":
+			info.BuildDate = setting.Value
+		}
+	}
+	info.Commit = orDefault(info.Commit, "unknown")
+	info.BuildDate = orDefault(info.BuildDate, "unknown")
+	return info
+}
+
+// logBuildInfo logs the build metadata when the loader starts.
+func logBuildInfo() {
+	info := buildInfo()
+	log.Info().
+		Str("version", info.Version).
+		Str("commit", info.Commit).
+		Str("build_date", info.BuildDate).
+		Str("config_schema", info.ConfigSchema).
+		Str("go_version", info.GoVersion).
+		Bool("fips140", info.FIPS140).
+		Msg("Starting keyring loader")
+}
+
+// versionCommand runs the version subcommand: it prints the build metadata, as text or as JSON.
+func versionCommand(asJSON bool) error {
+	info := buildInfo()
+
+	if asJSON {
+		content, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal build info: %w", err)
+		}
+		_, err = fmt.Println(string(content))
+		return err
+	}
+
+	_, err := fmt.Printf("shannon-keyring-loader %s\ncommit: %s\nbuild date: %s\nconfig schema: poktroll %s\ngo: %s %s\nfips 140-3: %t\n",
+		info.Version, info.Commit, info.BuildDate, info.ConfigSchema, info.GoVersion, info.Platform, info.FIPS140)
+	return err
+}
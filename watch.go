@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Run modes of the loader
+const (
+	// OnceRunMode runs a single import/generation pass and exits (default, suited for init containers).
+	OnceRunMode string = "once"
+	// WatchRunMode keeps running and re-runs the import/generation pass every time a source changes.
+	WatchRunMode string = "watch"
+	// DaemonRunMode keeps running and re-runs the import/generation pass every ResyncInterval, without watches.
+	DaemonRunMode string = "daemon"
+)
+
+// watchResource starts an informer on a single ConfigMap or Secret and calls notify every time it is
+// created or its content changes. Periodic resyncs that carry no change are ignored.
+// namespace may select several namespaces (a list or a wildcard), which watches the resource cluster-wide.
+func watchResource(ctx context.Context, clientset kubernetes.Interface, source, namespace, name string, notify func(reason string)) (cache.InformerSynced, error) {
+	informerNamespace := namespace
+	if isMultiNamespace(namespace) {
+		informerNamespace = v1.NamespaceAll
+	}
+	selected := func(obj interface{}) bool {
+		object, ok := obj.(v1.Object)
+		return !ok || informerNamespace != v1.NamespaceAll || matchesNamespace(namespace, object.GetNamespace())
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		clientset,
+		0,
+		informers.WithNamespace(informerNamespace),
+		informers.WithTweakListOptions(func(options *v1.ListOptions) {
+			options.FieldSelector = "metadata.name=" + name
+		}),
+	)
+
+	var informer cache.SharedIndexInformer
+	switch source {
+	case ConfigMapSource:
+		informer = factory.Core().V1().ConfigMaps().Informer()
+	case SecretSource:
+		informer = factory.Core().V1().Secrets().Informer()
+	default:
+		return nil, fmt.Errorf("unsupported watch source: %s", source)
+	}
+
+	reason := fmt.Sprintf("%s %s/%s", source, namespace, name)
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if selected(obj) {
+				notify(reason + " added")
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if !selected(newObj) {
+				return
+			}
+			oldMeta, oldOk := oldObj.(v1.Object)
+			newMeta, newOk := newObj.(v1.Object)
+			if oldOk && newOk && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			notify(reason + " updated")
+		},
+		DeleteFunc: func(obj interface{}) {
+			if !selected(obj) {
+				return
+			}
+			log.Warn().
+				Str("source", source).
+				Str("namespace", namespace).
+				Str("name", name).
+				Msg("Watched resource deleted, keeping the last generated state")
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error watching %s: %w", reason, err)
+	}
+
+	log.Info().
+		Str("source", source).
+		Str("namespace", namespace).
+		Str("name", name).
+		Msg("Watching resource")
+
+	factory.Start(ctx.Done())
+	return informer.HasSynced, nil
+}
+
+// watchKubernetesSources watches the keys Secret, the relay miner ConfigMap and the service groups ConfigMap,
+// calling notify every time one of them changes. Returns once the informer caches are synced.
+func watchKubernetesSources(ctx context.Context, appConfig *AppConfig, notify func(reason string)) error {
+	clientset, err := newKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	synced := make([]cache.InformerSynced, 0)
+	watched := [][3]string{
+		{SecretSource, appConfig.KeysNamespace, appConfig.KeysSecretName},
+	}
+	if appConfig.GenerateRelayMinerConfig {
+		watched = append(watched, [3]string{ConfigMapSource, appConfig.RelayMinerConfigNamespace, appConfig.RelayMinerConfigName})
+	}
+	if serviceGroupsEnabled(appConfig) {
+		watched = append(watched, [3]string{ConfigMapSource, appConfig.ServiceGroupsNamespace, appConfig.ServiceGroupsName})
+	}
+	for _, resource := range watched {
+		hasSynced, err := watchResource(ctx, clientset, resource[0], resource[1], resource[2], notify)
+		if err != nil {
+			return err
+		}
+		synced = append(synced, hasSynced)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("error waiting for watch caches to sync")
+	}
+	return nil
+}
+
+// fileFingerprint returns the content hash of the file behind path (following symlinks), or an empty string
+// when it can't be read, e.g. while a projected volume swaps its `..data` symlink.
+func fileFingerprint(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return contentHash(data)
+}
+
+// watchFileSources watches the local keys file, base relay miner config and service groups document with fsnotify,
+// calling notify every time the content of one of them changes. The parent directories are watched rather than
+// the files, so editors replacing files and the Kubernetes projected-volume `..data` symlink swap are both detected.
+func watchFileSources(ctx context.Context, appConfig *AppConfig, notify func(reason string)) error {
+	paths := []string{appConfig.KeysFilePath}
+	if appConfig.GenerateRelayMinerConfig {
+		paths = append(paths, appConfig.RelayMinerConfigFilePath)
+	}
+	if serviceGroupsEnabled(appConfig) {
+		paths = append(paths, appConfig.ServiceGroupsFilePath)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating file watcher: %w", err)
+	}
+
+	fingerprints := make(map[string]string)
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("failed to convert to absolute path: %w", err)
+		}
+		fingerprints[absPath] = fileFingerprint(absPath)
+
+		dir := filepath.Dir(absPath)
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("error watching directory '%s': %w", dir, err)
+		}
+		log.Info().Str("path", absPath).Msg("Watching file")
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error().Err(err).Msg("File watcher error")
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				log.Trace().Str("event", event.String()).Msg("File event")
+
+				// any event in a watched directory may be a symlink swap, so compare the actual contents
+				for path, previous := range fingerprints {
+					current := fileFingerprint(path)
+					if current == previous || current == "" {
+						continue
+					}
+					fingerprints[path] = current
+					notify("file " + path + " changed")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// watch runs the loader as a long-lived process: in watch mode it watches its sources (Kubernetes resources or
+// local files, depending on CONFIG_SOURCE) and re-runs the import/generation pass whenever one of them changes.
+// With a ResyncInterval (required in daemon mode) the pass is also re-run periodically, healing drift such as
+// keys deleted from the keyring. Bursts of changes are coalesced into a single pass after WatchDebounce.
+// On SIGTERM/SIGINT the in-flight pass is finished (within ShutdownTimeout) and the process exits cleanly.
+func watch(appConfig *AppConfig) error {
+	ctx, stop := shutdownContext()
+	defer stop()
+
+	// let operators inspect the loop with the status subcommand and SIGUSR1
+	if appConfig.status == nil {
+		appConfig.status = newStatusTracker(appConfig.RunMode)
+	}
+	dumpStateOnSignal(ctx, appConfig)
+	defer servePprof(appConfig)()
+	if appConfig.StatusSocketPath != "" {
+		stopStatus, err := serveStatus(appConfig.StatusSocketPath, appConfig.status)
+		if err != nil {
+			return err
+		}
+		defer stopStatus()
+	}
+
+	// a single pending trigger is enough, every pass reads all the sources
+	trigger := make(chan string, 1)
+	notify := func(reason string) {
+		appConfig.status.pending(reason)
+		select {
+		case trigger <- reason:
+		default:
+		}
+	}
+
+	switch {
+	case appConfig.RunMode == DaemonRunMode:
+		notify("startup")
+	case appConfig.ConfigSource == KubernetesSource:
+		// informers deliver an add event for every watched resource, triggering the first pass
+		if err := watchKubernetesSources(ctx, appConfig, notify); err != nil {
+			return err
+		}
+	case appConfig.ConfigSource == FileSource:
+		if err := watchFileSources(ctx, appConfig, notify); err != nil {
+			return err
+		}
+		notify("startup")
+	default:
+		return fmt.Errorf("unsupported configuration source: %s", appConfig.ConfigSource)
+	}
+
+	if appConfig.ResyncInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(appConfig.ResyncInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					notify("resync")
+				}
+			}
+		}()
+	}
+
+	log.Info().
+		Str("mode", appConfig.RunMode).
+		Dur("debounce", appConfig.WatchDebounce).
+		Dur("resync_interval", appConfig.ResyncInterval).
+		Msg("Waiting for changes")
+
+	for {
+		var reason string
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Shutdown requested, exiting")
+			return nil
+		case reason = <-trigger:
+		}
+		log.Info().Str("reason", reason).Msg("Change detected, reconciling")
+
+		// let a burst of changes settle before reading the sources
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Shutdown requested, exiting")
+			return nil
+		case <-time.After(appConfig.WatchDebounce):
+		}
+		select {
+		case <-trigger:
+		default:
+		}
+
+		appConfig.status.started(reason)
+		done := make(chan error, 1)
+		go func() {
+			done <- run(appConfig)
+		}()
+
+		var err error
+		shutdown := false
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			shutdown = true
+			err = waitInFlight(appConfig, done)
+		}
+
+		appConfig.status.finished(err)
+		if errors.Is(err, ErrPassAbandoned) {
+			// the abandoned pass may still hold the run lock, exit so the container restarts without it
+			return err
+		}
+		if err != nil {
+			// keep watching, the next change may fix it
+			log.Error().Err(err).Msg("Reconcile failed")
+		} else {
+			log.Info().Msg("Reconcile completed")
+		}
+		if shutdown {
+			log.Info().Msg("Shutdown completed")
+			return nil
+		}
+	}
+}
+
+// serveWatch runs the watch loop (under leader election when enabled), serving its liveness and readiness probes on
+// HealthListenAddress meanwhile, so standby replicas answer their probes too.
+func serveWatch(appConfig *AppConfig) error {
+	appConfig.status = newStatusTracker(appConfig.RunMode)
+
+	if appConfig.HealthListenAddress != "" {
+		health := newLoopHealthServer(appConfig, appConfig.status)
+		health.start()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := health.stop(ctx); err != nil {
+				log.Warn().Err(err).Msg("Unable to stop the health server")
+			}
+		}()
+	}
+
+	return leading(appConfig, func() error { return watch(appConfig) })
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/rs/zerolog/log"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// WebhookRunMode serves a mutating admission webhook injecting the loader as an init container.
+const WebhookRunMode string = "webhook"
+
+// Pod label and annotations understood by the webhook
+const (
+	// webhookInjectLabel opts a pod into the injection when set to "true".
+	webhookInjectLabel = "pokt.network/keyring-loader-inject"
+	// keysSecretAnnotation overrides the keys Secret mounted into the injected container (KEYS_SECRET_NAME by default).
+	keysSecretAnnotation = "pokt.network/keys-secret"
+	// relayMinerConfigAnnotation overrides the base relay miner ConfigMap (RELAYMINER_CONFIG_NAME by default).
+	relayMinerConfigAnnotation = "pokt.network/relayminer-config"
+)
+
+// Names and paths used by the injected init container
+const (
+	loaderContainerName   = "keyring-loader"
+	loaderKeysVolume      = "keyring-loader-keys"
+	loaderConfigVolume    = "keyring-loader-config"
+	loaderKeyringVolume   = "keyring-loader-keyring"
+	loaderGeneratedVolume = "keyring-loader-generated"
+	loaderKeysMountPath   = "/keyring-loader/keys"
+	loaderConfigMountPath = "/keyring-loader/config"
+)
+
+// jsonPatchOperation is a single RFC 6902 operation of an admission response patch.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// loaderInitContainer returns the init container running the loader once, reading the keys Secret and base config
+// from volumes and writing the keyring and the generated config to the volumes shared with the pod containers.
+func loaderInitContainer(appConfig *AppConfig) corev1.Container {
+	container := corev1.Container{
+		Name:  loaderContainerName,
+		Image: appConfig.WebhookLoaderImage,
+		Env: []corev1.EnvVar{
+			{Name: "RUN_MODE", Value: OnceRunMode},
+			{Name: "CONFIG_SOURCE", Value: FileSource},
+			{Name: "KEYS_FILE_PATH", Value: path.Join(loaderKeysMountPath, appConfig.KeysSecretKey)},
+			{Name: "KEYRING_DIR", Value: appConfig.WebhookKeyringMountPath},
+			{Name: "RELAYMINER_CONFIG_FILE_PATH", Value: path.Join(loaderConfigMountPath, appConfig.RelayMinerConfigKey)},
+			{Name: "RELAYMINER_CONFIG_FILE_OUTPUT_PATH", Value: path.Join(appConfig.WebhookConfigMountPath, appConfig.RelayMinerConfigOutputKey)},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: loaderKeysVolume, MountPath: loaderKeysMountPath, ReadOnly: true},
+			{Name: loaderConfigVolume, MountPath: loaderConfigMountPath, ReadOnly: true},
+			{Name: loaderKeyringVolume, MountPath: appConfig.WebhookKeyringMountPath},
+			{Name: loaderGeneratedVolume, MountPath: appConfig.WebhookConfigMountPath},
+		},
+	}
+
+	// settings not covered above (address prefix, keyring backend, ...) come from an optional ConfigMap
+	if appConfig.WebhookLoaderEnvConfigMap != "" {
+		container.EnvFrom = []corev1.EnvFromSource{{
+			ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: appConfig.WebhookLoaderEnvConfigMap},
+			},
+		}}
+	}
+	return container
+}
+
+// loaderVolumes returns the volumes of the injected init container, honoring the pod's source annotations.
+func loaderVolumes(appConfig *AppConfig, pod *corev1.Pod) []corev1.Volume {
+	keysSecret := orDefault(pod.Annotations[keysSecretAnnotation], appConfig.KeysSecretName)
+	relayMinerConfig := orDefault(pod.Annotations[relayMinerConfigAnnotation], appConfig.RelayMinerConfigName)
+
+	return []corev1.Volume{
+		{
+			Name:         loaderKeysVolume,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: keysSecret}},
+		},
+		{
+			Name: loaderConfigVolume,
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: relayMinerConfig},
+			}},
+		},
+		{Name: loaderKeyringVolume, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		{Name: loaderGeneratedVolume, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+}
+
+// injectionPatch returns the JSON patch injecting the loader into pod, or nil if the pod is not labeled for it
+// or already has the loader (e.g. when the webhook is re-invoked).
+func injectionPatch(appConfig *AppConfig, pod *corev1.Pod) []jsonPatchOperation {
+	if pod.Labels[webhookInjectLabel] != "true" {
+		return nil
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == loaderContainerName {
+			return nil
+		}
+	}
+
+	patch := make([]jsonPatchOperation, 0)
+
+	// the loader runs before any other init container
+	container := loaderInitContainer(appConfig)
+	if len(pod.Spec.InitContainers) == 0 {
+		patch = append(patch, jsonPatchOperation{Op: "add", Path: "/spec/initContainers", Value: []corev1.Container{container}})
+	} else {
+		patch = append(patch, jsonPatchOperation{Op: "add", Path: "/spec/initContainers/0", Value: container})
+	}
+
+	volumes := loaderVolumes(appConfig, pod)
+	if len(pod.Spec.Volumes) == 0 {
+		patch = append(patch, jsonPatchOperation{Op: "add", Path: "/spec/volumes", Value: volumes})
+	} else {
+		for _, volume := range volumes {
+			patch = append(patch, jsonPatchOperation{Op: "add", Path: "/spec/volumes/-", Value: volume})
+		}
+	}
+
+	// share the keyring and the generated config with the pod containers
+	mounts := []corev1.VolumeMount{
+		{Name: loaderKeyringVolume, MountPath: appConfig.WebhookKeyringMountPath},
+		{Name: loaderGeneratedVolume, MountPath: appConfig.WebhookConfigMountPath, ReadOnly: true},
+	}
+	for i, podContainer := range pod.Spec.Containers {
+		if len(podContainer.VolumeMounts) == 0 {
+			patch = append(patch, jsonPatchOperation{Op: "add", Path: fmt.Sprintf("/spec/containers/%d/volumeMounts", i), Value: mounts})
+			continue
+		}
+		for _, mount := range mounts {
+			patch = append(patch, jsonPatchOperation{Op: "add", Path: fmt.Sprintf("/spec/containers/%d/volumeMounts/-", i), Value: mount})
+		}
+	}
+
+	return patch
+}
+
+// mutatePod answers a pod admission request, patching labeled pods with the loader init container.
+// Requests that can't be handled are allowed untouched, so the webhook never blocks pod creation.
+func mutatePod(appConfig *AppConfig, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+
+	if request.Kind.Kind != "Pod" {
+		return response
+	}
+
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(request.Object.Raw, pod); err != nil {
+		log.Error().Err(err).Str("uid", string(request.UID)).Msg("Failed to decode admitted pod")
+		response.Warnings = []string{"keyring-loader: unable to decode pod, not injected"}
+		return response
+	}
+
+	patch := injectionPatch(appConfig, pod)
+	if patch == nil {
+		return response
+	}
+
+	content, err := json.Marshal(patch)
+	if err != nil {
+		log.Error().Err(err).Str("uid", string(request.UID)).Msg("Failed to encode injection patch")
+		response.Warnings = []string{"keyring-loader: unable to build patch, not injected"}
+		return response
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = content
+	response.PatchType = &patchType
+
+	log.Info().
+		Str("namespace", request.Namespace).
+		Str("pod", orDefault(pod.Name, pod.GenerateName)).
+		Int("operations", len(patch)).
+		Msg("Injecting keyring loader init container")
+	return response
+}
+
+// webhookHandler serves AdmissionReview requests.
+func webhookHandler(appConfig *AppConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "unable to read request", http.StatusBadRequest)
+			return
+		}
+
+		review := &admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+			log.Error().Err(err).Msg("Failed to decode admission review")
+			http.Error(w, "invalid admission review", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = mutatePod(appConfig, review.Request)
+		review.Request = nil
+
+		content, err := json.Marshal(review)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to encode admission review")
+			http.Error(w, "unable to encode admission review", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(content)
+	}
+}
+
+// serveWebhook serves the mutating admission webhook over TLS until the server fails.
+func serveWebhook(appConfig *AppConfig) error {
+	mux := http.NewServeMux()
+	mux.Handle("/mutate", webhookHandler(appConfig))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	log.Info().
+		Str("address", appConfig.WebhookListenAddress).
+		Str("image", appConfig.WebhookLoaderImage).
+		Msg("Serving keyring loader injection webhook")
+
+	err := http.ListenAndServeTLS(appConfig.WebhookListenAddress, appConfig.WebhookTLSCertFile, appConfig.WebhookTLSKeyFile, mux)
+	if err != nil {
+		return fmt.Errorf("error serving webhook: %w", err)
+	}
+	return nil
+}
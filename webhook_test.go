@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInjectionPatch(t *testing.T) {
+	appConfig := &AppConfig{
+		WebhookLoaderImage:      "ghcr.io/pokt-shannon/shannon-keyring-loader:latest",
+		WebhookKeyringMountPath: "/home/pocket/.pocket",
+		WebhookConfigMountPath:  "/config",
+		KeysSecretName:          "keys",
+		KeysSecretKey:           "keys.json",
+		RelayMinerConfigName:    "relayminer-config",
+		RelayMinerConfigKey:     "config.yaml",
+	}
+	labeled := metav1.ObjectMeta{Labels: map[string]string{webhookInjectLabel: "true"}}
+
+	tests := []struct {
+		name  string
+		pod   corev1.Pod
+		paths []string
+	}{
+		{
+			name: "not labeled",
+			pod:  corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "relayminer"}}}},
+		},
+		{
+			name: "already injected",
+			pod: corev1.Pod{ObjectMeta: labeled, Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: loaderContainerName}},
+				Containers:     []corev1.Container{{Name: "relayminer"}},
+			}},
+		},
+		{
+			name: "bare pod",
+			pod:  corev1.Pod{ObjectMeta: labeled, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "relayminer"}}}},
+			paths: []string{
+				"/spec/initContainers",
+				"/spec/volumes",
+				"/spec/containers/0/volumeMounts",
+			},
+		},
+		{
+			name: "pod with init containers, volumes and mounts",
+			pod: corev1.Pod{ObjectMeta: labeled, Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "wait-for-node"}},
+				Volumes:        []corev1.Volume{{Name: "data"}},
+				Containers: []corev1.Container{
+					{Name: "relayminer", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}}},
+					{Name: "sidecar"},
+				},
+			}},
+			paths: []string{
+				"/spec/initContainers/0",
+				"/spec/volumes/-",
+				"/spec/volumes/-",
+				"/spec/volumes/-",
+				"/spec/volumes/-",
+				"/spec/containers/0/volumeMounts/-",
+				"/spec/containers/0/volumeMounts/-",
+				"/spec/containers/1/volumeMounts",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := injectionPatch(appConfig, &tt.pod)
+			var paths []string
+			for _, operation := range patch {
+				if operation.Op != "add" {
+					t.Errorf("operation %s on %s, want add", operation.Op, operation.Path)
+				}
+				paths = append(paths, operation.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("injectionPatch paths = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestInjectionPatchAnnotations(t *testing.T) {
+	appConfig := &AppConfig{KeysSecretName: "keys", RelayMinerConfigName: "relayminer-config"}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{webhookInjectLabel: "true"},
+			Annotations: map[string]string{keysSecretAnnotation: "customer-keys"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "relayminer"}}},
+	}
+
+	patch := injectionPatch(appConfig, pod)
+	if len(patch) < 2 {
+		t.Fatalf("injectionPatch returned %d operations, want the init container and the volumes", len(patch))
+	}
+	volumes, ok := patch[1].Value.([]corev1.Volume)
+	if !ok {
+		t.Fatalf("volumes operation value is %T", patch[1].Value)
+	}
+	if secret := volumes[0].Secret.SecretName; secret != "customer-keys" {
+		t.Errorf("keys volume secret = %s, want customer-keys", secret)
+	}
+	if configMap := volumes[1].ConfigMap.Name; configMap != "relayminer-config" {
+		t.Errorf("config volume ConfigMap = %s, want relayminer-config", configMap)
+	}
+}